@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
 
 	_ "embed"
 
@@ -14,9 +15,15 @@ import (
 
 func main() {
 	testBot := flag.Bool("test", false, "Should execute test bot")
+	configFile := flag.String("config", "", "YAML or TOML configuration file")
 	flag.Parse()
 
-	if err := app.InitializeConfiguration(*testBot, envFile, envTestFile); err != nil {
+	if err := app.InitializeConfiguration(*testBot, *configFile, envFile, envTestFile); err != nil {
+		log.Fatal(err)
+	}
+
+	shutdownTracing, err := app.InitializeTracing(context.Background())
+	if err != nil {
 		log.Fatal(err)
 	}
 
@@ -29,6 +36,15 @@ func main() {
 		log.Fatal(err)
 	}
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			_ = botApp.Reload(context.Background())
+		}
+	}()
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
@@ -37,6 +53,7 @@ func main() {
 		<-c
 		botApp.Stop()
 		cleanup()
+		_ = shutdownTracing(context.Background())
 	}()
 
 	botApp.Run()
@@ -4,42 +4,180 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
 
+	"filippo.io/age"
 	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
 	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/i18n"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/metrics"
+	"github.com/javiyt/tweetgram/internal/server"
+	"github.com/javiyt/tweetgram/internal/tracing"
+	"github.com/sirupsen/logrus"
 	"github.com/subosito/gotenv"
 )
 
+const (
+	ageHeader = "age-encryption.org/v1"
+	envKeyVar = "ENV_AGE_KEY"
+)
+
 type botProvider func() (bot.AppBot, error)
 
+type configProvider func() (config.AppConfig, error)
+
+type readinessChecker interface {
+	Ready() error
+}
+
 type App struct {
-	bp botProvider
-	tb bot.AppBot
-	hm *handlers.Manager
+	bp      botProvider
+	cp      configProvider
+	cfg     config.AppConfig
+	log     *logrus.Logger
+	tb      bot.AppBot
+	hm      *handlers.Manager
+	srv     *server.Server
+	th      readinessChecker
+	running atomic.Bool
+}
+
+type Option func(a *App)
+
+func WithConfig(cfg config.AppConfig) Option {
+	return func(a *App) {
+		a.cfg = cfg
+	}
+}
+
+// WithConfigProvider sets how the configuration is read again on reload.
+func WithConfigProvider(cp configProvider) Option {
+	return func(a *App) {
+		a.cp = cp
+	}
 }
 
-func InitializeConfiguration(testBot bool, envFile []byte, envTestFile []byte) error {
-	err := gotenv.OverApply(bytes.NewReader(envFile))
+func WithLogger(log *logrus.Logger) Option {
+	return func(a *App) {
+		a.log = log
+	}
+}
+
+// WithTelegramHealth sets what tells readiness checks whether updates are
+// being received from Telegram.
+func WithTelegramHealth(th readinessChecker) Option {
+	return func(a *App) {
+		a.th = th
+	}
+}
+
+// InitializeConfiguration loads the embedded env files and, when given, sets
+// the configuration file read for the keys missing in the environment. The env
+// files come last, so the configuration file overrides them.
+func InitializeConfiguration(testBot bool, configFile string, envFile []byte, envTestFile []byte) error {
+	env, err := readEnv(envFile)
 	if err != nil {
 		return fmt.Errorf("error loading env file: %w", err)
 	}
 
 	if testBot {
-		err := gotenv.OverApply(bytes.NewReader(envTestFile))
+		testEnv, err := readEnv(envTestFile)
 		if err != nil {
 			return fmt.Errorf("error loading env.test file: %w", err)
 		}
+
+		for k, v := range testEnv {
+			env[k] = v
+		}
+	}
+
+	config.Embed(env)
+
+	if configFile != "" {
+		return os.Setenv(config.FileKey, configFile)
 	}
 
 	return nil
 }
 
-func NewApp(bp botProvider, hm *handlers.Manager) *App {
+func readEnv(content []byte) (map[string]string, error) {
+	content, err := decryptEnv(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return gotenv.StrictParse(bytes.NewReader(content))
+}
+
+// decryptEnv returns the env file as is unless it's age encrypted, then it's
+// decrypted with the identities in ENV_AGE_KEY or in the file set in
+// ENV_AGE_KEY_FILE, so the binary doesn't carry the secrets in plaintext.
+func decryptEnv(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, []byte(ageHeader)) {
+		return content, nil
+	}
+
+	keys := os.Getenv(envKeyVar)
+	if path := os.Getenv(envKeyVar + "_FILE"); keys == "" && path != "" {
+		k, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = string(k)
+	}
+
+	if keys == "" {
+		return nil, fmt.Errorf("file is encrypted and %s is not set", envKeyVar)
+	}
+
+	identities, err := age.ParseIdentities(strings.NewReader(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(content), identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+// InitializeTracing installs the tracer provider selected in the configuration,
+// the returned function flushes the spans pending to be exported.
+func InitializeTracing(ctx context.Context) (func(context.Context) error, error) {
+	cfg, err := provideConfiguration()
+	if err != nil {
+		return nil, err
+	}
+
+	shutdown, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing tracing: %w", err)
+	}
+
+	return shutdown, nil
+}
+
+func NewApp(bp botProvider, hm *handlers.Manager, srv *server.Server, options ...Option) *App {
 	if bp == nil {
 		bp = provideBot
 	}
 
-	return &App{bp: bp, hm: hm}
+	a := &App{bp: bp, cp: provideConfiguration, log: logging.Discard(), hm: hm, srv: srv}
+
+	for _, o := range options {
+		o(a)
+	}
+
+	return a
 }
 
 func (a *App) Start(ctx context.Context) error {
@@ -55,13 +193,97 @@ func (a *App) Start(ctx context.Context) error {
 	a.hm.StartHandlers(ctx)
 	a.tb = tBot
 
+	if a.srv != nil {
+		a.srv.Handle("/healthz", http.HandlerFunc(a.healthz))
+		a.srv.Handle("/readyz", http.HandlerFunc(a.readyz))
+		a.srv.Handle("/metrics", metrics.Handler())
+
+		if err := a.srv.Start(); err != nil {
+			return fmt.Errorf("error starting http server: %w", err)
+		}
+	}
+
 	return nil
 }
 
 func (a *App) Run() {
+	a.running.Store(true)
+	defer a.running.Store(false)
+
 	a.tb.Run()
 }
 
+// Reload reads the configuration again and, when valid, hands the settings
+// that can change while running to the bot and the handlers. What changed, or
+// why the new configuration was rejected, is logged and, with RELOAD_NOTIFY,
+// sent to the admins.
+func (a *App) Reload(ctx context.Context) error {
+	cfg, changes, err := a.reloadConfig()
+	if err != nil {
+		a.log.WithContext(ctx).WithError(err).Error("configuration reload rejected")
+		a.notifyAdmins(ctx, i18n.T(a.lang(), "reload.rejected", err.Error()))
+
+		return err
+	}
+
+	a.cfg = cfg
+	a.tb.Reload(cfg)
+	a.hm.Reload(cfg)
+
+	a.log.WithContext(ctx).WithFields(logrus.Fields{
+		"reloaded":         changes.Reloaded,
+		"restart_required": changes.Restart,
+	}).Info("configuration reloaded")
+	a.notifyAdmins(ctx, reloadSummary(a.lang(), changes))
+
+	return nil
+}
+
+func (a *App) reloadConfig() (config.AppConfig, config.Changes, error) {
+	updated, err := a.cp()
+	if err != nil {
+		return config.AppConfig{}, config.Changes{}, err
+	}
+
+	return a.cfg.Reload(updated)
+}
+
+func (a *App) notifyAdmins(ctx context.Context, text string) {
+	if !a.cfg.ReloadNotify {
+		return
+	}
+
+	if err := a.tb.NotifyAdmins(ctx, text); err != nil {
+		a.log.WithContext(ctx).WithError(err).Error("error notifying configuration reload")
+	}
+}
+
+// lang is the language the admins are told about reloads in.
+func (a *App) lang() string {
+	return i18n.Language("", a.cfg.DefaultLanguage)
+}
+
+func reloadSummary(lang string, changes config.Changes) string {
+	if len(changes.Reloaded) == 0 && len(changes.Restart) == 0 {
+		return i18n.T(lang, "reload.unchanged")
+	}
+
+	summary := i18n.T(lang, "reload.done")
+	if len(changes.Reloaded) > 0 {
+		summary += "\n" + i18n.T(lang, "reload.applied", strings.Join(changes.Reloaded, ", "))
+	}
+
+	if len(changes.Restart) > 0 {
+		summary += "\n" + i18n.T(lang, "reload.restart", strings.Join(changes.Restart, ", "))
+	}
+
+	return summary
+}
+
 func (a *App) Stop() {
+	if a.srv != nil {
+		_ = a.srv.Stop(context.Background())
+	}
+
 	a.tb.Stop()
 }
@@ -1,17 +1,28 @@
 package app_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
+	"filippo.io/age"
 	"github.com/ThreeDotsLabs/watermill/message"
 	pubsub2 "github.com/javiyt/tweetgram/internal/pubsub"
 	"github.com/javiyt/tweetgram/mocks/pubsub"
 
 	"github.com/javiyt/tweetgram/internal/app"
 	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
 	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/server"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	mockBot "github.com/javiyt/tweetgram/mocks/bot"
@@ -33,34 +44,115 @@ func TestInitializeConfiguration(t *testing.T) {
 	envFile := []byte("BOT_TOKEN=asdfg")
 	envTestFile := []byte("BOT_TOKEN=qwert")
 
+	t.Cleanup(func() { config.Embed(nil) })
+
 	t.Run("it should load configuration from environment file when not in test env", func(t *testing.T) {
-		e := app.InitializeConfiguration(false, envFile, envTestFile)
+		e := app.InitializeConfiguration(false, "", envFile, envTestFile)
 
 		require.NoError(t, e)
-		require.Equal(t, "asdfg", os.Getenv("BOT_TOKEN"))
-		_ = os.Unsetenv("BOT_TOKEN")
+		require.Equal(t, "asdfg", embeddedBotToken(t))
 	})
 
 	t.Run("it should load test configuration from environment file when in test env", func(t *testing.T) {
-		e := app.InitializeConfiguration(true, envFile, envTestFile)
+		e := app.InitializeConfiguration(true, "", envFile, envTestFile)
 
 		require.NoError(t, e)
-		require.Equal(t, "qwert", os.Getenv("BOT_TOKEN"))
-		_ = os.Unsetenv("BOT_TOKEN")
+		require.Equal(t, "qwert", embeddedBotToken(t))
 	})
 
 	t.Run("it should fail when not a valid env file", func(t *testing.T) {
-		e := app.InitializeConfiguration(true, []byte("BOT_TOKEN"), envTestFile)
+		e := app.InitializeConfiguration(true, "", []byte("BOT_TOKEN"), envTestFile)
 
 		require.EqualError(t, e, "error loading env file: line `BOT_TOKEN` doesn't match format")
 	})
 
 	t.Run("it should fail when not a valid env.test file", func(t *testing.T) {
-		e := app.InitializeConfiguration(true, envFile, []byte("BOT_TOKEN"))
+		e := app.InitializeConfiguration(true, "", envFile, []byte("BOT_TOKEN"))
 
 		require.EqualError(t, e, "error loading env.test file: line `BOT_TOKEN` doesn't match format")
-		_ = os.Unsetenv("BOT_TOKEN")
 	})
+
+	t.Run("it should decrypt age encrypted env file", func(t *testing.T) {
+		identity, err := age.GenerateX25519Identity()
+		require.NoError(t, err)
+
+		var encrypted bytes.Buffer
+		w, err := age.Encrypt(&encrypted, identity.Recipient())
+		require.NoError(t, err)
+		_, _ = w.Write(envFile)
+		require.NoError(t, w.Close())
+
+		t.Setenv("ENV_AGE_KEY", identity.String())
+
+		e := app.InitializeConfiguration(false, "", encrypted.Bytes(), envTestFile)
+
+		require.NoError(t, e)
+		require.Equal(t, "asdfg", embeddedBotToken(t))
+
+		keyFile := filepath.Join(t.TempDir(), "key.txt")
+		require.NoError(t, os.WriteFile(keyFile, []byte("# created: today\n"+identity.String()+"\n"), 0o600))
+		t.Setenv("ENV_AGE_KEY", "")
+		t.Setenv("ENV_AGE_KEY_FILE", keyFile)
+
+		e = app.InitializeConfiguration(false, "", encrypted.Bytes(), envTestFile)
+
+		require.NoError(t, e)
+		require.Equal(t, "asdfg", embeddedBotToken(t))
+	})
+
+	t.Run("it should fail when env file is encrypted and key is not set", func(t *testing.T) {
+		t.Setenv("ENV_AGE_KEY", "")
+		t.Setenv("ENV_AGE_KEY_FILE", "")
+
+		e := app.InitializeConfiguration(false, "", []byte("age-encryption.org/v1\n"), envTestFile)
+
+		require.EqualError(t, e, "error loading env file: file is encrypted and ENV_AGE_KEY is not set")
+	})
+
+	t.Run("it should set configuration file", func(t *testing.T) {
+		t.Setenv(config.FileKey, "")
+
+		e := app.InitializeConfiguration(false, "config.yaml", envFile, envTestFile)
+
+		require.NoError(t, e)
+		require.Equal(t, "config.yaml", os.Getenv(config.FileKey))
+	})
+
+	t.Run("it should prefer the configuration file over the env files", func(t *testing.T) {
+		t.Setenv(config.FileKey, "")
+
+		configFile := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(configFile, []byte("bot_token: zxcvb\n"), 0o600))
+
+		e := app.InitializeConfiguration(false, configFile, envFile, envTestFile)
+
+		require.NoError(t, e)
+		require.Equal(t, "zxcvb", embeddedBotToken(t))
+	})
+}
+
+// embeddedBotToken reads the configuration with every required setting but
+// the bot token in the environment, so it's the one from the env files.
+func embeddedBotToken(t *testing.T) string {
+	t.Helper()
+
+	for k, v := range map[string]string{
+		"ADMINS":                "12345",
+		"BROADCAST_CHANNEL":     "9876543",
+		"TWITTER_API_KEY":       "asdfg1234",
+		"TWITTER_API_SECRET":    "poiuyt",
+		"TWITTER_BEARER_TOKEN":  "qwertyui",
+		"TWITTER_ACCESS_TOKEN":  "zxcvbnm",
+		"TWITTER_ACCESS_SECRET": "lkjhgfd",
+		"ENVIRONMENT":           "testing",
+	} {
+		t.Setenv(k, v)
+	}
+
+	cfg, err := config.NewAppConfig()
+	require.NoError(t, err)
+
+	return cfg.BotToken
 }
 
 func TestStart(t *testing.T) {
@@ -69,7 +161,7 @@ func TestStart(t *testing.T) {
 			return nil, botInstanceError{}
 		}
 
-		a := app.NewApp(mbp, handlers.NewHandlersManager(nil))
+		a := app.NewApp(mbp, handlers.NewHandlersManager(nil), nil)
 		e := a.Start(context.Background())
 
 		require.EqualError(t, e, "error getting bot instance: bot instance not ready")
@@ -82,13 +174,32 @@ func TestStart(t *testing.T) {
 		}
 		mb.On("Start", context.Background()).Once().Return(startAppError{})
 
-		a := app.NewApp(mbp, handlers.NewHandlersManager(nil))
+		a := app.NewApp(mbp, handlers.NewHandlersManager(nil), nil)
 		e := a.Start(context.Background())
 
 		require.EqualError(t, e, "error starting bot: could not start")
 		mb.AssertExpectations(t)
 	})
 
+	t.Run("it should fail when starting http server", func(t *testing.T) {
+		q := new(pubsub.Queue)
+		mb := new(mockBot.AppBot)
+		mbp := func() (bot.AppBot, error) {
+			return mb, nil
+		}
+		mb.On("Start", context.Background()).Once().Return(nil)
+		q.On("Subscribe", context.Background(), pubsub2.CommandTopic.String()).
+			Return(func(context.Context, string) <-chan *message.Message {
+				return make(chan *message.Message)
+			}, nil)
+
+		a := app.NewApp(mbp, handlers.NewHandlersManager(q), server.NewServer("invalid:address:1"))
+		e := a.Start(context.Background())
+
+		require.ErrorContains(t, e, "error starting http server:")
+		mb.AssertExpectations(t)
+	})
+
 	t.Run("it should start bot instance", func(t *testing.T) {
 		q := new(pubsub.Queue)
 		mb := new(mockBot.AppBot)
@@ -101,7 +212,7 @@ func TestStart(t *testing.T) {
 				return make(chan *message.Message)
 			}, nil)
 
-		a := app.NewApp(mbp, handlers.NewHandlersManager(q))
+		a := app.NewApp(mbp, handlers.NewHandlersManager(q), nil)
 		e := a.Start(context.Background())
 
 		require.NoError(t, e)
@@ -123,7 +234,7 @@ func TestRun(t *testing.T) {
 			return make(chan *message.Message)
 		}, nil)
 
-	a := app.NewApp(mbp, handlers.NewHandlersManager(q))
+	a := app.NewApp(mbp, handlers.NewHandlersManager(q), nil)
 	_ = a.Start(context.Background())
 	a.Run()
 
@@ -144,10 +255,199 @@ func TestStop(t *testing.T) {
 			return make(chan *message.Message)
 		}, nil)
 
-	a := app.NewApp(mbp, handlers.NewHandlersManager(q))
+	a := app.NewApp(mbp, handlers.NewHandlersManager(q), nil)
 	e := a.Start(context.Background())
 	a.Stop()
 
 	require.NoError(t, e)
 	mb.AssertExpectations(t)
 }
+
+type readiness struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (r *readiness) Ready() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *readiness) set(err error) {
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	q := new(pubsub.Queue)
+	mb := new(mockBot.AppBot)
+	mbp := func() (bot.AppBot, error) {
+		return mb, nil
+	}
+	srv := server.NewServer("127.0.0.1:0")
+	running := make(chan struct{})
+	stopped := make(chan struct{})
+
+	mb.On("Start", context.Background()).Once().Return(nil)
+	mb.On("Run").Once().Run(func(mock.Arguments) {
+		close(running)
+		<-stopped
+	})
+	mb.On("Stop").Once().Run(func(mock.Arguments) {
+		close(stopped)
+	})
+	q.On("Subscribe", context.Background(), pubsub2.CommandTopic.String()).
+		Return(func(context.Context, string) <-chan *message.Message {
+			return make(chan *message.Message)
+		}, nil)
+	th := &readiness{err: errors.New("not receiving updates from Telegram")}
+
+	a := app.NewApp(mbp, handlers.NewHandlersManager(handlers.NewMonitor(q, time.Minute)), srv, app.WithTelegramHealth(th))
+	require.NoError(t, a.Start(context.Background()))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec
+	}
+
+	t.Run("it should report process alive", func(t *testing.T) {
+		rec := get("/healthz")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, "{\"status\":\"ok\"}", rec.Body.String())
+	})
+
+	t.Run("it should not be ready until bot receives updates", func(t *testing.T) {
+		rec := get("/readyz")
+
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.JSONEq(
+			t,
+			"{\"status\":\"unavailable\",\"checks\":{\"queue\":\"ok\",\"telegram_bot\":\"bot is not receiving updates\"}}",
+			rec.Body.String(),
+		)
+	})
+
+	t.Run("it should not be ready while Telegram can't be polled", func(t *testing.T) {
+		go a.Run()
+		<-running
+
+		rec := get("/readyz")
+
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.JSONEq(
+			t,
+			"{\"status\":\"unavailable\",\"checks\":{\"queue\":\"ok\","+
+				"\"telegram_bot\":\"not receiving updates from Telegram\"}}",
+			rec.Body.String(),
+		)
+	})
+
+	t.Run("it should be ready while bot receives updates", func(t *testing.T) {
+		th.set(nil)
+
+		rec := get("/readyz")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(
+			t,
+			"{\"status\":\"ok\",\"checks\":{\"queue\":\"ok\",\"telegram_bot\":\"ok\"}}",
+			rec.Body.String(),
+		)
+	})
+
+	a.Stop()
+	mb.AssertExpectations(t)
+}
+
+type reloadableHandler struct {
+	cfg config.AppConfig
+}
+
+func (h *reloadableHandler) ID() string {
+	return "reloadable"
+}
+
+func (h *reloadableHandler) ExecuteHandlers(context.Context) {}
+
+func (h *reloadableHandler) StopNotifications() {}
+
+func (h *reloadableHandler) Reload(cfg config.AppConfig) {
+	h.cfg = cfg
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	current := config.AppConfig{Admins: []int{12345}, LogFormat: "text", ReloadNotify: true}
+
+	generateApp := func(updated config.AppConfig, err error) (*app.App, *mockBot.AppBot, *reloadableHandler) {
+		q := new(pubsub.Queue)
+		mb := new(mockBot.AppBot)
+		h := &reloadableHandler{cfg: current}
+
+		mb.On("Start", ctx).Once().Return(nil)
+		q.On("Subscribe", ctx, pubsub2.CommandTopic.String()).
+			Return(func(context.Context, string) <-chan *message.Message {
+				return make(chan *message.Message)
+			}, nil)
+
+		a := app.NewApp(
+			func() (bot.AppBot, error) { return mb, nil },
+			handlers.NewHandlersManager(q, h),
+			nil,
+			app.WithConfig(current),
+			app.WithConfigProvider(func() (config.AppConfig, error) { return updated, err }),
+		)
+		require.NoError(t, a.Start(ctx))
+
+		return a, mb, h
+	}
+
+	t.Run("it should hand reloadable settings to bot and handlers", func(t *testing.T) {
+		updated := current
+		updated.Admins = []int{12345, 6789}
+		updated.LogFormat = "json"
+
+		reloaded := current
+		reloaded.Admins = updated.Admins
+
+		a, mb, h := generateApp(updated, nil)
+		mb.On("Reload", reloaded).Once()
+		mb.On("NotifyAdmins", ctx, "Configuration reloaded\nApplied: ADMINS\nNeeds a restart: LOG_FORMAT").
+			Once().
+			Return(nil)
+
+		require.NoError(t, a.Reload(ctx))
+		require.Equal(t, reloaded, h.cfg)
+		mb.AssertExpectations(t)
+	})
+
+	t.Run("it should keep configuration when new one is invalid", func(t *testing.T) {
+		a, mb, h := generateApp(config.AppConfig{}, config.ValidationError{"ADMINS: missing value"})
+		mb.On("NotifyAdmins", ctx, "Configuration reload rejected, invalid configuration:\n  - ADMINS: missing value").
+			Once().
+			Return(nil)
+
+		require.EqualError(t, a.Reload(ctx), "invalid configuration:\n  - ADMINS: missing value")
+		require.Equal(t, current, h.cfg)
+		mb.AssertNotCalled(t, "Reload", mock.Anything)
+		mb.AssertExpectations(t)
+	})
+
+	t.Run("it should tell admins about the reload in the default language", func(t *testing.T) {
+		updated := current
+		updated.DefaultLanguage = "es"
+
+		a, mb, _ := generateApp(updated, nil)
+		mb.On("Reload", updated).Once()
+		mb.On("NotifyAdmins", ctx, "Configuración recargada\nAplicado: DEFAULT_LANGUAGE").Once().Return(nil)
+
+		require.NoError(t, a.Reload(ctx))
+		mb.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var errBotNotRunning = errors.New("bot is not receiving updates")
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func (a *App) healthz(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+func (a *App) readyz(w http.ResponseWriter, _ *http.Request) {
+	status := a.hm.Status()
+
+	switch {
+	case !a.running.Load():
+		status["telegram_bot"] = errBotNotRunning
+	case a.th != nil:
+		status["telegram_bot"] = a.th.Ready()
+	default:
+		status["telegram_bot"] = nil
+	}
+
+	resp := healthResponse{Status: "ok", Checks: make(map[string]string, len(status))}
+	code := http.StatusOK
+
+	for name, err := range status {
+		resp.Checks[name] = "ok"
+
+		if err != nil {
+			resp.Checks[name] = err.Error()
+			resp.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	writeHealth(w, code, resp)
+}
+
+func writeHealth(w http.ResponseWriter, code int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -4,19 +4,32 @@
 package app
 
 import (
+	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/javiyt/tweetgram/internal/telegram"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/javiyt/tweetgram/internal/audit"
 	"github.com/javiyt/tweetgram/internal/handlers"
+	hsa "github.com/javiyt/tweetgram/internal/handlers/archive"
+	hsm "github.com/javiyt/tweetgram/internal/handlers/email"
 	hse "github.com/javiyt/tweetgram/internal/handlers/error"
+	hsf "github.com/javiyt/tweetgram/internal/handlers/feed"
+	hsmn "github.com/javiyt/tweetgram/internal/handlers/mentions"
+	hsr "github.com/javiyt/tweetgram/internal/handlers/receipts"
 	hstl "github.com/javiyt/tweetgram/internal/handlers/telegram"
+	hsti "github.com/javiyt/tweetgram/internal/handlers/timeline"
 	hstw "github.com/javiyt/tweetgram/internal/handlers/twitter"
+	"github.com/javiyt/tweetgram/internal/logging"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/server"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/translate"
 	"github.com/sirupsen/logrus"
 
 	"github.com/dghubble/oauth1"
@@ -32,29 +45,89 @@ import (
 type customHandlerGenerator func() []handlers.EventHandler
 
 var (
-	queueInstance *gochannel.GoChannel
-	twitterClient = wire.NewSet(
+	queueInstance     *handlers.Monitor
+	healthInstance    *telegram.Health
+	storeInstance     *storage.FileStore
+	auditInstance     *audit.FileLog
+	tableInstance     *translate.Table
+	publishedInstance *hstw.Published
+	loggerInstance    *logrus.Logger
+	logFile           *os.File
+	twitterClient     = wire.NewSet(
 		provideTwitterHttpClient,
 		provideTwitterClient,
 		wire.Bind(new(bot.TwitterClient), new(*twitter.Client)),
 	)
-	queue        = wire.NewSet(provideGoChannelQueue, wire.Bind(new(pubsub.Queue), new(*gochannel.GoChannel)))
-	telegramDeps = wire.NewSet(provideConfiguration, provideTBot, queue)
-	twitterDeps  = wire.NewSet(provideConfiguration, twitterClient, queue)
-	errorDeps    = wire.NewSet(provideConfiguration, queue, provideLogger)
-	tbBot        = wire.NewSet(provideConfiguration, provideTBotSettings, tb.NewBot, wire.Bind(new(telegram.TbBot), new(*tb.Bot)))
+	queue        = wire.NewSet(provideQueue, wire.Bind(new(pubsub.Queue), new(*handlers.Monitor)))
+	telegramDeps = wire.NewSet(provideConfiguration, provideTBot, queue, store, provideTranslations, provideLogger)
+	twitterDeps  = wire.NewSet(
+		provideConfiguration,
+		twitterClient,
+		queue,
+		store,
+		provideTranslations,
+		providePublished,
+		provideLogger,
+	)
+	errorDeps    = wire.NewSet(provideConfiguration, provideTBot, queue, store, provideLogger)
+	store        = wire.NewSet(provideFileStore, wire.Bind(new(storage.Store), new(*storage.FileStore)))
+	feedDeps     = wire.NewSet(provideConfiguration, queue, store, provideTranslations, provideLogger)
+	emailDeps    = wire.NewSet(provideConfiguration, queue, store, provideTranslations, provideMailer, provideLogger)
+	archiveDeps  = wire.NewSet(provideConfiguration, queue, store, provideTranslations, provideLogger)
+	receiptsDeps = wire.NewSet(provideConfiguration, provideTBot, queue, auditLog, provideLogger)
+	auditLog     = wire.NewSet(provideAuditLog, wire.Bind(new(audit.Log), new(*audit.FileLog)))
+	timelineDeps = wire.NewSet(
+		provideConfiguration,
+		provideLogger,
+		queue,
+		store,
+		provideTwitterHttpClient,
+		provideTwitterClient,
+		wire.Bind(new(hsti.TimelineClient), new(*twitter.Client)),
+	)
+	mentionsDeps = wire.NewSet(
+		provideConfiguration,
+		provideLogger,
+		provideTBot,
+		queue,
+		store,
+		provideTwitterHttpClient,
+		provideTwitterClient,
+		wire.Bind(new(hsmn.MentionsClient), new(*twitter.Client)),
+	)
+	tbBot = wire.NewSet(
+		provideConfiguration,
+		provideTelegramHealth,
+		provideTBotSettings,
+		provideTBotOptions,
+		tb.NewBot,
+		wire.Bind(new(telegram.TbBot), new(*tb.Bot)),
+	)
 )
 
 func ProvideApp() (*App, func(), error) {
 	panic(wire.Build(
+		provideConfiguration,
+		provideLogger,
+		provideTelegramHealth,
+		provideAppOptions,
 		provideBotProvider,
 		initializeCustomHandlers,
 		provideHandlers,
 		wire.NewSet(queue, provideHandlerManager),
+		provideServer,
 		NewApp,
 	))
 }
 
+func provideAppOptions(cfg config.AppConfig, log *logrus.Logger, h *telegram.Health) []Option {
+	return []Option{
+		WithConfig(cfg),
+		WithLogger(log),
+		WithTelegramHealth(h),
+	}
+}
+
 func provideBotProvider() botProvider {
 	return provideBot
 }
@@ -65,6 +138,12 @@ func provideBot() (bot.AppBot, error) {
 		provideTBot,
 		twitterClient,
 		queue,
+		store,
+		provideTranslations,
+		providePublished,
+		wire.Bind(new(bot.PublishedRecorder), new(*hstw.Published)),
+		auditLog,
+		provideLogger,
 		provideBotOptions,
 		bot.NewBot,
 	))
@@ -78,11 +157,28 @@ func provideTBot() (bot.TelegramBot, error) {
 	panic(wire.Build(tbBot, telegram.NewBot))
 }
 
-func provideTBotSettings(cfg config.AppConfig) tb.Settings {
+func provideTBotSettings(cfg config.AppConfig, h *telegram.Health) tb.Settings {
 	return tb.Settings{
 		Token:  cfg.BotToken,
-		Poller: &tb.LongPoller{Timeout: 10 * time.Second},
+		Poller: telegram.NewPoller(cfg, h),
+		Client: &http.Client{Timeout: time.Minute, Transport: h.Transport(nil)},
+	}
+}
+
+func provideTelegramHealth(cfg config.AppConfig) *telegram.Health {
+	if healthInstance == nil {
+		healthInstance = telegram.NewHealth(cfg)
+	}
+
+	return healthInstance
+}
+
+func provideTBotOptions(cfg config.AppConfig) []telegram.Option {
+	if cfg.IsWebhook() {
+		return []telegram.Option{telegram.WithWebhook()}
 	}
+
+	return nil
 }
 
 func provideTwitterClient(*http.Client) *twitter.Client {
@@ -92,68 +188,121 @@ func provideTwitterClient(*http.Client) *twitter.Client {
 }
 
 func provideTwitterHttpClient(cfg config.AppConfig) *http.Client {
-	return oauth1.NewConfig(cfg.TwitterAPIKey, cfg.TwitterAPISecret).
-		Client(oauth1.NoContext, oauth1.NewToken(cfg.TwitterAccessToken, cfg.TwitterAccessSecret))
+	return oauth1.NewConfig(cfg.Twitter.APIKey, cfg.Twitter.APISecret).
+		Client(oauth1.NoContext, oauth1.NewToken(cfg.Twitter.AccessToken, cfg.Twitter.AccessSecret))
 }
 
-func provideGoChannelQueue() *gochannel.GoChannel {
+func provideQueue(cfg config.AppConfig) *handlers.Monitor {
 	if queueInstance == nil {
-		queueInstance = gochannel.NewGoChannel(
-			gochannel.Config{},
-			watermill.NewStdLogger(true, true),
+		queueInstance = handlers.NewMonitor(
+			gochannel.NewGoChannel(
+				gochannel.Config{},
+				watermill.NewStdLogger(true, true),
+			),
+			cfg.HandlerStuckTimeout,
 		)
 	}
 	return queueInstance
 }
 
-func provideBotOptions(b bot.TelegramBot, cfg config.AppConfig, tc bot.TwitterClient, gq pubsub.Queue) []bot.Option {
+func provideBotOptions(
+	b bot.TelegramBot,
+	cfg config.AppConfig,
+	tc bot.TwitterClient,
+	pr bot.PublishedRecorder,
+	gq pubsub.Queue,
+	s storage.Store,
+	tr *translate.Table,
+	al audit.Log,
+	log *logrus.Logger,
+) []bot.Option {
 	return []bot.Option{
 		bot.WithTelegramBot(b),
 		bot.WithConfig(cfg),
 		bot.WithTwitterClient(tc),
+		bot.WithPublishedRecorder(pr),
 		bot.WithQueue(gq),
+		bot.WithStore(s),
+		bot.WithTranslations(tr),
+		bot.WithAuditLog(al),
+		bot.WithLogger(log),
 	}
 }
 
-func provideLogger(cfg config.AppConfig) (*logrus.Logger, func()) {
-	var (
-		file *os.File
-		err  error
-	)
+func provideFileStore(cfg config.AppConfig) *storage.FileStore {
+	if storeInstance == nil {
+		storeInstance = storage.NewFileStore(cfg.StoragePath)
+	}
+	return storeInstance
+}
+
+func provideAuditLog(cfg config.AppConfig) *audit.FileLog {
+	if auditInstance == nil {
+		auditInstance = audit.NewFileLog(filepath.Join(cfg.StoragePath, "audit.jsonl"))
+	}
+	return auditInstance
+}
 
-	logger := logrus.New()
-	logger.SetFormatter(&logrus.TextFormatter{
-		DisableColors: true,
-		FullTimestamp: true,
-	})
-	logger.SetReportCaller(true)
-
-	lvl := logrus.DebugLevel
-	if cfg.IsProd() {
-		lvl = logrus.ErrorLevel
-
-		if cfg.LogFile != "" {
-			file, err = os.OpenFile(cfg.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o755)
-			if err != nil {
-				logger.Fatal(err)
-			}
-			logger.SetOutput(file)
+// provideTranslations shares the mentions and hashtags rewritten by owners
+// between the bot, where they are managed, and the handlers.
+func provideTranslations(s storage.Store) (*translate.Table, error) {
+	if tableInstance == nil {
+		t := translate.NewTable(s)
+		if err := t.Load(); err != nil {
+			return nil, err
 		}
+		tableInstance = t
 	}
+	return tableInstance, nil
+}
 
-	logger.SetLevel(lvl)
+// providePublished shares the tweets sent by tweetgram between the Twitter
+// handler and the bot, which sends the replies.
+func providePublished(s storage.Store) *hstw.Published {
+	if publishedInstance == nil {
+		publishedInstance = hstw.NewPublished(s)
+	}
+	return publishedInstance
+}
 
-	return logger, func() {
-		logger.Exit(0)
-		_ = file.Close()
+func provideLogger(cfg config.AppConfig) *logrus.Logger {
+	if loggerInstance != nil {
+		return loggerInstance
 	}
+
+	out := io.Writer(os.Stderr)
+	if cfg.IsProd() && cfg.LogFile != "" {
+		file, err := os.OpenFile(cfg.LogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o755)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		logFile = file
+		out = file
+	}
+
+	loggerInstance = logging.NewLogger(cfg, out)
+
+	return loggerInstance
+}
+
+func closeLogger() {
+	loggerInstance.Exit(0)
+	_ = logFile.Close()
 }
 
-func provideTelegramOptions(cfg config.AppConfig, tb bot.TelegramBot, pq pubsub.Queue) []hstl.Option {
+func provideTelegramOptions(
+	cfg config.AppConfig,
+	tb bot.TelegramBot,
+	pq pubsub.Queue,
+	tr *translate.Table,
+	log *logrus.Logger,
+) []hstl.Option {
 	return []hstl.Option{
 		hstl.WithAppConfig(cfg),
 		hstl.WithTelegramBot(tb),
 		hstl.WithQueue(pq),
+		hstl.WithTranslations(tr),
+		hstl.WithLogger(log),
 	}
 }
 
@@ -161,10 +310,21 @@ func provideTelegramHandler() (*hstl.Telegram, error) {
 	panic(wire.Build(telegramDeps, provideTelegramOptions, hstl.NewTelegram))
 }
 
-func provideTwitterOptions(tc bot.TwitterClient, pq pubsub.Queue) []hstw.Option {
+func provideTwitterOptions(
+	cfg config.AppConfig,
+	tc bot.TwitterClient,
+	pq pubsub.Queue,
+	p *hstw.Published,
+	tr *translate.Table,
+	log *logrus.Logger,
+) []hstw.Option {
 	return []hstw.Option{
+		hstw.WithAppConfig(cfg),
 		hstw.WithTwitterClient(tc),
 		hstw.WithQueue(pq),
+		hstw.WithTranslations(tr),
+		hstw.WithPublished(p),
+		hstw.WithLogger(log),
 	}
 }
 
@@ -172,11 +332,160 @@ func provideTwitterHandler() (*hstw.Twitter, error) {
 	panic(wire.Build(twitterDeps, provideTwitterOptions, hstw.NewTwitter))
 }
 
-func provideErrorHandler() (*hse.ErrorHandler, func(), error) {
-	panic(wire.Build(errorDeps, hse.NewErrorHandler))
+func provideErrorOptions(cfg config.AppConfig, tb bot.TelegramBot, s storage.Store) []hse.Option {
+	return []hse.Option{
+		hse.WithAppConfig(cfg),
+		hse.WithTelegramBot(tb),
+		hse.WithStore(s),
+	}
+}
+
+func provideErrorHandler() (*hse.ErrorHandler, error) {
+	panic(wire.Build(errorDeps, provideErrorOptions, hse.NewErrorHandler))
+}
+
+func provideFeedOptions(
+	cfg config.AppConfig,
+	pq pubsub.Queue,
+	s storage.Store,
+	tr *translate.Table,
+	log *logrus.Logger,
+) []hsf.Option {
+	return []hsf.Option{
+		hsf.WithAppConfig(cfg),
+		hsf.WithQueue(pq),
+		hsf.WithTranslations(tr),
+		hsf.WithStore(s),
+		hsf.WithLogger(log),
+	}
+}
+
+func provideFeedHandler() (*hsf.Feed, error) {
+	panic(wire.Build(feedDeps, provideFeedOptions, hsf.NewFeed))
+}
+
+func provideMailer(cfg config.AppConfig) hsm.Mailer {
+	var options []hsm.SMTPOption
+	if cfg.SMTP.Username != "" {
+		options = append(options, hsm.WithCredentials(cfg.SMTP.Username, cfg.SMTP.Password))
+	}
+	if cfg.SMTP.StartTLS {
+		options = append(options, hsm.WithStartTLS(nil))
+	}
+
+	return hsm.NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, options...)
+}
+
+func provideEmailOptions(
+	cfg config.AppConfig,
+	pq pubsub.Queue,
+	s storage.Store,
+	m hsm.Mailer,
+	tr *translate.Table,
+	log *logrus.Logger,
+) []hsm.Option {
+	return []hsm.Option{
+		hsm.WithAppConfig(cfg),
+		hsm.WithQueue(pq),
+		hsm.WithTranslations(tr),
+		hsm.WithStore(s),
+		hsm.WithMailer(m),
+		hsm.WithLogger(log),
+	}
+}
+
+func provideEmailHandler() (*hsm.Email, error) {
+	panic(wire.Build(emailDeps, provideEmailOptions, hsm.NewEmail))
+}
+
+func provideArchiveOptions(
+	cfg config.AppConfig,
+	pq pubsub.Queue,
+	tr *translate.Table,
+	log *logrus.Logger,
+) []hsa.Option {
+	return []hsa.Option{
+		hsa.WithAppConfig(cfg),
+		hsa.WithQueue(pq),
+		hsa.WithTranslations(tr),
+		hsa.WithLogger(log),
+	}
+}
+
+func provideArchiveHandler() (*hsa.Archive, error) {
+	panic(wire.Build(archiveDeps, provideArchiveOptions, hsa.NewArchive))
+}
+
+func provideTimelineOptions(
+	cfg config.AppConfig,
+	pq pubsub.Queue,
+	s storage.Store,
+	tc hsti.TimelineClient,
+	pc hsti.PublishedChecker,
+	log *logrus.Logger,
+) []hsti.Option {
+	return []hsti.Option{
+		hsti.WithAppConfig(cfg),
+		hsti.WithQueue(pq),
+		hsti.WithStore(s),
+		hsti.WithTimelineClient(tc),
+		hsti.WithPublishedChecker(pc),
+		hsti.WithLogger(log),
+	}
+}
+
+func provideTimelineHandler(pc hsti.PublishedChecker) (*hsti.Timeline, error) {
+	panic(wire.Build(timelineDeps, provideTimelineOptions, hsti.NewTimeline))
+}
+
+func provideMentionsOptions(
+	cfg config.AppConfig,
+	tb bot.TelegramBot,
+	pq pubsub.Queue,
+	s storage.Store,
+	mc hsmn.MentionsClient,
+	log *logrus.Logger,
+) []hsmn.Option {
+	return []hsmn.Option{
+		hsmn.WithAppConfig(cfg),
+		hsmn.WithTelegramBot(tb),
+		hsmn.WithQueue(pq),
+		hsmn.WithStore(s),
+		hsmn.WithMentionsClient(mc),
+		hsmn.WithLogger(log),
+	}
+}
+
+func provideMentionsHandler() (*hsmn.Mentions, error) {
+	panic(wire.Build(mentionsDeps, provideMentionsOptions, hsmn.NewMentions))
+}
+
+func provideReceiptsOptions(
+	cfg config.AppConfig,
+	tb bot.TelegramBot,
+	pq pubsub.Queue,
+	al audit.Log,
+	log *logrus.Logger,
+	destinations []string,
+) []hsr.Option {
+	return []hsr.Option{
+		hsr.WithAppConfig(cfg),
+		hsr.WithTelegramBot(tb),
+		hsr.WithQueue(pq),
+		hsr.WithAuditLog(al),
+		hsr.WithLogger(log),
+		hsr.WithDestinations(destinations...),
+	}
 }
 
-func provideHandlers(customHandlers customHandlerGenerator) ([]handlers.EventHandler, func(), error) {
+func provideReceiptsHandler(destinations []string) (*hsr.Receipts, error) {
+	panic(wire.Build(receiptsDeps, provideReceiptsOptions, hsr.NewReceipts))
+}
+
+func provideHandlers(
+	cfg config.AppConfig,
+	customHandlers customHandlerGenerator,
+) ([]handlers.EventHandler, func(), error) {
 	telegramHandler, err := provideTelegramHandler()
 	if err != nil {
 		return nil, nil, err
@@ -185,22 +494,89 @@ func provideHandlers(customHandlers customHandlerGenerator) ([]handlers.EventHan
 	if err != nil {
 		return nil, nil, err
 	}
-	errorHandler, cleanup, err := provideErrorHandler()
+	errorHandler, err := provideErrorHandler()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return append(customHandlers(),
+	hs := append(customHandlers(),
 		telegramHandler,
 		twitterHandler,
 		errorHandler,
-	), cleanup, nil
+	)
+	destinations := []string{telegramHandler.ID(), twitterHandler.ID()}
+
+	if cfg.Feed.Enabled {
+		feedHandler, err := provideFeedHandler()
+		if err != nil {
+			return nil, nil, err
+		}
+		hs = append(hs, feedHandler)
+		destinations = append(destinations, feedHandler.ID())
+	}
+
+	if cfg.SMTP.Enabled {
+		emailHandler, err := provideEmailHandler()
+		if err != nil {
+			return nil, nil, err
+		}
+		hs = append(hs, emailHandler)
+		destinations = append(destinations, emailHandler.ID())
+	}
+
+	if cfg.Archive.Enabled {
+		archiveHandler, err := provideArchiveHandler()
+		if err != nil {
+			return nil, nil, err
+		}
+		hs = append(hs, archiveHandler)
+		destinations = append(destinations, archiveHandler.ID())
+	}
+
+	if cfg.TimelineEnabled {
+		timelineHandler, err := provideTimelineHandler(twitterHandler)
+		if err != nil {
+			return nil, nil, err
+		}
+		hs = append(hs, timelineHandler)
+	}
+
+	if cfg.MentionsEnabled {
+		mentionsHandler, err := provideMentionsHandler()
+		if err != nil {
+			return nil, nil, err
+		}
+		hs = append(hs, mentionsHandler)
+	}
+
+	receiptsHandler, err := provideReceiptsHandler(destinations)
+	if err != nil {
+		return nil, nil, err
+	}
+	hs = append(hs, receiptsHandler)
+
+	return hs, closeLogger, nil
 }
 
 func provideHandlerManager(q pubsub.Queue, h []handlers.EventHandler) *handlers.Manager {
 	return handlers.NewHandlersManager(q, h...)
 }
 
+func provideServer(cfg config.AppConfig, hs []handlers.EventHandler) *server.Server {
+	if cfg.HTTPAddress == "" {
+		return nil
+	}
+
+	var routers []server.Router
+	for _, h := range hs {
+		if r, ok := h.(server.Router); ok {
+			routers = append(routers, r)
+		}
+	}
+
+	return server.NewServer(cfg.HTTPAddress, routers...)
+}
+
 func initializeCustomHandlers() customHandlerGenerator {
 	return func() []handlers.EventHandler {
 		return nil
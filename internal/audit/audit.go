@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of an audited interaction.
+const (
+	OutcomeOK     = "ok"
+	OutcomeDenied = "denied"
+	OutcomeFailed = "failed"
+)
+
+const maxLineSize = 1024 * 1024
+
+// Log keeps a record of the interactions of the staff with the bot, entries
+// are only ever appended.
+type Log interface {
+	Append(Entry) error
+	Last(n int) ([]Entry, error)
+}
+
+// Entry is an interaction with the bot. Published content is only kept as a
+// hash, the results of publishing it are appended later as another entry with
+// the same message UUID.
+type Entry struct {
+	Time         time.Time `json:"time"`
+	SenderID     string    `json:"senderId"`
+	Username     string    `json:"username,omitempty"`
+	Action       string    `json:"action"`
+	Args         string    `json:"args,omitempty"`
+	ContentHash  string    `json:"contentHash,omitempty"`
+	MessageUUID  string    `json:"messageUuid,omitempty"`
+	Destinations []string  `json:"destinations,omitempty"`
+	Posts        []string  `json:"posts,omitempty"`
+	Outcome      string    `json:"outcome"`
+	Err          string    `json:"error,omitempty"`
+}
+
+// FileLog writes every entry as a JSON line at the end of the file.
+type FileLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileLog(path string) *FileLog {
+	return &FileLog{path: path}
+}
+
+func (l *FileLog) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// Last returns the last n entries, oldest first, or all of them when n isn't
+// positive.
+func (l *FileLog) Last(n int) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+		if n > 0 && len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// WriteJSON writes the entries as a JSON array.
+func WriteJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(entries)
+}
+
+// WriteCSV writes the entries with a header, lists are joined by spaces.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{
+		"time", "sender_id", "username", "action", "args", "content_hash", "message_uuid", "destinations", "posts",
+		"outcome", "error",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err := cw.Write([]string{
+			e.Time.UTC().Format(time.RFC3339),
+			e.SenderID,
+			e.Username,
+			e.Action,
+			e.Args,
+			e.ContentHash,
+			e.MessageUUID,
+			strings.Join(e.Destinations, " "),
+			strings.Join(e.Posts, " "),
+			e.Outcome,
+			e.Err,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,114 @@
+package audit_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/audit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "audit.jsonl")
+	l := audit.NewFileLog(path)
+	now := time.Date(2022, 5, 3, 10, 20, 30, 0, time.UTC)
+
+	t.Run("it should return nothing when nothing was recorded", func(t *testing.T) {
+		entries, err := l.Last(10)
+
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("it should append every entry as a json line", func(t *testing.T) {
+		require.NoError(t, l.Append(audit.Entry{Time: now, SenderID: "1234", Action: "/stop", Outcome: audit.OutcomeOK}))
+		require.NoError(t, l.Append(audit.Entry{
+			Time:        now,
+			SenderID:    "1234",
+			Action:      "text",
+			ContentHash: "abc",
+			MessageUUID: "uuid",
+			Outcome:     audit.OutcomeOK,
+		}))
+		require.NoError(t, l.Append(audit.Entry{Time: now, SenderID: "5678", Action: "/stop", Outcome: audit.OutcomeDenied}))
+
+		content, err := os.ReadFile(path)
+
+		require.NoError(t, err)
+		require.Equal(t,
+			"{\"time\":\"2022-05-03T10:20:30Z\",\"senderId\":\"1234\",\"action\":\"/stop\",\"outcome\":\"ok\"}\n"+
+				"{\"time\":\"2022-05-03T10:20:30Z\",\"senderId\":\"1234\",\"action\":\"text\",\"contentHash\":\"abc\","+
+				"\"messageUuid\":\"uuid\",\"outcome\":\"ok\"}\n"+
+				"{\"time\":\"2022-05-03T10:20:30Z\",\"senderId\":\"5678\",\"action\":\"/stop\",\"outcome\":\"denied\"}\n",
+			string(content))
+	})
+
+	t.Run("it should return the last entries oldest first", func(t *testing.T) {
+		entries, err := l.Last(2)
+
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, "text", entries[0].Action)
+		require.Equal(t, "5678", entries[1].SenderID)
+	})
+
+	t.Run("it should return every entry", func(t *testing.T) {
+		entries, err := l.Last(0)
+
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+	})
+
+	t.Run("it should fail when the log is not valid", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("{\"time\""), 0o600))
+
+		_, err := l.Last(0)
+
+		require.EqualError(t, err, "unexpected end of JSON input")
+	})
+}
+
+func TestWrite(t *testing.T) {
+	entries := []audit.Entry{
+		{
+			Time:         time.Date(2022, 5, 3, 10, 20, 30, 0, time.UTC),
+			SenderID:     "1234",
+			Username:     "admin",
+			Action:       "receipt",
+			MessageUUID:  "uuid",
+			Destinations: []string{"telegram", "twitter"},
+			Posts:        []string{"https://t.me/channel/1", "https://twitter.com/i/web/status/2"},
+			Outcome:      audit.OutcomeFailed,
+			Err:          "feed",
+		},
+	}
+
+	t.Run("it should write entries as csv", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		require.NoError(t, audit.WriteCSV(buf, entries))
+		require.Equal(t, "time,sender_id,username,action,args,content_hash,message_uuid,destinations,posts,outcome,error\n"+
+			"2022-05-03T10:20:30Z,1234,admin,receipt,,,uuid,telegram twitter,"+
+			"https://t.me/channel/1 https://twitter.com/i/web/status/2,failed,feed\n", buf.String())
+	})
+
+	t.Run("it should write entries as json", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		require.NoError(t, audit.WriteJSON(buf, entries))
+		require.JSONEq(t, `[{"time":"2022-05-03T10:20:30Z","senderId":"1234","username":"admin","action":"receipt",
+			"messageUuid":"uuid","destinations":["telegram","twitter"],
+			"posts":["https://t.me/channel/1","https://twitter.com/i/web/status/2"],"outcome":"failed","error":"feed"}]`,
+			buf.String())
+	})
+
+	t.Run("it should write an empty json array when there are no entries", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+
+		require.NoError(t, audit.WriteJSON(buf, nil))
+		require.JSONEq(t, "[]", buf.String())
+	})
+}
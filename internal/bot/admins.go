@@ -0,0 +1,237 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/storage"
+)
+
+const (
+	adminsKey = "bot/admins"
+	usersKey  = "bot/users"
+)
+
+// config returns the configuration with the admins added through /addadmin
+// merged into the configured ones, so they apply as soon as they are added.
+func (b *Bot) config() config.AppConfig {
+	b.mu.Lock()
+	added := b.admins
+	b.mu.Unlock()
+
+	return withAdmins(b.cfg.Get(), added)
+}
+
+// StaffConfig returns the configuration with the admins added through
+// /addadmin, so handlers outside the bot reach the same staff it lets in.
+func StaffConfig(cfg config.AppConfig, s storage.Store) (config.AppConfig, error) {
+	var admins []int
+	if err := s.Load(adminsKey, &admins); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return cfg, err
+	}
+
+	return withAdmins(cfg, admins), nil
+}
+
+func withAdmins(cfg config.AppConfig, added []int) config.AppConfig {
+	admins := append([]int(nil), cfg.Admins...)
+	for _, id := range added {
+		if !cfg.IsAdmin(id) {
+			admins = append(admins, id)
+		}
+	}
+
+	cfg.Admins = admins
+
+	return cfg
+}
+
+// loadAdmins reads the admins added at runtime and the usernames known so far.
+func (b *Bot) loadAdmins() error {
+	var admins []int
+	if err := b.s.Load(adminsKey, &admins); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	users := make(map[string]int)
+	if err := b.s.Load(usersKey, &users); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	b.mu.Lock()
+	b.admins, b.users = admins, users
+	b.mu.Unlock()
+
+	return nil
+}
+
+// rememberUser records the username of staff members, the Bot API has no way
+// to look up a user by username so it's the only way to promote them to admins
+// by it. Users without a role are left out, so anyone writing to the bot
+// doesn't grow the stored users.
+func (b *Bot) rememberUser(m TelegramMessage) {
+	id, err := strconv.Atoi(m.SenderID)
+	if err != nil || m.SenderUsername == "" || b.config().Role(id) == config.RoleNone {
+		return
+	}
+
+	username := strings.ToLower(m.SenderUsername)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.users[username] == id {
+		return
+	}
+
+	for u, v := range b.users {
+		if v == id {
+			delete(b.users, u)
+		}
+	}
+
+	b.users[username] = id
+
+	if err := b.s.Save(usersKey, b.users); err != nil {
+		b.log.WithError(err).WithField("user_id", id).Warn("error saving username")
+	}
+}
+
+func (b *Bot) handleAdminsCommand(ctx context.Context, m TelegramMessage) error {
+	configured := b.cfg.Get()
+	usernames := b.usernames()
+
+	var text string
+
+	for _, id := range b.config().Admins {
+		text += strconv.Itoa(id)
+		if u, ok := usernames[id]; ok {
+			text += " @" + u
+		}
+
+		if configured.IsAdmin(id) {
+			text += " " + b.t(m, "admins.configured")
+		}
+
+		text += "\n"
+	}
+
+	return b.bot.Send(ctx, m.SenderID, text)
+}
+
+func (b *Bot) handleAddAdminCommand(ctx context.Context, m TelegramMessage) error {
+	arg := strings.TrimSpace(m.Payload)
+
+	id, err := strconv.Atoi(arg)
+
+	switch {
+	case strings.HasPrefix(arg, "@"):
+		var ok bool
+		if id, ok = b.knownUser(arg); !ok {
+			return b.bot.Send(ctx, m.SenderID, b.t(m, "admins.unknown", arg))
+		}
+	case err != nil:
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "admins.add_usage"))
+	}
+
+	added, err := b.updateAdmins(func(admins []int) ([]int, bool) {
+		if withAdmins(b.cfg.Get(), admins).IsAdmin(id) {
+			return admins, false
+		}
+
+		return append(append([]int(nil), admins...), id), true
+	})
+	if err != nil {
+		return err
+	}
+
+	if !added {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "admins.already", arg))
+	}
+
+	b.setUserCommands(b.config(), id)
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "admins.added", arg))
+}
+
+func (b *Bot) handleRemoveAdminCommand(ctx context.Context, m TelegramMessage) error {
+	arg := strings.TrimSpace(m.Payload)
+
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "admins.remove_usage"))
+	}
+
+	if b.cfg.Get().IsAdmin(id) {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "admins.not_removable", arg))
+	}
+
+	removed, err := b.updateAdmins(func(admins []int) ([]int, bool) {
+		kept := make([]int, 0, len(admins))
+		for _, a := range admins {
+			if a != id {
+				kept = append(kept, a)
+			}
+		}
+
+		return kept, len(kept) < len(admins)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "admins.not_admin", arg))
+	}
+
+	b.setUserCommands(b.config(), id)
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "admins.removed", arg))
+}
+
+// knownUser returns the ID of the staff member with the username, as long as
+// they have already talked to the bot.
+func (b *Bot) knownUser(username string) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id, ok := b.users[strings.ToLower(strings.TrimPrefix(username, "@"))]
+
+	return id, ok
+}
+
+func (b *Bot) usernames() map[int]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usernames := make(map[int]string, len(b.users))
+	for u, id := range b.users {
+		usernames[id] = u
+	}
+
+	return usernames
+}
+
+// updateAdmins replaces the admins added at runtime with the ones returned by
+// update, which reports whether they changed. The lock is held until they are
+// saved, so concurrent commands don't overwrite each other's changes.
+func (b *Bot) updateAdmins(update func(admins []int) ([]int, bool)) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	admins, changed := update(b.admins)
+	if !changed {
+		return false, nil
+	}
+
+	if err := b.s.Save(adminsKey, admins); err != nil {
+		return false, err
+	}
+
+	b.admins = admins
+
+	return true, nil
+}
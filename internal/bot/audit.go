@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/javiyt/tweetgram/internal/audit"
+	"github.com/javiyt/tweetgram/internal/config"
+)
+
+const (
+	defaultAuditEntries = 10
+	maxAuditEntries     = 100
+)
+
+type (
+	auditKey    struct{}
+	endpointKey struct{}
+)
+
+// audited records in the audit log the interaction handled by f when the
+// sender has a role, along with what publishing it did.
+func (b *Bot) audited(action string, f TelegramHandler) TelegramHandler {
+	return func(ctx context.Context, m TelegramMessage) error {
+		if b.audit == nil || b.role(m) == config.RoleNone {
+			return f(ctx, m)
+		}
+
+		e := b.auditEntry(m, action)
+
+		err := f(context.WithValue(ctx, auditKey{}, e), m)
+		if err != nil {
+			e.Outcome, e.Err = audit.OutcomeFailed, err.Error()
+		}
+
+		b.record(ctx, *e)
+
+		return err
+	}
+}
+
+// auditDenied records the update the sender wasn't allowed to send, whether
+// they have a role or not.
+func (b *Bot) auditDenied(ctx context.Context, m TelegramMessage) {
+	b.auditOutcome(ctx, m, audit.OutcomeDenied)
+}
+
+// auditOutcome records the update with the outcome given, for the ones audited
+// leaves out as they come from users without a role.
+func (b *Bot) auditOutcome(ctx context.Context, m TelegramMessage, outcome string) {
+	if b.audit == nil {
+		return
+	}
+
+	endpoint, _ := ctx.Value(endpointKey{}).(string)
+
+	e := b.auditEntry(m, endpoint)
+	e.Outcome = outcome
+
+	b.record(ctx, *e)
+}
+
+func (b *Bot) auditEntry(m TelegramMessage, action string) *audit.Entry {
+	e := &audit.Entry{
+		Time:     b.now().UTC(),
+		SenderID: m.SenderID,
+		Username: m.SenderUsername,
+		Action:   action,
+		Args:     strings.TrimSpace(m.Payload),
+		Outcome:  audit.OutcomeOK,
+	}
+
+	if m.CallbackData != "" {
+		e.Args = m.CallbackData
+	}
+
+	return e
+}
+
+// annotate adds to the audit entry of the interaction being handled what
+// came out of it.
+func annotate(ctx context.Context, f func(e *audit.Entry)) {
+	if e, ok := ctx.Value(auditKey{}).(*audit.Entry); ok {
+		f(e)
+	}
+}
+
+// contentHash is how published content is kept in the audit log.
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (b *Bot) record(ctx context.Context, e audit.Entry) {
+	if err := b.audit.Append(e); err != nil {
+		b.log.WithContext(ctx).WithError(err).Error("error writing audit log")
+	}
+}
+
+// handleAuditCommand lists the last entries of the audit log, 10 unless a
+// number is given, or sends all of them as a csv or json file.
+func (b *Bot) handleAuditCommand(ctx context.Context, m TelegramMessage) error {
+	if b.audit == nil {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "audit.disabled"))
+	}
+
+	arg := strings.ToLower(strings.TrimSpace(m.Payload))
+
+	switch arg {
+	case "csv", "json":
+		return b.exportAudit(ctx, m, arg)
+	case "":
+		arg = strconv.Itoa(defaultAuditEntries)
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "audit.usage"))
+	}
+
+	entries, err := b.audit.Last(min(n, maxAuditEntries))
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "audit.empty"))
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, auditLine(e))
+	}
+
+	return b.bot.Send(ctx, m.SenderID, strings.Join(lines, "\n"))
+}
+
+func (b *Bot) exportAudit(ctx context.Context, m TelegramMessage, format string) error {
+	entries, err := b.audit.Last(0)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if format == "csv" {
+		err = audit.WriteCSV(buf, entries)
+	} else {
+		err = audit.WriteJSON(buf, entries)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return b.bot.Send(ctx, m.SenderID, TelegramDocument{FileName: "audit." + format, Content: buf.Bytes()})
+}
+
+func auditLine(e audit.Entry) string {
+	parts := []string{e.Time.Format("2006-01-02 15:04:05"), e.SenderID}
+	if e.Username != "" {
+		parts = append(parts, "@"+e.Username)
+	}
+
+	parts = append(parts, e.Action)
+
+	if e.Args != "" {
+		parts = append(parts, e.Args)
+	}
+
+	if e.ContentHash != "" {
+		parts = append(parts, e.ContentHash[:12])
+	}
+
+	if len(e.Destinations) > 0 {
+		parts = append(parts, "to "+strings.Join(e.Destinations, ","))
+	}
+
+	parts = append(parts, e.Posts...)
+
+	if e.Err != "" {
+		return strings.Join(append(parts, e.Outcome+": "+e.Err), " ")
+	}
+
+	return strings.Join(append(parts, e.Outcome), " ")
+}
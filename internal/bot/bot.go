@@ -3,25 +3,39 @@ package bot
 import (
 	"context"
 	"io"
+	"net/http"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/javiyt/tweetgram/internal/audit"
+	"github.com/javiyt/tweetgram/internal/i18n"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/metrics"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/translate"
 
 	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/sirupsen/logrus"
 	tb "gopkg.in/telebot.v3"
 )
 
+// ReplyButton identifies the inline button used to answer a tweet from Telegram.
+const ReplyButton = "reply"
+
 type TelegramBot interface {
 	Start()
 	Stop()
-	SetCommands([]TelegramBotCommand) error
+	SetCommands([]TelegramBotCommand, ...interface{}) error
 	Handle(string, TelegramHandler)
-	Send(string, interface{}, ...interface{}) error
-	GetFile(string) (io.ReadCloser, error)
+	Send(context.Context, string, interface{}, ...interface{}) error
+	GetFile(context.Context, string) (io.ReadCloser, error)
 }
 
-type TelegramHandler func(TelegramMessage) error
+type TelegramHandler func(context.Context, TelegramMessage) error
 
 type TelegramBotCommand struct {
 	Text        string
@@ -30,11 +44,21 @@ type TelegramBotCommand struct {
 }
 
 type TelegramMessage struct {
-	SenderID  string
-	Text      string
-	Payload   string
-	Photo     TelegramPhoto
-	IsPrivate bool
+	SenderID       string
+	SenderUsername string
+	LanguageCode   string
+	ChatID         int64
+	MessageID      int
+	Text           string
+	Payload        string
+	CallbackData   string
+	Photo          TelegramPhoto
+	Forward        TelegramForward
+	IsPrivate      bool
+	Mentioned      bool
+	// Preview is whether the sender chose to show the link preview, nil
+	// when they left the default.
+	Preview *bool
 }
 
 type TelegramPhoto struct {
@@ -44,30 +68,104 @@ type TelegramPhoto struct {
 	FileSize int64
 }
 
+// TelegramDocument is sent as a file named FileName.
+type TelegramDocument struct {
+	FileName string
+	Content  []byte
+}
+
+// TelegramForward is the channel a message was forwarded from, URL is only
+// known for public channels.
+type TelegramForward struct {
+	Channel string
+	URL     string
+}
+
+// TelegramKeyboard is sent as a send option to attach inline buttons to a message.
+type TelegramKeyboard [][]TelegramButton
+
+type TelegramButton struct {
+	Unique string
+	Text   string
+	Data   string
+}
+
+// TelegramCommandScope is sent as a SetCommands option to set the commands
+// shown in the chat with that ID instead of the default ones.
+type TelegramCommandScope int64
+
+// TelegramLanguage is sent as a SetCommands option to set the commands shown
+// to users whose app is in that language.
+type TelegramLanguage string
+
+// TelegramReplyTo is sent as a send option to answer the message with that ID.
+type TelegramReplyTo int
+
+// TelegramPreview is sent as a send option to show or hide the preview of
+// the first link in the message.
+type TelegramPreview bool
+
+// TelegramSent is sent as a send option to get back the details of the first
+// message sent, URL is only known for channels and groups.
+type TelegramSent struct {
+	MessageID int
+	URL       string
+}
+
 type AppBot interface {
 	Start(ctx context.Context) error
 	Run()
 	Stop()
+	Reload(config.AppConfig)
+	NotifyAdmins(context.Context, string) error
 }
 
 type TwitterClient interface {
-	SendUpdate(string) error
-	SendUpdateWithPhoto(string, []byte) error
+	SendUpdate(context.Context, string) ([]int64, error)
+	SendUpdateWithPhoto(context.Context, string, []byte) ([]int64, error)
+	SendReply(context.Context, int64, string) ([]int64, error)
+}
+
+// PublishedRecorder keeps the IDs of the tweets sent by tweetgram, so they
+// aren't imported back from the timeline.
+type PublishedRecorder interface {
+	Record(ids []int64) error
 }
 
 type Bot struct {
-	bot TelegramBot
-	tc  TwitterClient
-	cfg config.AppConfig
-	q   pubsub.Queue
+	bot       TelegramBot
+	tc        TwitterClient
+	published PublishedRecorder
+	cfg       config.Holder
+	q         pubsub.Queue
+	s         storage.Store
+	tr        *translate.Table
+	client    *http.Client
+	audit     audit.Log
+	log       *logrus.Logger
+	mu        sync.Mutex
+	replies   map[string]pendingReply
+	edits     map[string]string
+	reviews   sync.Mutex
+	admins    []int
+	users     map[string]int
+	now       func() time.Time
+	limited   map[string]time.Time
+}
+
+type pendingReply struct {
+	tweetID int64
+	author  string
 }
 
 type Option func(b *Bot)
 
+// botHandler is an endpoint of the bot, help being the catalog key of its
+// description when it's a command shown to users.
 type botHandler struct {
 	handlerFunc TelegramHandler
 	help        string
-	isAdmin     bool
+	permission  permission
 	filters     []filterFunc
 }
 
@@ -79,7 +177,7 @@ func WithTelegramBot(tb TelegramBot) Option {
 
 func WithConfig(cfg config.AppConfig) Option {
 	return func(b *Bot) {
-		b.cfg = cfg
+		b.cfg.Set(cfg)
 	}
 }
 
@@ -89,23 +187,102 @@ func WithTwitterClient(tc TwitterClient) Option {
 	}
 }
 
+// WithPublishedRecorder sets where the replies sent to tweets are recorded.
+func WithPublishedRecorder(r PublishedRecorder) Option {
+	return func(b *Bot) {
+		b.published = r
+	}
+}
+
 func WithQueue(q pubsub.Queue) Option {
 	return func(b *Bot) {
 		b.q = q
 	}
 }
 
+// WithStore sets where the admins added with /addadmin are kept.
+func WithStore(s storage.Store) Option {
+	return func(b *Bot) {
+		b.s = s
+	}
+}
+
+// WithTranslations sets the table /translate adds rules to, shared with the
+// handlers applying them. By default it's kept in the store.
+func WithTranslations(tr *translate.Table) Option {
+	return func(b *Bot) {
+		b.tr = tr
+	}
+}
+
+// WithHTTPClient sets the client expanding shortened links before they're
+// published.
+func WithHTTPClient(c *http.Client) Option {
+	return func(b *Bot) {
+		b.client = c
+	}
+}
+
+// WithAuditLog sets where the interactions of users with a role are recorded.
+func WithAuditLog(l audit.Log) Option {
+	return func(b *Bot) {
+		b.audit = l
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(b *Bot) {
+		b.log = log
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(b *Bot) {
+		b.now = now
+	}
+}
+
+// ReplyKeyboard builds the inline keyboard, in lang, that lets an admin answer
+// the given tweet.
+func ReplyKeyboard(lang string, tweetID int64, author string) TelegramKeyboard {
+	return TelegramKeyboard{{{
+		Unique: ReplyButton,
+		Text:   i18n.T(lang, "mentions.button.reply"),
+		Data:   strconv.FormatInt(tweetID, 10) + "|" + author,
+	}}}
+}
+
 func NewBot(options ...Option) AppBot {
-	b := &Bot{}
+	b := &Bot{
+		log:     logging.Discard(),
+		client:  http.DefaultClient,
+		replies: make(map[string]pendingReply),
+		edits:   make(map[string]string),
+		users:   make(map[string]int),
+		now:     time.Now,
+		limited: make(map[string]time.Time),
+	}
 
 	for _, o := range options {
 		o(b)
 	}
 
+	if b.tr == nil {
+		b.tr = translate.NewTable(b.s)
+	}
+
 	return b
 }
 
 func (b *Bot) Start(context.Context) error {
+	if err := b.loadAdmins(); err != nil {
+		return err
+	}
+
+	if err := b.tr.Load(); err != nil {
+		return err
+	}
+
 	if err := b.setCommandList(); err != nil {
 		return err
 	}
@@ -124,60 +301,193 @@ func (b *Bot) Stop() {
 	_ = b.q.Close()
 }
 
+// Reload swaps the configuration used to filter updates, so roles given or
+// taken are taken into account from the next update on. Admins added with
+// /addadmin are kept.
+func (b *Bot) Reload(cfg config.AppConfig) {
+	b.cfg.Set(cfg)
+
+	if err := b.setCommandList(); err != nil {
+		b.log.WithError(err).Error("error setting commands")
+	}
+}
+
+// NotifyAdmins sends text to every admin, stopping at the first failure.
+func (b *Bot) NotifyAdmins(ctx context.Context, text string) error {
+	for _, admin := range b.config().Admins {
+		if err := b.bot.Send(ctx, strconv.Itoa(admin), text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (b *Bot) getHandlers() map[string]botHandler {
 	return map[string]botHandler{
 		"/start": {
 			handlerFunc: b.handleStartCommand,
-			help:        "Start a conversation with the bot",
+			help:        "command.start",
 			filters: []filterFunc{
 				b.onlyPrivate,
 			},
 		},
 		"/help": {
 			handlerFunc: b.handleHelpCommand,
-			help:        "Show help",
+			help:        "command.help",
 			filters: []filterFunc{
 				b.onlyPrivate,
 			},
 		},
 		"/stop": {
 			handlerFunc: b.handleStopNotificationsCommand,
-			help:        "Stop notifications for all handlers or specific handler",
+			help:        "command.stop",
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionStop),
+			},
+			permission: permissionStop,
+		},
+		"/admins": {
+			handlerFunc: b.handleAdminsCommand,
+			help:        "command.admins",
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionManage),
+			},
+			permission: permissionManage,
+		},
+		"/addadmin": {
+			handlerFunc: b.handleAddAdminCommand,
+			help:        "command.addadmin",
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionManage),
+			},
+			permission: permissionManage,
+		},
+		"/removeadmin": {
+			handlerFunc: b.handleRemoveAdminCommand,
+			help:        "command.removeadmin",
 			filters: []filterFunc{
 				b.onlyPrivate,
-				b.onlyAdmins,
+				b.allowedTo(permissionManage),
 			},
-			isAdmin: true,
+			permission: permissionManage,
+		},
+		"/audit": {
+			handlerFunc: b.handleAuditCommand,
+			help:        "command.audit",
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionManage),
+			},
+			permission: permissionManage,
+		},
+		"/translate": {
+			handlerFunc: b.handleTranslateCommand,
+			help:        "command.translate",
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionManage),
+			},
+			permission: permissionManage,
+		},
+		"/untranslate": {
+			handlerFunc: b.handleUntranslateCommand,
+			help:        "command.untranslate",
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionManage),
+			},
+			permission: permissionManage,
+		},
+		"/cancel": {
+			handlerFunc: b.handleCancelCommand,
+			help:        "command.cancel",
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionReview),
+			},
+			permission: permissionReview,
+		},
+		"\f" + accessButton: {
+			handlerFunc: b.handleAccessButton,
+			filters: []filterFunc{
+				b.onlyPrivate,
+			},
+		},
+		"\f" + approveButton: {
+			handlerFunc: b.handleApproveButton,
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionReview),
+			},
+		},
+		"\f" + editButton: {
+			handlerFunc: b.handleEditButton,
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionReview),
+			},
+		},
+		"\f" + rejectButton: {
+			handlerFunc: b.handleRejectButton,
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionReview),
+			},
+		},
+		"\f" + ReplyButton: {
+			handlerFunc: b.handleReplyButton,
+			filters: []filterFunc{
+				b.onlyPrivate,
+				b.allowedTo(permissionPublish),
+			},
+		},
+		"/post": {
+			handlerFunc: b.handlePostCommand,
+			help:        "command.post",
+			filters: []filterFunc{
+				b.onlyPrivateOrStaff,
+				b.allowedTo(permissionPublish),
+			},
+			permission: permissionPublish,
 		},
 		tb.OnPhoto: {
 			handlerFunc: b.handlePhoto,
 			filters: []filterFunc{
-				b.onlyPrivate,
-				b.onlyAdmins,
+				b.onlyPrivateOrStaff,
+				b.onlyMentionedInGroup,
+				b.allowedTo(permissionPublish),
+				b.submitDrafts,
 			},
 		},
 		tb.OnText: {
 			handlerFunc: b.handleText,
 			filters: []filterFunc{
-				b.onlyPrivate,
-				b.onlyAdmins,
+				b.onlyPrivateOrStaff,
+				b.onlyMentionedInGroup,
+				b.allowedTo(permissionPublish),
+				b.submitDrafts,
+				b.editDrafts,
 			},
 		},
 	}
 }
 
-func (b *Bot) getCommands(includeAdmin bool) []TelegramBotCommand {
+func (b *Bot) getCommands(role config.Role, lang string) []TelegramBotCommand {
 	var cmd []TelegramBotCommand
 
 	for c, h := range b.getHandlers() {
-		if !includeAdmin && h.isAdmin {
+		if !h.permission.grantedTo(role) {
 			continue
 		}
 
 		if strings.TrimSpace(h.help) != "" {
 			cmd = append(cmd, TelegramBotCommand{
 				Text:        strings.Replace(c, "/", "", 1),
-				Description: h.help,
+				Description: i18n.T(lang, h.help),
 			})
 		}
 	}
@@ -189,18 +499,102 @@ func (b *Bot) getCommands(includeAdmin bool) []TelegramBotCommand {
 	return cmd
 }
 
+// setCommandList sets the commands everybody sees and, in the chat with every
+// user given a role, the ones allowed to them. Failing to set the latter is
+// only logged, as it can't be done until the user has talked to the bot.
 func (b *Bot) setCommandList() error {
-	return b.bot.SetCommands(b.getCommands(false))
+	cfg := b.config()
+
+	if err := b.setCommands(cfg, config.RoleNone); err != nil {
+		return err
+	}
+
+	for _, id := range cfg.Members() {
+		b.setUserCommands(cfg, id)
+	}
+
+	return nil
+}
+
+func (b *Bot) setUserCommands(cfg config.AppConfig, id int) {
+	if err := b.setCommands(cfg, cfg.Role(id), TelegramCommandScope(id)); err != nil {
+		b.log.WithError(err).WithField("user_id", id).Warn("error setting user commands")
+	}
+}
+
+// setCommands sets the commands allowed to the role described in the default
+// language and, for users whose app is set to another one, in theirs.
+func (b *Bot) setCommands(cfg config.AppConfig, role config.Role, options ...interface{}) error {
+	def := i18n.Language("", cfg.DefaultLanguage)
+	if err := b.bot.SetCommands(b.getCommands(role, def), options...); err != nil {
+		return err
+	}
+
+	for _, lang := range i18n.Languages() {
+		if lang == def {
+			continue
+		}
+
+		if err := b.bot.SetCommands(b.getCommands(role, lang), append(options, TelegramLanguage(lang))...); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (b *Bot) setUpHandlers() {
 	for c, h := range b.getHandlers() {
-		exec := h.handlerFunc
+		exec := b.audited(action(c), h.handlerFunc)
 
 		for _, v := range h.filters {
 			exec = v(exec)
 		}
 
-		b.bot.Handle(c, exec)
+		b.bot.Handle(c, b.observe(c, exec))
+	}
+}
+
+// observe counts and logs the updates received by the endpoint, along with
+// the errors returned when handling them.
+func (b *Bot) observe(endpoint string, f TelegramHandler) TelegramHandler {
+	counter := metrics.UpdatesReceived.WithLabelValues(action(endpoint))
+
+	return func(ctx context.Context, m TelegramMessage) error {
+		ctx = context.WithValue(ctx, endpointKey{}, action(endpoint))
+
+		counter.Inc()
+		b.log.WithContext(ctx).Debug("update received")
+		b.rememberUser(m)
+
+		err := f(ctx, m)
+		if err != nil {
+			b.log.WithContext(ctx).WithError(err).Error("error handling update")
+		}
+
+		return err
 	}
 }
+
+// action names the endpoint without the prefixes telebot uses for events and
+// buttons.
+func action(endpoint string) string {
+	return strings.TrimLeft(endpoint, "\a\f")
+}
+
+// lang is the language the sender is answered in, the one of their app when
+// there is a catalog for it.
+func (b *Bot) lang(m TelegramMessage) string {
+	return i18n.Language(m.LanguageCode, b.config().DefaultLanguage)
+}
+
+// t translates the key to the language of the sender.
+func (b *Bot) t(m TelegramMessage, key string, args ...interface{}) string {
+	return i18n.T(b.lang(m), key, args...)
+}
+
+// defaultLang is the language of the messages sent to other users than the
+// sender, as their language isn't known.
+func (b *Bot) defaultLang() string {
+	return i18n.Language("", b.config().DefaultLanguage)
+}
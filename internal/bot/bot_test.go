@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/storage"
 	mb "github.com/javiyt/tweetgram/mocks/bot"
 	mq "github.com/javiyt/tweetgram/mocks/pubsub"
 	"github.com/stretchr/testify/mock"
@@ -32,7 +34,11 @@ func TestStart(t *testing.T) {
 		},
 	}
 
-	b := bot.NewBot(bot.WithTelegramBot(mockedBot), bot.WithTwitterClient(mockedTwitter))
+	b := bot.NewBot(
+		bot.WithTelegramBot(mockedBot),
+		bot.WithTwitterClient(mockedTwitter),
+		bot.WithStore(storage.NewFileStore(t.TempDir())),
+	)
 
 	t.Run("it should fail setting up the commands", func(t *testing.T) {
 		mockedBot.On("SetCommands", cmds).Once().Return(settingCommandError{})
@@ -49,9 +55,26 @@ func TestStart(t *testing.T) {
 
 	t.Run("it should start the bot successfully", func(t *testing.T) {
 		mockedBot.On("SetCommands", cmds).Once().Return(nil)
+		mockedBot.On("SetCommands", []bot.TelegramBotCommand{
+			{Text: "help", Description: "Mostrar la ayuda"},
+			{Text: "start", Description: "Empezar una conversación con el bot"},
+		}, bot.TelegramLanguage("es")).Once().Return(nil)
 		mockedBot.On("Handle", "/start", mock.Anything).Once().Return(nil, nil)
 		mockedBot.On("Handle", "/help", mock.Anything).Once().Return(nil, nil)
 		mockedBot.On("Handle", "/stop", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "/admins", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "/addadmin", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "/removeadmin", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "/audit", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "/translate", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "/untranslate", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "/cancel", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "/post", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "\f"+bot.ReplyButton, mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "\faccess", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "\fapprove", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "\fedit", mock.Anything).Once().Return(nil, nil)
+		mockedBot.On("Handle", "\freject", mock.Anything).Once().Return(nil, nil)
 		mockedBot.On("Handle", tb.OnPhoto, mock.Anything).Once().Return(nil, nil)
 		mockedBot.On("Handle", tb.OnText, mock.Anything).Once().Return(nil, nil)
 
@@ -61,6 +84,36 @@ func TestStart(t *testing.T) {
 	})
 }
 
+func TestStartCommandsByRole(t *testing.T) {
+	mockedBot := new(mb.TelegramBot)
+	cfg := config.AppConfig{Admins: []int{1234}, Viewers: []int{5678}, DefaultLanguage: "es"}
+
+	mockedBot.On("SetCommands", mock.Anything).Once().Return(nil)
+	mockedBot.On("SetCommands", mock.Anything, bot.TelegramLanguage("en")).Once().Return(nil)
+	mockedBot.On("SetCommands", mock.MatchedBy(func(cmds []bot.TelegramBotCommand) bool {
+		return len(cmds) == 11 && cmds[0].Description == "Añadir un administrador por ID de usuario o @usuario"
+	}), bot.TelegramCommandScope(1234)).Once().Return(nil)
+	mockedBot.On("SetCommands", mock.MatchedBy(func(cmds []bot.TelegramBotCommand) bool {
+		return len(cmds) == 11 && cmds[0].Description == "Add an admin by user ID or @username"
+	}), bot.TelegramCommandScope(1234), bot.TelegramLanguage("en")).Once().Return(nil)
+	mockedBot.On("SetCommands", mock.MatchedBy(func(cmds []bot.TelegramBotCommand) bool {
+		return len(cmds) == 2
+	}), bot.TelegramCommandScope(5678)).Once().Return(settingCommandError{})
+	mockedBot.On("Handle", mock.Anything, mock.Anything).Return(nil, nil)
+
+	b := bot.NewBot(
+		bot.WithTelegramBot(mockedBot),
+		bot.WithConfig(cfg),
+		bot.WithStore(storage.NewFileStore(t.TempDir())),
+	)
+
+	t.Run("it should set the commands allowed in the chat of every member in every language", func(t *testing.T) {
+		require.NoError(t, b.Start(nil))
+
+		mockedBot.AssertExpectations(t)
+	})
+}
+
 func TestRun(t *testing.T) {
 	mockedBot := new(mb.TelegramBot)
 	mockedBot.On("Start").Once()
@@ -0,0 +1,304 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/javiyt/tweetgram/internal/audit"
+	"github.com/javiyt/tweetgram/internal/i18n"
+	"github.com/javiyt/tweetgram/internal/storage"
+)
+
+const (
+	approveButton = "approve"
+	editButton    = "edit"
+	rejectButton  = "reject"
+	draftsKey     = "bot/drafts/"
+)
+
+// draft is a post sent by a contributor, kept until a reviewer approves or
+// rejects it. The text is the caption when it's a photo.
+type draft struct {
+	SenderID  string `json:"senderId"`
+	Username  string `json:"username,omitempty"`
+	Language  string `json:"language,omitempty"`
+	MessageID int    `json:"messageId"`
+	Text      string `json:"text"`
+	FileID    string `json:"fileId,omitempty"`
+	FileURL   string `json:"fileUrl,omitempty"`
+	FileSize  int64  `json:"fileSize,omitempty"`
+	Preview   *bool  `json:"preview,omitempty"`
+}
+
+// message rebuilds the contributor message, so publishing it once approved
+// reports back to them.
+func (d draft) message() TelegramMessage {
+	m := TelegramMessage{
+		SenderID:       d.SenderID,
+		SenderUsername: d.Username,
+		LanguageCode:   d.Language,
+		MessageID:      d.MessageID,
+		IsPrivate:      true,
+		Preview:        d.Preview,
+	}
+	if d.FileID == "" {
+		m.Text = d.Text
+
+		return m
+	}
+
+	m.Photo = TelegramPhoto{Caption: d.Text, FileID: d.FileID, FileURL: d.FileURL, FileSize: d.FileSize}
+
+	return m
+}
+
+// draftKeyboard builds the inline keyboard reviewers use to handle the draft.
+func draftKeyboard(lang string, id string) TelegramKeyboard {
+	return TelegramKeyboard{{
+		{Unique: approveButton, Text: i18n.T(lang, "drafts.button.approve"), Data: id},
+		{Unique: editButton, Text: i18n.T(lang, "drafts.button.edit"), Data: id},
+		{Unique: rejectButton, Text: i18n.T(lang, "drafts.button.reject"), Data: id},
+	}}
+}
+
+// submitDrafts turns the posts sent by users allowed to submit them, but not
+// to publish, into drafts sent to the reviewers.
+func (b *Bot) submitDrafts(f TelegramHandler) TelegramHandler {
+	return func(ctx context.Context, m TelegramMessage) error {
+		senderID, err := strconv.Atoi(m.SenderID)
+		if err != nil || !m.IsPrivate {
+			return f(ctx, m)
+		}
+
+		role := b.config().Role(senderID)
+		if permissionPublish.grantedTo(role) || !permissionSubmit.grantedTo(role) {
+			return f(ctx, m)
+		}
+
+		return b.audited("draft", b.submitDraft)(ctx, m)
+	}
+}
+
+// editDrafts takes the text sent after pressing the Edit button as the new
+// text of the draft.
+func (b *Bot) editDrafts(f TelegramHandler) TelegramHandler {
+	return func(ctx context.Context, m TelegramMessage) error {
+		if !m.IsPrivate {
+			return f(ctx, m)
+		}
+
+		id, ok := b.takeEdit(m.SenderID)
+		if !ok {
+			return f(ctx, m)
+		}
+
+		return b.audited("edit draft", func(ctx context.Context, m TelegramMessage) error {
+			return b.editDraft(ctx, m, id)
+		})(ctx, m)
+	}
+}
+
+func (b *Bot) submitDraft(ctx context.Context, m TelegramMessage) error {
+	d := draft{
+		SenderID:  m.SenderID,
+		Username:  m.SenderUsername,
+		Language:  m.LanguageCode,
+		MessageID: m.MessageID,
+		Text:      strings.TrimSpace(m.Text),
+		Preview:   m.Preview,
+	}
+
+	if m.Photo.FileID != "" {
+		d.Text = strings.TrimSpace(m.Photo.Caption)
+		d.FileID, d.FileURL, d.FileSize = m.Photo.FileID, m.Photo.FileURL, m.Photo.FileSize
+	}
+
+	if d.Text == "" {
+		return nil
+	}
+
+	d.Text = b.attribute(m, d.Text)
+
+	id := watermill.NewShortUUID()
+	if err := b.s.Save(draftsKey+id, d); err != nil {
+		return err
+	}
+
+	annotate(ctx, func(e *audit.Entry) { e.Args, e.ContentHash = id, contentHash([]byte(d.Text)) })
+
+	lang := b.defaultLang()
+
+	for _, r := range b.reviewers() {
+		if err := b.sendDraft(ctx, strconv.Itoa(r), lang, id, d); err != nil {
+			b.log.WithContext(ctx).WithError(err).WithField("user_id", r).Warn("error sending draft")
+		}
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "drafts.submitted"), TelegramReplyTo(m.MessageID))
+}
+
+// sendDraft shows the draft to a reviewer in lang: the default language when
+// it's submitted, or the one of the reviewer that edited it.
+func (b *Bot) sendDraft(ctx context.Context, to string, lang string, id string, d draft) error {
+	author := "@" + d.Username
+	if d.Username == "" {
+		author = i18n.T(lang, "drafts.user", d.SenderID)
+	}
+
+	text := i18n.T(lang, "drafts.from", author, d.Text)
+	if d.FileID == "" {
+		return b.bot.Send(ctx, to, text, draftKeyboard(lang, id))
+	}
+
+	return b.bot.Send(ctx, to, TelegramPhoto{Caption: text, FileID: d.FileID, FileURL: d.FileURL, FileSize: d.FileSize},
+		draftKeyboard(lang, id))
+}
+
+// reviewers returns every user allowed to review drafts.
+func (b *Bot) reviewers() []int {
+	cfg := b.config()
+
+	var ids []int
+
+	for _, id := range cfg.Members() {
+		if permissionReview.grantedTo(cfg.Role(id)) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (b *Bot) handleApproveButton(ctx context.Context, m TelegramMessage) error {
+	b.reviews.Lock()
+	defer b.reviews.Unlock()
+
+	id := m.CallbackData
+
+	d, ok, err := b.loadDraft(id)
+	if err != nil || !ok {
+		return b.alreadyReviewed(ctx, m, err)
+	}
+
+	// The draft is removed before publishing it, so approving it again after a
+	// failure can't publish it twice, and put back when publishing fails.
+	if err := b.s.Delete(draftsKey + id); err != nil {
+		return err
+	}
+
+	// The contributor gets the receipt, but the post goes only where the
+	// reviewer approving it can publish.
+	if d.FileID != "" {
+		err = b.handlePhoto(publishedBy(ctx, m.SenderID), d.message())
+	} else {
+		err = b.publishText(publishedBy(ctx, m.SenderID), d.message(), d.Text)
+	}
+
+	if err != nil {
+		if err := b.s.Save(draftsKey+id, d); err != nil {
+			b.log.WithContext(ctx).WithError(err).WithField("draft_id", id).Warn("error restoring draft")
+		}
+
+		return err
+	}
+
+	if err := b.bot.Send(ctx, d.SenderID, b.t(d.message(), "drafts.approved"), TelegramReplyTo(d.MessageID)); err != nil {
+		return err
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "drafts.published"))
+}
+
+func (b *Bot) handleRejectButton(ctx context.Context, m TelegramMessage) error {
+	b.reviews.Lock()
+	defer b.reviews.Unlock()
+
+	id := m.CallbackData
+
+	d, ok, err := b.loadDraft(id)
+	if err != nil || !ok {
+		return b.alreadyReviewed(ctx, m, err)
+	}
+
+	if err := b.s.Delete(draftsKey + id); err != nil {
+		return err
+	}
+
+	if err := b.bot.Send(ctx, d.SenderID, b.t(d.message(), "drafts.rejected"), TelegramReplyTo(d.MessageID)); err != nil {
+		return err
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "drafts.discarded"))
+}
+
+func (b *Bot) handleEditButton(ctx context.Context, m TelegramMessage) error {
+	_, ok, err := b.loadDraft(m.CallbackData)
+	if err != nil || !ok {
+		return b.alreadyReviewed(ctx, m, err)
+	}
+
+	b.mu.Lock()
+	b.edits[m.SenderID] = m.CallbackData
+	b.mu.Unlock()
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "drafts.edit_prompt"))
+}
+
+func (b *Bot) editDraft(ctx context.Context, m TelegramMessage, id string) error {
+	text := strings.TrimSpace(m.Text)
+	if text == "" {
+		return nil
+	}
+
+	annotate(ctx, func(e *audit.Entry) { e.Args, e.ContentHash = id, contentHash([]byte(text)) })
+
+	b.reviews.Lock()
+
+	d, ok, err := b.loadDraft(id)
+	if err == nil && ok {
+		d.Text = text
+		err = b.s.Save(draftsKey+id, d)
+	}
+
+	b.reviews.Unlock()
+
+	if err != nil || !ok {
+		return b.alreadyReviewed(ctx, m, err)
+	}
+
+	return b.sendDraft(ctx, m.SenderID, b.lang(m), id, d)
+}
+
+func (b *Bot) takeEdit(senderID string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id, ok := b.edits[senderID]
+	delete(b.edits, senderID)
+
+	return id, ok
+}
+
+func (b *Bot) loadDraft(id string) (draft, bool, error) {
+	var d draft
+
+	err := b.s.Load(draftsKey+id, &d)
+	if errors.Is(err, storage.ErrNotFound) {
+		return d, false, nil
+	}
+
+	return d, err == nil, err
+}
+
+// alreadyReviewed answers a reviewer acting on a draft that is no longer
+// there, unless loading it failed.
+func (b *Bot) alreadyReviewed(ctx context.Context, m TelegramMessage, err error) error {
+	if err != nil {
+		return err
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "drafts.reviewed"))
+}
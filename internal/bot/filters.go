@@ -1,32 +1,108 @@
 package bot
 
 import (
+	"context"
 	"strconv"
+
+	"github.com/javiyt/tweetgram/internal/config"
 )
 
 type filterFunc func(f TelegramHandler) TelegramHandler
 
+// permission is what a user needs to be allowed to use an endpoint.
+type permission int
+
+const (
+	permissionNone permission = iota
+	permissionView
+	permissionSubmit
+	permissionReview
+	permissionPublish
+	permissionStop
+	permissionManage
+)
+
+// rolePermissions grants every role its permissions, contributors submit
+// drafts that reviewers approve but only publishers and owners can publish
+// straight away.
+var rolePermissions = map[config.Role][]permission{
+	config.RoleViewer:      {permissionView},
+	config.RoleContributor: {permissionView, permissionSubmit},
+	config.RoleReviewer:    {permissionView, permissionReview},
+	config.RolePublisher:   {permissionView, permissionReview, permissionPublish},
+	config.RoleOwner:       {permissionView, permissionReview, permissionPublish, permissionStop, permissionManage},
+}
+
+func (p permission) grantedTo(role config.Role) bool {
+	if p == permissionNone {
+		return true
+	}
+
+	for _, v := range rolePermissions[role] {
+		if v == p {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (b *Bot) onlyPrivate(f TelegramHandler) TelegramHandler {
-	return func(m TelegramMessage) error {
+	return func(ctx context.Context, m TelegramMessage) error {
 		if !m.IsPrivate {
 			return nil
 		}
 
-		return f(m)
+		return f(ctx, m)
 	}
 }
 
-func (b *Bot) onlyAdmins(f TelegramHandler) TelegramHandler {
-	return func(m TelegramMessage) error {
-		senderID, err := strconv.Atoi(m.SenderID)
-		if err != nil {
-			return err
+// onlyPrivateOrStaff lets through private messages and the ones sent to the
+// staff group, when there is one.
+func (b *Bot) onlyPrivateOrStaff(f TelegramHandler) TelegramHandler {
+	return func(ctx context.Context, m TelegramMessage) error {
+		if !m.IsPrivate && !b.config().IsStaffGroup(m.ChatID) {
+			return nil
 		}
 
-		if !b.cfg.IsAdmin(senderID) {
+		return f(ctx, m)
+	}
+}
+
+// onlyMentionedInGroup drops the group messages not mentioning the bot, so the
+// staff can talk in the group without publishing everything they say.
+func (b *Bot) onlyMentionedInGroup(f TelegramHandler) TelegramHandler {
+	return func(ctx context.Context, m TelegramMessage) error {
+		if !m.IsPrivate && !m.Mentioned {
 			return nil
 		}
 
-		return f(m)
+		return f(ctx, m)
 	}
 }
+
+// allowedTo only lets through the updates sent by users whose role has been
+// granted the permission, the rest are handled as unauthorized.
+func (b *Bot) allowedTo(p permission) filterFunc {
+	return func(f TelegramHandler) TelegramHandler {
+		return func(ctx context.Context, m TelegramMessage) error {
+			role := b.role(m)
+			if !p.grantedTo(role) {
+				return b.unauthorized(ctx, m, role)
+			}
+
+			return f(ctx, m)
+		}
+	}
+}
+
+// role returns the role of the sender, none when the update doesn't come
+// from a user, as with messages sent on behalf of a channel.
+func (b *Bot) role(m TelegramMessage) config.Role {
+	senderID, err := strconv.Atoi(m.SenderID)
+	if err != nil {
+		return config.RoleNone
+	}
+
+	return b.config().Role(senderID)
+}
@@ -2,34 +2,32 @@ package bot
 
 import (
 	"bytes"
+	"context"
 	"strconv"
 	"strings"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/audit"
+	"github.com/javiyt/tweetgram/internal/links"
 	"github.com/javiyt/tweetgram/internal/pubsub"
 	"github.com/mailru/easyjson"
 )
 
-func (b *Bot) handleStartCommand(m TelegramMessage) error {
-	return b.bot.Send(m.SenderID, "Thanks for using the bot! You can type /help command to know what can I do")
+func (b *Bot) handleStartCommand(ctx context.Context, m TelegramMessage) error {
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "start"))
 }
 
-func (b *Bot) handleHelpCommand(m TelegramMessage) error {
-	user, err := strconv.Atoi(m.SenderID)
-	if err != nil {
-		return err
-	}
-
+func (b *Bot) handleHelpCommand(ctx context.Context, m TelegramMessage) error {
 	var helpText string
-	for _, h := range b.getCommands(b.cfg.IsAdmin(user)) {
+	for _, h := range b.getCommands(b.role(m), b.lang(m)) {
 		helpText += "/" + h.Text + " - " + h.Description + "\n"
 	}
 
-	return b.bot.Send(m.SenderID, helpText)
+	return b.bot.Send(ctx, m.SenderID, helpText)
 }
 
-func (b *Bot) handleStopNotificationsCommand(m TelegramMessage) error {
+func (b *Bot) handleStopNotificationsCommand(ctx context.Context, m TelegramMessage) error {
 	ce := pubsub.CommandEvent{Command: pubsub.StopCommand}
 	if m.Payload != "" {
 		ce.Handler = m.Payload
@@ -37,16 +35,29 @@ func (b *Bot) handleStopNotificationsCommand(m TelegramMessage) error {
 
 	marshal, _ := easyjson.Marshal(ce)
 
-	return b.q.Publish(pubsub.CommandTopic.String(), message.NewMessage(watermill.NewUUID(), marshal))
+	return b.publish(ctx, m, pubsub.CommandTopic, marshal)
 }
 
-func (b *Bot) handlePhoto(m TelegramMessage) error {
+func (b *Bot) handlePostCommand(ctx context.Context, m TelegramMessage) error {
+	text := strings.TrimSpace(m.Payload)
+	if text == "" {
+		return nil
+	}
+
+	annotate(ctx, func(e *audit.Entry) { e.Args = "" })
+
+	return b.publishText(ctx, m, text)
+}
+
+func (b *Bot) handlePhoto(ctx context.Context, m TelegramMessage) error {
 	caption := strings.TrimSpace(m.Photo.Caption)
 	if caption == "" {
 		return nil
 	}
 
-	fileReader, err := b.bot.GetFile(m.Photo.FileID)
+	caption = b.attribute(m, b.cleanLinks(ctx, caption))
+
+	fileReader, err := b.bot.GetFile(ctx, m.Photo.FileID)
 	if err != nil {
 		return err
 	}
@@ -62,16 +73,195 @@ func (b *Bot) handlePhoto(m TelegramMessage) error {
 		FileContent: fileContent.Bytes(),
 	})
 
-	return b.q.Publish(pubsub.PhotoTopic.String(), message.NewMessage(watermill.NewUUID(), mb))
+	return b.publish(ctx, m, pubsub.PhotoTopic, mb)
 }
 
-func (b *Bot) handleText(m TelegramMessage) error {
+func (b *Bot) handleText(ctx context.Context, m TelegramMessage) error {
 	msg := strings.TrimSpace(m.Text)
 	if msg == "" {
 		return nil
 	}
 
-	mb, _ := easyjson.Marshal(pubsub.TextEvent{Text: msg})
+	if m.IsPrivate {
+		if r, ok := b.takeReply(m.SenderID); ok {
+			return b.sendReply(ctx, m, r, msg)
+		}
+	}
+
+	return b.publishText(ctx, m, msg)
+}
+
+func (b *Bot) publishText(ctx context.Context, m TelegramMessage, text string) error {
+	mb, _ := easyjson.Marshal(pubsub.TextEvent{Text: b.attribute(m, b.cleanLinks(ctx, text))})
+
+	return b.publish(ctx, m, pubsub.TextTopic, mb)
+}
+
+// cleanLinks expands the links of the shorteners in LINKS_SHORTENERS and
+// strips their trackers, waiting LINKS_EXPAND_TIMEOUT at most.
+func (b *Bot) cleanLinks(ctx context.Context, text string) string {
+	cfg := b.config().Links
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ExpandTimeout)
+	defer cancel()
+
+	return links.NewProcessor(
+		links.WithHTTPClient(b.client),
+		links.WithShorteners(cfg.Shorteners...),
+		links.WithTrackersStripped(cfg.StripTrackers),
+	).Process(ctx, text)
+}
+
+// attribute credits the channel the message was forwarded from as set in
+// FORWARD_ATTRIBUTION, {link} being the original post or the channel name
+// when it's not public.
+func (b *Bot) attribute(m TelegramMessage, text string) string {
+	if m.Forward.Channel == "" {
+		return text
+	}
+
+	link := m.Forward.URL
+	if link == "" {
+		link = m.Forward.Channel
+	}
+
+	a := strings.NewReplacer("{channel}", m.Forward.Channel, "{link}", link).Replace(b.config().ForwardAttribution)
+	if strings.TrimSpace(a) == "" {
+		return text
+	}
+
+	return text + "\n\n" + a
+}
+
+func (b *Bot) handleReplyButton(ctx context.Context, m TelegramMessage) error {
+	if !b.canPublishTo(m.SenderID, "twitter") {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "reply.not_allowed"))
+	}
+
+	data := strings.SplitN(m.CallbackData, "|", 2)
+
+	tweetID, err := strconv.ParseInt(data[0], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	r := pendingReply{tweetID: tweetID}
+	if len(data) > 1 {
+		r.author = data[1]
+	}
+
+	b.mu.Lock()
+	b.replies[m.SenderID] = r
+	b.mu.Unlock()
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "reply.prompt", r.author))
+}
+
+func (b *Bot) handleCancelCommand(ctx context.Context, m TelegramMessage) error {
+	if _, ok := b.takeEdit(m.SenderID); ok {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "cancel.edit"))
+	}
+
+	if _, ok := b.takeReply(m.SenderID); !ok {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "cancel.nothing"))
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "cancel.reply"))
+}
+
+func (b *Bot) takeReply(senderID string) (pendingReply, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, ok := b.replies[senderID]
+	delete(b.replies, senderID)
+
+	return r, ok
+}
+
+func (b *Bot) sendReply(ctx context.Context, m TelegramMessage, r pendingReply, text string) error {
+	mention := "@" + r.author
+	if r.author != "" && !strings.HasPrefix(strings.ToLower(text), strings.ToLower(mention)) {
+		text = mention + " " + text
+	}
+
+	ids, err := b.tc.SendReply(ctx, r.tweetID, text)
+	if err != nil {
+		return err
+	}
+
+	if b.published != nil {
+		if err := b.published.Record(ids); err != nil {
+			b.log.WithContext(ctx).WithError(err).WithField("tweet_id", r.tweetID).Warn("error recording reply")
+		}
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "reply.published"))
+}
+
+type publisherKey struct{}
+
+// publishedBy sets who approved what is published within ctx, so the
+// destinations are the ones they are limited to rather than the sender's.
+func publishedBy(ctx context.Context, publisherID string) context.Context {
+	return context.WithValue(ctx, publisherKey{}, publisherID)
+}
+
+// publish sends the payload to the topic carrying the update context, so the
+// queue can propagate its trace to the handlers, and the destinations the
+// sender, or whoever approved it, is limited to. Messages sent in a group are
+// not replied to, as the receipt is sent in private.
+func (b *Bot) publish(ctx context.Context, m TelegramMessage, topic pubsub.TopicName, payload []byte) error {
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.SetContext(ctx)
+	msg.Metadata.Set(pubsub.SenderIDKey, m.SenderID)
+	msg.Metadata.Set(pubsub.AuthorKey, m.SenderUsername)
+	msg.Metadata.Set(pubsub.LanguageKey, m.LanguageCode)
+
+	if m.Preview != nil {
+		msg.Metadata.Set(pubsub.PreviewKey, strconv.FormatBool(*m.Preview))
+	}
+
+	if m.IsPrivate {
+		msg.Metadata.Set(pubsub.MessageIDKey, strconv.Itoa(m.MessageID))
+	}
+
+	publisher := m.SenderID
+	if id, ok := ctx.Value(publisherKey{}).(string); ok {
+		publisher = id
+	}
+
+	var destinations []string
+	if publisherID, err := strconv.Atoi(publisher); err == nil {
+		if destinations = b.config().Destinations(publisherID); destinations != nil {
+			msg.Metadata.Set(pubsub.DestinationsKey, strings.Join(destinations, ","))
+		}
+	}
+
+	annotate(ctx, func(e *audit.Entry) {
+		if topic != pubsub.CommandTopic {
+			e.ContentHash = contentHash(payload)
+		}
+
+		e.MessageUUID, e.Destinations = msg.UUID, destinations
+	})
+
+	return b.q.Publish(topic.String(), msg)
+}
+
+func (b *Bot) canPublishTo(senderID string, destination string) bool {
+	id, _ := strconv.Atoi(senderID)
+
+	destinations := b.config().Destinations(id)
+	if destinations == nil {
+		return true
+	}
+
+	for _, d := range destinations {
+		if d == destination {
+			return true
+		}
+	}
 
-	return b.q.Publish(pubsub.TextTopic.String(), message.NewMessage(watermill.NewUUID(), mb))
+	return false
 }
@@ -1,17 +1,29 @@
 package bot_test
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/audit"
 	"github.com/javiyt/tweetgram/internal/bot"
 	"github.com/javiyt/tweetgram/internal/config"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/translate"
 	mb "github.com/javiyt/tweetgram/mocks/bot"
 	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	tb "gopkg.in/telebot.v3"
 )
@@ -33,6 +45,12 @@ func (m downloadImageError) Error() string {
 	return "error downloading image"
 }
 
+type publishError struct{}
+
+func (m publishError) Error() string {
+	return "error publishing message"
+}
+
 func TestHandlerStartAndHelpCommand(t *testing.T) {
 	commands := []struct {
 		command  string
@@ -52,40 +70,69 @@ func TestHandlerStartAndHelpCommand(t *testing.T) {
 		handler, mockedBot, _ := generateHandlerAndMockedBot(t, commands[i].command, config.AppConfig{})
 
 		t.Run("it should do nothing when not in private conversation", func(t *testing.T) {
-			_ = handler(bot.TelegramMessage{
+			_ = handler(context.Background(), bot.TelegramMessage{
 				IsPrivate: false,
 			})
 
 			mockedBot.AssertExpectations(t)
-			mockedBot.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+			mockedBot.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
 		})
 
 		t.Run("it should message when in private conversation", func(t *testing.T) {
 			m := bot.TelegramMessage{IsPrivate: true, SenderID: "1234"}
-			mockedBot.On("Send", m.SenderID, commands[i].expected).Once().Return(nil, nil)
+			mockedBot.On("Send", mock.Anything, m.SenderID, commands[i].expected).Once().Return(nil, nil)
 
-			_ = handler(m)
+			_ = handler(context.Background(), m)
 
 			mockedBot.AssertExpectations(t)
 		})
 	}
 
-	t.Run("it should fail when help requested by non numeric user id", func(t *testing.T) {
-		handler, _, _ := generateHandlerAndMockedBot(t, "/help", config.AppConfig{})
+	t.Run("it should send basic commands when help requested by non numeric user id", func(t *testing.T) {
+		handler, mockedBot, _ := generateHandlerAndMockedBot(t, "/help", config.AppConfig{})
 		m := bot.TelegramMessage{IsPrivate: true, SenderID: "asdf"}
+		mockedBot.On("Send", mock.Anything, m.SenderID, commands[1].expected).Once().Return(nil)
 
-		_ = handler(m)
+		require.NoError(t, handler(context.Background(), m))
+		mockedBot.AssertExpectations(t)
 	})
 
 	t.Run("it should send admin commands when user admin", func(t *testing.T) {
 		handler, mockedBot, _ := generateHandlerAndMockedBot(t, "/help", config.AppConfig{Admins: []int{1234}})
 		m := bot.TelegramMessage{IsPrivate: true, SenderID: "1234"}
-		expected := "/help - Show help\n/start - Start a conversation with the bot\n/stop - Stop notifications" +
-			" for all handlers or specific handler\n"
-		mockedBot.On("Send", m.SenderID, expected).Once().Return(nil, nil)
+		expected := "/addadmin - Add an admin by user ID or @username\n/admins - List the admins\n" +
+			"/audit - Show the last entries of the audit log or export it as csv or json\n" +
+			"/cancel - Cancel the pending reply to a tweet or draft edit\n/help - Show help\n" +
+			"/post - Publish the text following the command, also in the staff group\n" +
+			"/removeadmin - Remove an admin added with /addadmin\n" +
+			"/start - Start a conversation with the bot\n/stop - Stop notifications" +
+			" for all handlers or specific handler\n" +
+			"/translate - List the mentions and hashtags rewritten for every destination or add one\n" +
+			"/untranslate - Stop rewriting a mention or hashtag added with /translate\n"
+		mockedBot.On("Send", mock.Anything, m.SenderID, expected).Once().Return(nil, nil)
+
+		_ = handler(context.Background(), m)
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should answer in the language of the user", func(t *testing.T) {
+		handler, mockedBot, _ := generateHandlerAndMockedBot(t, "/start", config.AppConfig{})
+		m := bot.TelegramMessage{IsPrivate: true, SenderID: "1234", LanguageCode: "es-MX"}
+		mockedBot.On("Send", mock.Anything, m.SenderID,
+			"¡Gracias por usar el bot! Escribe /help para saber qué puedo hacer").Once().Return(nil)
+
+		require.NoError(t, handler(context.Background(), m))
+		mockedBot.AssertExpectations(t)
+	})
 
-		_ = handler(m)
+	t.Run("it should answer in the default language when there are no texts in the one of the user", func(t *testing.T) {
+		handler, mockedBot, _ := generateHandlerAndMockedBot(t, "/help", config.AppConfig{DefaultLanguage: "es"})
+		m := bot.TelegramMessage{IsPrivate: true, SenderID: "1234", LanguageCode: "fr"}
+		mockedBot.On("Send", mock.Anything, m.SenderID,
+			"/help - Mostrar la ayuda\n/start - Empezar una conversación con el bot\n").Once().Return(nil)
 
+		require.NoError(t, handler(context.Background(), m))
 		mockedBot.AssertExpectations(t)
 	})
 }
@@ -122,7 +169,7 @@ func TestHandlersFilters(t *testing.T) {
 				},
 			},
 			{
-				name: "it should fail when in private conversation but sender can't be converted to int",
+				name: "it should do nothing when in private conversation but sender can't be converted to int",
 				m: bot.TelegramMessage{
 					IsPrivate: true,
 					SenderID:  "asdfg",
@@ -133,7 +180,7 @@ func TestHandlersFilters(t *testing.T) {
 		for i := range testCases {
 			i := i
 			t.Run(testCases[i].name, func(t *testing.T) {
-				_ = handler(testCases[i].m)
+				require.NoError(t, handler(context.Background(), testCases[i].m))
 
 				mockedBot.AssertExpectations(t)
 				mockedQueue.Test(t)
@@ -161,7 +208,7 @@ func TestHandlerPhoto(t *testing.T) {
 	}
 
 	t.Run("it should do nothing when caption no present", func(t *testing.T) {
-		_ = handler(bot.TelegramMessage{
+		_ = handler(context.Background(), bot.TelegramMessage{
 			IsPrivate: true,
 			SenderID:  strconv.Itoa(adminID),
 			Photo:     bot.TelegramPhoto{Caption: ""},
@@ -173,10 +220,10 @@ func TestHandlerPhoto(t *testing.T) {
 	})
 
 	t.Run("it should do nothing when error getting image", func(t *testing.T) {
-		mockedBot.On("GetFile", successPhoto.Photo.FileID).Once().
+		mockedBot.On("GetFile", mock.Anything, successPhoto.Photo.FileID).Once().
 			Return(nil, downloadImageError{})
 
-		_ = handler(successPhoto)
+		_ = handler(context.Background(), successPhoto)
 
 		mockedBot.AssertExpectations(t)
 		mockedQueue.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
@@ -186,7 +233,7 @@ func TestHandlerPhoto(t *testing.T) {
 		file, _ := os.Open("testdata/test.png")
 		defer func() { _ = file.Close() }()
 
-		mockedBot.On("GetFile", successPhoto.Photo.FileID).Once().Return(file, nil)
+		mockedBot.On("GetFile", mock.Anything, successPhoto.Photo.FileID).Once().Return(file, nil)
 		mockedQueue.On(
 			"Publish",
 			pubsub.PhotoTopic.String(),
@@ -195,7 +242,7 @@ func TestHandlerPhoto(t *testing.T) {
 			}),
 		).Once().Return(nil)
 
-		_ = handler(successPhoto)
+		_ = handler(context.Background(), successPhoto)
 
 		mockedBot.AssertExpectations(t)
 		mockedQueue.AssertExpectations(t)
@@ -209,7 +256,7 @@ func TestHandlerText(t *testing.T) {
 	})
 
 	t.Run("it should do nothing when text no present", func(t *testing.T) {
-		_ = handler(bot.TelegramMessage{
+		_ = handler(context.Background(), bot.TelegramMessage{
 			IsPrivate: true,
 			SenderID:  strconv.Itoa(adminID),
 			Text:      "",
@@ -217,7 +264,7 @@ func TestHandlerText(t *testing.T) {
 
 		mockedBot.AssertExpectations(t)
 		mockedQueue.Test(t)
-		mockedQueue.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+		mockedQueue.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("it should send text when present", func(t *testing.T) {
@@ -230,17 +277,97 @@ func TestHandlerText(t *testing.T) {
 			"Publish",
 			pubsub.TextTopic.String(),
 			mock.MatchedBy(func(message *message.Message) bool {
-				return string(message.Payload) == "{\"text\":\"testing\"}"
+				return string(message.Payload) == "{\"text\":\"testing\"}" &&
+					message.Metadata.Get(pubsub.SenderIDKey) == strconv.Itoa(adminID)
 			}),
 		).Once().Return(nil)
 
-		_ = handler(m)
+		_ = handler(context.Background(), m)
 
 		mockedBot.AssertExpectations(t)
 		mockedQueue.AssertExpectations(t)
 	})
 }
 
+func TestHandleLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/short" {
+			http.Redirect(w, r, "/post?id=1&utm_source=newsletter", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.AppConfig{Admins: []int{adminID}, BroadcastChannel: broadcastChannel}
+	cfg.Links.Shorteners = []string{"bit.ly"}
+	cfg.Links.ExpandTimeout = time.Second
+	cfg.Links.StripTrackers = true
+
+	handlers, mockedBot, mockedQueue, _ := generateHandlersAndMocks(
+		t, cfg, bot.WithHTTPClient(&http.Client{Transport: rerouted(server.Listener.Addr().String())}),
+	)
+
+	t.Run("it should expand shortened links and strip their trackers", func(t *testing.T) {
+		mockedQueue.On(
+			"Publish",
+			pubsub.TextTopic.String(),
+			mock.MatchedBy(func(message *message.Message) bool {
+				return string(message.Payload) == "{\"text\":\"read http://bit.ly/post?id=1\"}" &&
+					message.Metadata.Get(pubsub.PreviewKey) == ""
+			}),
+		).Once().Return(nil)
+
+		_ = handlers[tb.OnText](context.Background(), bot.TelegramMessage{
+			IsPrivate: true,
+			SenderID:  strconv.Itoa(adminID),
+			Text:      "read bit.ly/short",
+		})
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should carry whether the sender chose to show the link preview", func(t *testing.T) {
+		preview := false
+
+		mockedQueue.On(
+			"Publish",
+			pubsub.TextTopic.String(),
+			mock.MatchedBy(func(message *message.Message) bool {
+				return string(message.Payload) == "{\"text\":\"read https://example.com/?id=2\"}" &&
+					message.Metadata.Get(pubsub.PreviewKey) == "false"
+			}),
+		).Once().Return(nil)
+
+		_ = handlers[tb.OnText](context.Background(), bot.TelegramMessage{
+			IsPrivate: true,
+			SenderID:  strconv.Itoa(adminID),
+			Text:      "read https://example.com/?fbclid=abc&id=2",
+			Preview:   &preview,
+		})
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+	})
+}
+
+// rerouted sends every request to the test server at its address.
+type rerouted string
+
+func (r rerouted) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = "http"
+	out.URL.Host = string(r)
+
+	resp, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Request = req
+
+	return resp, nil
+}
+
 func TestHandleStopNotifications(t *testing.T) {
 	handler, mockedBot, mockedQueue := generateHandlerAndMockedBot(t, "/stop", config.AppConfig{
 		Admins:           []int{adminID},
@@ -251,7 +378,7 @@ func TestHandleStopNotifications(t *testing.T) {
 		mockedQueue.On("Publish", pubsub.CommandTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
 			return string(m.Payload) == "{\"command\":0,\"handler\":\"\"}"
 		})).Once().Return(nil)
-		_ = handler(bot.TelegramMessage{
+		_ = handler(context.Background(), bot.TelegramMessage{
 			IsPrivate: true,
 			SenderID:  strconv.Itoa(adminID),
 			Text:      "/stop",
@@ -265,7 +392,7 @@ func TestHandleStopNotifications(t *testing.T) {
 		mockedQueue.On("Publish", pubsub.CommandTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
 			return string(m.Payload) == "{\"command\":0,\"handler\":\"telegram\"}"
 		})).Once().Return(nil)
-		_ = handler(bot.TelegramMessage{
+		_ = handler(context.Background(), bot.TelegramMessage{
 			IsPrivate: true,
 			SenderID:  strconv.Itoa(adminID),
 			Text:      "/stop telegram",
@@ -277,44 +404,865 @@ func TestHandleStopNotifications(t *testing.T) {
 	})
 }
 
+func TestHandleReplyToTweet(t *testing.T) {
+	cfg := config.AppConfig{
+		Admins:           []int{adminID},
+		BroadcastChannel: broadcastChannel,
+	}
+	sender := strconv.Itoa(adminID)
+
+	t.Run("it should fail when callback data is not a tweet id", func(t *testing.T) {
+		hs, mockedBot, _, _ := generateHandlersAndMocks(t, cfg)
+
+		require.Error(t, hs["\f"+bot.ReplyButton](context.Background(), bot.TelegramMessage{
+			IsPrivate:    true,
+			SenderID:     sender,
+			CallbackData: "asdf|someone",
+		}))
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should publish next text as reply in thread", func(t *testing.T) {
+		hs, mockedBot, mockedQueue, mockedTwitter := generateHandlersAndMocks(t, cfg)
+
+		mockedBot.On("Send", mock.Anything, sender, "Send me the reply to @someone or type /cancel to discard it").
+			Once().Return(nil)
+		mockedTwitter.On("SendReply", mock.Anything, int64(1234), "@someone thanks!").Once().Return([]int64{1235}, nil)
+		mockedBot.On("Send", mock.Anything, sender, "Reply published").Once().Return(nil)
+
+		require.NoError(t, hs["\f"+bot.ReplyButton](context.Background(), bot.TelegramMessage{
+			IsPrivate:    true,
+			SenderID:     sender,
+			CallbackData: "1234|someone",
+		}))
+		require.NoError(t, hs[tb.OnText](
+			context.Background(),
+			bot.TelegramMessage{IsPrivate: true, SenderID: sender, Text: "thanks!"},
+		))
+
+		mockedBot.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should record the reply as published, so the timeline doesn't import it", func(t *testing.T) {
+		mockedPublished := new(mb.PublishedRecorder)
+		hs, mockedBot, _, mockedTwitter := generateHandlersAndMocks(t, cfg, bot.WithPublishedRecorder(mockedPublished))
+
+		mockedBot.On("Send", mock.Anything, sender, "Send me the reply to @someone or type /cancel to discard it").
+			Once().Return(nil)
+		mockedTwitter.On("SendReply", mock.Anything, int64(1234), "@someone thanks!").
+			Once().Return([]int64{1235, 1236}, nil)
+		mockedPublished.On("Record", []int64{1235, 1236}).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, sender, "Reply published").Once().Return(nil)
+
+		require.NoError(t, hs["\f"+bot.ReplyButton](context.Background(), bot.TelegramMessage{
+			IsPrivate:    true,
+			SenderID:     sender,
+			CallbackData: "1234|someone",
+		}))
+		require.NoError(t, hs[tb.OnText](
+			context.Background(),
+			bot.TelegramMessage{IsPrivate: true, SenderID: sender, Text: "thanks!"},
+		))
+
+		mockedBot.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+		mockedPublished.AssertExpectations(t)
+	})
+
+	t.Run("it should fail when reply can't be published", func(t *testing.T) {
+		hs, mockedBot, _, mockedTwitter := generateHandlersAndMocks(t, cfg)
+
+		mockedBot.On("Send", mock.Anything, sender, "Send me the reply to @someone or type /cancel to discard it").
+			Once().Return(nil)
+		mockedTwitter.On("SendReply", mock.Anything, int64(1234), "@Someone thanks!").Once().Return(nil, downloadImageError{})
+
+		require.NoError(t, hs["\f"+bot.ReplyButton](context.Background(), bot.TelegramMessage{
+			IsPrivate:    true,
+			SenderID:     sender,
+			CallbackData: "1234|someone",
+		}))
+		require.EqualError(
+			t,
+			hs[tb.OnText](
+				context.Background(),
+				bot.TelegramMessage{IsPrivate: true, SenderID: sender, Text: "@Someone thanks!"},
+			),
+			"error downloading image",
+		)
+
+		mockedBot.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+	})
+
+	t.Run("it should discard pending reply on cancel", func(t *testing.T) {
+		hs, mockedBot, mockedQueue, mockedTwitter := generateHandlersAndMocks(t, cfg)
+
+		mockedBot.On("Send", mock.Anything, sender, "There is nothing to cancel").Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, sender, "Send me the reply to @someone or type /cancel to discard it").
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, sender, "Reply discarded").Once().Return(nil)
+		mockedQueue.On("Publish", pubsub.TextTopic.String(), mock.Anything).Once().Return(nil)
+
+		require.NoError(t, hs["/cancel"](context.Background(), bot.TelegramMessage{IsPrivate: true, SenderID: sender}))
+		require.NoError(t, hs["\f"+bot.ReplyButton](context.Background(), bot.TelegramMessage{
+			IsPrivate:    true,
+			SenderID:     sender,
+			CallbackData: "1234|someone",
+		}))
+		require.NoError(t, hs["/cancel"](context.Background(), bot.TelegramMessage{IsPrivate: true, SenderID: sender}))
+		require.NoError(t, hs[tb.OnText](
+			context.Background(),
+			bot.TelegramMessage{IsPrivate: true, SenderID: sender, Text: "testing"},
+		))
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+		mockedTwitter.AssertNotCalled(t, "SendReply", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandlersRoles(t *testing.T) {
+	publisherID, reviewerID := 2345, 3456
+	publisher, reviewer := strconv.Itoa(publisherID), strconv.Itoa(reviewerID)
+	cfg := config.AppConfig{
+		Admins:           []int{adminID},
+		Publishers:       []int{publisherID},
+		Reviewers:        []int{reviewerID},
+		PublishTo:        []string{"telegram", "feed"},
+		BroadcastChannel: broadcastChannel,
+	}
+
+	t.Run("it should send commands allowed to the user role", func(t *testing.T) {
+		hs, mockedBot, _, _ := generateHandlersAndMocks(t, cfg)
+		expected := "/cancel - Cancel the pending reply to a tweet or draft edit\n/help - Show help\n" +
+			"/post - Publish the text following the command, also in the staff group\n" +
+			"/start - Start a conversation with the bot\n"
+		mockedBot.On("Send", mock.Anything, publisher, expected).Once().Return(nil)
+
+		require.NoError(t, hs["/help"](context.Background(), bot.TelegramMessage{IsPrivate: true, SenderID: publisher}))
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should not publish messages sent by reviewers", func(t *testing.T) {
+		hs, _, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+
+		require.NoError(t, hs[tb.OnText](
+			context.Background(),
+			bot.TelegramMessage{IsPrivate: true, SenderID: reviewer, Text: "testing"},
+		))
+
+		mockedQueue.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+
+	t.Run("it should limit the destinations of messages sent by publishers", func(t *testing.T) {
+		hs, _, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		mockedQueue.On(
+			"Publish",
+			pubsub.TextTopic.String(),
+			mock.MatchedBy(func(message *message.Message) bool {
+				return message.Metadata.Get(pubsub.DestinationsKey) == "telegram,feed"
+			}),
+		).Once().Return(nil)
+
+		require.NoError(t, hs[tb.OnText](
+			context.Background(),
+			bot.TelegramMessage{IsPrivate: true, SenderID: publisher, Text: "testing"},
+		))
+
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should not let publishers reply to tweets when twitter isn't allowed", func(t *testing.T) {
+		hs, mockedBot, _, mockedTwitter := generateHandlersAndMocks(t, cfg)
+		mockedBot.On("Send", mock.Anything, publisher, "You are not allowed to publish on Twitter").Once().Return(nil)
+
+		require.NoError(t, hs["\f"+bot.ReplyButton](context.Background(), bot.TelegramMessage{
+			IsPrivate:    true,
+			SenderID:     publisher,
+			CallbackData: "1234|someone",
+		}))
+
+		mockedBot.AssertExpectations(t)
+		mockedTwitter.AssertNotCalled(t, "SendReply", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleAdmins(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.AppConfig{Admins: []int{adminID}, Publishers: []int{6789}, BroadcastChannel: broadcastChannel}
+	owner := strconv.Itoa(adminID)
+	store := storage.NewFileStore(t.TempDir())
+	hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg, bot.WithStore(store))
+
+	send := func(t *testing.T, handler string, m bot.TelegramMessage, expected string) {
+		t.Helper()
+
+		m.IsPrivate = true
+		mockedBot.On("Send", mock.Anything, m.SenderID, expected).Once().Return(nil)
+
+		require.NoError(t, hs[handler](ctx, m))
+		mockedBot.AssertExpectations(t)
+	}
+
+	t.Run("it should not let other users manage admins", func(t *testing.T) {
+		require.NoError(t, hs["/addadmin"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: "6789", Payload: "6789"}))
+
+		mockedBot.AssertNotCalled(t, "Send", mock.Anything, "6789", mock.Anything)
+	})
+
+	t.Run("it should explain usage when argument is not valid", func(t *testing.T) {
+		send(t, "/addadmin", bot.TelegramMessage{SenderID: owner, Payload: "someone"}, "Usage: /addadmin <id|@username>")
+		send(t, "/removeadmin", bot.TelegramMessage{SenderID: owner}, "Usage: /removeadmin <id>")
+	})
+
+	t.Run("it should not add users who haven't talked to the bot by username", func(t *testing.T) {
+		send(t, "/addadmin", bot.TelegramMessage{SenderID: owner, Payload: "@someone"},
+			"I don't know @someone, only staff members who talked to the bot can be added by username")
+	})
+
+	t.Run("it should not remember the username of users without a role", func(t *testing.T) {
+		send(t, "/start", bot.TelegramMessage{SenderID: "7777", SenderUsername: "stranger"},
+			"Thanks for using the bot! You can type /help command to know what can I do")
+		send(t, "/addadmin", bot.TelegramMessage{SenderID: owner, Payload: "@stranger"},
+			"I don't know @stranger, only staff members who talked to the bot can be added by username")
+	})
+
+	t.Run("it should add admin by username", func(t *testing.T) {
+		send(t, "/start", bot.TelegramMessage{SenderID: "6789", SenderUsername: "Someone"},
+			"Thanks for using the bot! You can type /help command to know what can I do")
+		send(t, "/addadmin", bot.TelegramMessage{SenderID: owner, Payload: "@someone"}, "@someone is now an admin")
+		send(t, "/addadmin", bot.TelegramMessage{SenderID: owner, Payload: "6789"}, "6789 is already an admin")
+
+		mockedQueue.On("Publish", pubsub.CommandTopic.String(), mock.Anything).Once().Return(nil)
+		require.NoError(t, hs["/stop"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: "6789"}))
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should add admin by id", func(t *testing.T) {
+		send(t, "/addadmin", bot.TelegramMessage{SenderID: owner, Payload: "5555"}, "5555 is now an admin")
+	})
+
+	t.Run("it should list every admin", func(t *testing.T) {
+		send(t, "/admins", bot.TelegramMessage{SenderID: "6789"}, "12345 (configured)\n6789 @someone\n5555\n")
+	})
+
+	t.Run("it should keep added admins after a restart", func(t *testing.T) {
+		restarted, restartedBot, _, _ := generateHandlersAndMocks(t, cfg, bot.WithStore(store))
+		restartedBot.On("Send", mock.Anything, owner, "12345 (configured)\n6789 @someone\n5555\n").Once().Return(nil)
+
+		require.NoError(t, restarted["/admins"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner}))
+		restartedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should not remove configured admins", func(t *testing.T) {
+		send(t, "/removeadmin", bot.TelegramMessage{SenderID: "6789", Payload: owner},
+			"12345 is configured as admin and can only be removed from the configuration")
+	})
+
+	t.Run("it should remove added admins", func(t *testing.T) {
+		send(t, "/removeadmin", bot.TelegramMessage{SenderID: owner, Payload: "6789"}, "6789 is no longer an admin")
+		send(t, "/removeadmin", bot.TelegramMessage{SenderID: owner, Payload: "6789"}, "6789 is not an admin")
+		send(t, "/admins", bot.TelegramMessage{SenderID: owner}, "12345 (configured)\n5555\n")
+	})
+
+	t.Run("it should keep every admin added at the same time", func(t *testing.T) {
+		store := slowStore{storage.NewFileStore(t.TempDir())}
+		hs, mockedBot, _, _ := generateHandlersAndMocks(t, cfg, bot.WithStore(store))
+		mockedBot.On("Send", mock.Anything, owner, mock.Anything).Return(nil)
+
+		errs := make(chan error)
+
+		for id := 1001; id <= 1010; id++ {
+			go func(id int) {
+				errs <- hs["/addadmin"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner, Payload: strconv.Itoa(id)})
+			}(id)
+		}
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, <-errs)
+		}
+
+		var admins []int
+		require.NoError(t, store.Load("bot/admins", &admins))
+		require.ElementsMatch(t, []int{1001, 1002, 1003, 1004, 1005, 1006, 1007, 1008, 1009, 1010}, admins)
+	})
+}
+
+// slowStore takes a while to save, so concurrent updates overlap.
+type slowStore struct {
+	storage.Store
+}
+
+func (s slowStore) Save(key string, v interface{}) error {
+	time.Sleep(5 * time.Millisecond)
+
+	return s.Store.Save(key, v)
+}
+
+func TestHandleTranslations(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.AppConfig{
+		Admins:           []int{adminID},
+		Publishers:       []int{2345},
+		BroadcastChannel: broadcastChannel,
+		Translate:        config.TranslateConfig{Twitter: map[string]string{"@Acme_TG": "@AcmeCorp"}},
+	}
+	owner := strconv.Itoa(adminID)
+	table := translate.NewTable(storage.NewFileStore(t.TempDir()))
+	hs, mockedBot, _, _ := generateHandlersAndMocks(t, cfg, bot.WithTranslations(table))
+
+	send := func(t *testing.T, handler string, payload string, expected string) {
+		t.Helper()
+
+		mockedBot.On("Send", mock.Anything, owner, expected).Once().Return(nil)
+
+		require.NoError(t, hs[handler](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner, Payload: payload}))
+		mockedBot.AssertExpectations(t)
+	}
+
+	t.Run("it should not let other users manage translations", func(t *testing.T) {
+		require.NoError(t, hs["/translate"](ctx, bot.TelegramMessage{
+			IsPrivate: true,
+			SenderID:  "2345",
+			Payload:   "twitter #tg #tw",
+		}))
+
+		mockedBot.AssertNotCalled(t, "Send", mock.Anything, "2345", mock.Anything)
+		require.Empty(t, table.Rules("twitter"))
+	})
+
+	t.Run("it should explain usage when arguments are not valid", func(t *testing.T) {
+		send(t, "/translate", "twitter #tg", "Usage: /translate [<destination> <@mention|#hashtag> <text>]")
+		send(t, "/translate", "instagram #tg #ig",
+			"instagram is not a destination, use one of telegram, twitter, feed, email, archive")
+		send(t, "/translate", "twitter tg #tw", "tg is not a mention or hashtag")
+		send(t, "/untranslate", "twitter", "Usage: /untranslate <destination> <@mention|#hashtag>")
+	})
+
+	t.Run("it should add translations", func(t *testing.T) {
+		send(t, "/translate", "Twitter #TG #tw", "#TG is now written #tw on twitter")
+		send(t, "/translate", "feed @acme_tg ACME Corp", "@acme_tg is now written ACME Corp on feed")
+
+		require.Equal(t, translate.Rules{"#tg": "#tw"}, table.Rules("twitter"))
+	})
+
+	t.Run("it should list configured and added translations", func(t *testing.T) {
+		send(t, "/translate", "",
+			"twitter: #tg → #tw\ntwitter: @acme_tg → @AcmeCorp (configured)\nfeed: @acme_tg → ACME Corp\n")
+	})
+
+	t.Run("it should not remove configured translations", func(t *testing.T) {
+		send(t, "/untranslate", "twitter @acme_tg",
+			"@acme_tg is configured for twitter and can only be removed from the configuration")
+		send(t, "/untranslate", "email @acme_tg", "@acme_tg is not rewritten on email")
+	})
+
+	t.Run("it should remove added translations", func(t *testing.T) {
+		send(t, "/untranslate", "twitter #tg", "#tg is no longer rewritten on twitter")
+		send(t, "/untranslate", "feed @acme_tg", "@acme_tg is no longer rewritten on feed")
+		send(t, "/translate", "", "twitter: @acme_tg → @AcmeCorp (configured)\n")
+	})
+
+	t.Run("it should say when there are no translations", func(t *testing.T) {
+		hs, mockedBot, _, _ := generateHandlersAndMocks(t, config.AppConfig{Admins: []int{adminID}})
+		mockedBot.On("Send", mock.Anything, owner, "No mention or hashtag is rewritten").Once().Return(nil)
+
+		require.NoError(t, hs["/translate"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner}))
+		mockedBot.AssertExpectations(t)
+	})
+}
+
+func TestHandleDrafts(t *testing.T) {
+	ctx := context.Background()
+	owner, reviewer, contributor := strconv.Itoa(adminID), "3456", "4567"
+	cfg := config.AppConfig{
+		Admins:           []int{adminID},
+		Reviewers:        []int{3456},
+		Contributors:     []int{4567},
+		Viewers:          []int{5678},
+		BroadcastChannel: broadcastChannel,
+	}
+
+	submit := func(t *testing.T, hs map[string]bot.TelegramHandler, mockedBot *mb.TelegramBot, what interface{}) string {
+		t.Helper()
+
+		var id string
+
+		keyboard := mock.MatchedBy(func(k bot.TelegramKeyboard) bool {
+			id = k[0][0].Data
+
+			return len(k[0]) == 3
+		})
+		mockedBot.On("Send", mock.Anything, owner, what, keyboard).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, reviewer, what, keyboard).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, contributor, "Your post has been sent for review", bot.TelegramReplyTo(10)).
+			Once().Return(nil)
+
+		m := bot.TelegramMessage{IsPrivate: true, SenderID: contributor, SenderUsername: "writer", MessageID: 10}
+		if p, ok := what.(bot.TelegramPhoto); ok {
+			m.SenderUsername = ""
+			m.Photo = bot.TelegramPhoto{Caption: "caption", FileID: p.FileID}
+			require.NoError(t, hs[tb.OnPhoto](ctx, m))
+		} else {
+			m.Text = "testing"
+			require.NoError(t, hs[tb.OnText](ctx, m))
+		}
+
+		mockedBot.AssertExpectations(t)
+
+		return id
+	}
+
+	t.Run("it should publish drafts once approved", func(t *testing.T) {
+		hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		id := submit(t, hs, mockedBot, "Draft from @writer:\n\ntesting")
+
+		mockedQueue.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+
+		mockedQueue.On("Publish", pubsub.TextTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return string(m.Payload) == "{\"text\":\"testing\"}" &&
+				m.Metadata.Get(pubsub.SenderIDKey) == contributor &&
+				m.Metadata.Get(pubsub.MessageIDKey) == "10"
+		})).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, contributor, "Your post has been approved", bot.TelegramReplyTo(10)).
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, reviewer, "Draft approved and published").Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, owner, "This draft has already been reviewed").Once().Return(nil)
+
+		require.NoError(t, hs["\fapprove"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: reviewer, CallbackData: id}))
+		require.NoError(t, hs["\freject"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner, CallbackData: id}))
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should publish approved drafts only where the publisher approving them can", func(t *testing.T) {
+		publisher := "2345"
+		cfg := cfg
+		cfg.Publishers = []int{2345}
+		cfg.PublishTo = []string{"telegram"}
+
+		hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		mockedBot.On("Send", mock.Anything, publisher, "Draft from @writer:\n\ntesting", mock.Anything).Once().Return(nil)
+		id := submit(t, hs, mockedBot, "Draft from @writer:\n\ntesting")
+
+		mockedQueue.On("Publish", pubsub.TextTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return m.Metadata.Get(pubsub.SenderIDKey) == contributor &&
+				m.Metadata.Get(pubsub.DestinationsKey) == "telegram"
+		})).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, contributor, "Your post has been approved", bot.TelegramReplyTo(10)).
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, publisher, "Draft approved and published").Once().Return(nil)
+
+		require.NoError(t, hs["\fapprove"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: publisher, CallbackData: id}))
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should publish photo drafts once approved", func(t *testing.T) {
+		hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		id := submit(t, hs, mockedBot, bot.TelegramPhoto{Caption: "Draft from user 4567:\n\ncaption", FileID: "abc"})
+
+		mockedBot.On("GetFile", mock.Anything, "abc").Once().Return(io.NopCloser(strings.NewReader("image")), nil)
+		mockedQueue.On("Publish", pubsub.PhotoTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return m.Metadata.Get(pubsub.SenderIDKey) == contributor
+		})).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, contributor, "Your post has been approved", bot.TelegramReplyTo(10)).
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, owner, "Draft approved and published").Once().Return(nil)
+
+		require.NoError(t, hs["\fapprove"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner, CallbackData: id}))
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should keep drafts that couldn't be published to approve them again", func(t *testing.T) {
+		hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		id := submit(t, hs, mockedBot, "Draft from @writer:\n\ntesting")
+		approve := bot.TelegramMessage{IsPrivate: true, SenderID: reviewer, CallbackData: id}
+
+		mockedQueue.On("Publish", pubsub.TextTopic.String(), mock.Anything).Once().Return(publishError{})
+		require.EqualError(t, hs["\fapprove"](ctx, approve), "error publishing message")
+
+		mockedQueue.On("Publish", pubsub.TextTopic.String(), mock.Anything).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, contributor, "Your post has been approved", bot.TelegramReplyTo(10)).
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, reviewer, "Draft approved and published").Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, reviewer, "This draft has already been reviewed").Once().Return(nil)
+
+		require.NoError(t, hs["\fapprove"](ctx, approve))
+		require.NoError(t, hs["\fapprove"](ctx, approve))
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should edit drafts before reviewing them", func(t *testing.T) {
+		hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		id := submit(t, hs, mockedBot, "Draft from @writer:\n\ntesting")
+		edit := bot.TelegramMessage{IsPrivate: true, SenderID: reviewer, CallbackData: id}
+
+		mockedBot.On("Send", mock.Anything, reviewer, "Send me the new text of the draft or type /cancel to keep it").
+			Twice().Return(nil)
+		mockedBot.On("Send", mock.Anything, reviewer, "Draft edit discarded").Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, reviewer, "Draft from @writer:\n\nedited", bot.TelegramKeyboard{{
+			{Unique: "approve", Text: "Approve", Data: id},
+			{Unique: "edit", Text: "Edit", Data: id},
+			{Unique: "reject", Text: "Reject", Data: id},
+		}}).
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, contributor, "Your post has been rejected", bot.TelegramReplyTo(10)).
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, reviewer, "Draft rejected").Once().Return(nil)
+
+		require.NoError(t, hs["\fedit"](ctx, edit))
+		require.NoError(t, hs["/cancel"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: reviewer}))
+		require.NoError(t, hs["\fedit"](ctx, edit))
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: reviewer, Text: "edited"}))
+		require.NoError(t, hs["\freject"](ctx, edit))
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+
+	t.Run("it should ignore posts sent by viewers", func(t *testing.T) {
+		hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: "5678", Text: "testing"}))
+
+		mockedBot.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockedQueue.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleStaffGroup(t *testing.T) {
+	ctx := context.Background()
+	staffGroup := int64(-1001)
+	sender := strconv.Itoa(adminID)
+	cfg := config.AppConfig{
+		Admins:             []int{adminID},
+		BroadcastChannel:   broadcastChannel,
+		StaffGroup:         staffGroup,
+		ForwardAttribution: "Forwarded from {channel}: {link}",
+	}
+
+	publishes := func(mockedQueue *mq.Queue, payload string) {
+		mockedQueue.On("Publish", pubsub.TextTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return string(m.Payload) == payload &&
+				m.Metadata.Get(pubsub.SenderIDKey) == sender &&
+				m.Metadata.Get(pubsub.MessageIDKey) == ""
+		})).Once().Return(nil)
+	}
+
+	t.Run("it should publish messages mentioning the bot in the staff group", func(t *testing.T) {
+		hs, _, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		publishes(mockedQueue, "{\"text\":\"testing\"}")
+
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{
+			SenderID: sender, ChatID: staffGroup, MessageID: 10, Text: "testing", Mentioned: true,
+		}))
+
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should publish post command in the staff group", func(t *testing.T) {
+		hs, _, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		publishes(mockedQueue, "{\"text\":\"testing\"}")
+
+		require.NoError(t, hs["/post"](ctx, bot.TelegramMessage{SenderID: sender, ChatID: staffGroup, Payload: "testing"}))
+
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should keep the pending reply of admins publishing in the staff group", func(t *testing.T) {
+		hs, mockedBot, mockedQueue, mockedTwitter := generateHandlersAndMocks(t, cfg)
+		publishes(mockedQueue, "{\"text\":\"testing\"}")
+
+		mockedBot.On("Send", mock.Anything, sender, "Send me the reply to @someone or type /cancel to discard it").
+			Once().Return(nil)
+		mockedTwitter.On("SendReply", mock.Anything, int64(1234), "@someone thanks!").Once().Return([]int64{1235}, nil)
+		mockedBot.On("Send", mock.Anything, sender, "Reply published").Once().Return(nil)
+
+		require.NoError(t, hs["\f"+bot.ReplyButton](ctx, bot.TelegramMessage{
+			IsPrivate: true, SenderID: sender, CallbackData: "1234|someone",
+		}))
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{
+			SenderID: sender, ChatID: staffGroup, MessageID: 10, Text: "testing", Mentioned: true,
+		}))
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: sender, Text: "thanks!"}))
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+	})
+
+	t.Run("it should ignore group messages not meant to be published", func(t *testing.T) {
+		hs, _, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{SenderID: sender, ChatID: staffGroup, Text: "testing"}))
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{
+			SenderID: sender, ChatID: -2002, Text: "testing", Mentioned: true,
+		}))
+		require.NoError(t, hs["/post"](ctx, bot.TelegramMessage{SenderID: sender, ChatID: -2002, Payload: "testing"}))
+		require.NoError(t, hs["/post"](ctx, bot.TelegramMessage{SenderID: "54321", ChatID: staffGroup, Payload: "testing"}))
+
+		mockedQueue.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+
+	t.Run("it should credit the channel forwarded messages come from", func(t *testing.T) {
+		hs, _, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
+		publishes(mockedQueue, "{\"text\":\"testing\\n\\nForwarded from News: https://t.me/news/42\"}")
+		publishes(mockedQueue, "{\"text\":\"testing\\n\\nForwarded from Private: Private\"}")
+
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{
+			SenderID: sender, ChatID: staffGroup, Text: "testing", Mentioned: true,
+			Forward: bot.TelegramForward{Channel: "News", URL: "https://t.me/news/42"},
+		}))
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{
+			SenderID: sender, ChatID: staffGroup, Text: "testing", Mentioned: true,
+			Forward: bot.TelegramForward{Channel: "Private"},
+		}))
+
+		mockedQueue.AssertExpectations(t)
+	})
+}
+
+func TestHandleUnauthorized(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.AppConfig{
+		Admins:               []int{adminID},
+		Reviewers:            []int{3456},
+		BroadcastChannel:     broadcastChannel,
+		UnauthorizedAction:   "request",
+		UnauthorizedInterval: time.Hour,
+	}
+	stranger := bot.TelegramMessage{
+		IsPrivate:      true,
+		SenderID:       "7777",
+		SenderUsername: "stranger",
+		LanguageCode:   "es-ES",
+		Text:           "testing",
+	}
+
+	t.Run("it should reply once per interval with the option to request access", func(t *testing.T) {
+		now := time.Now()
+		logger, hook := logrusTest.NewNullLogger()
+		hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg,
+			bot.WithClock(func() time.Time { return now }),
+			bot.WithLogger(logger),
+		)
+		mockedBot.On("Send", mock.Anything, "7777", "Lo siento, no tienes permiso para usar este bot",
+			bot.TelegramKeyboard{{{Unique: "access", Text: "Solicitar acceso"}}}).Twice().Return(nil)
+
+		require.NoError(t, hs[tb.OnText](ctx, stranger))
+		require.NoError(t, hs["/stop"](ctx, stranger))
+
+		now = now.Add(time.Hour)
+		require.NoError(t, hs[tb.OnText](ctx, stranger))
+
+		mockedBot.AssertExpectations(t)
+		mockedQueue.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+		require.Len(t, hook.Entries, 2)
+		require.Equal(t, "unauthorized update", hook.LastEntry().Message)
+		require.Equal(t, "7777", hook.LastEntry().Data["user_id"])
+		require.Equal(t, "stranger", hook.LastEntry().Data["username"])
+	})
+
+	t.Run("it should notify owners when access is requested", func(t *testing.T) {
+		hs, mockedBot, _, _ := generateHandlersAndMocks(t, cfg)
+		mockedBot.On("Send", mock.Anything, strconv.Itoa(adminID), "User 7777 (@stranger) asks for access to the bot").
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, "7777", "Tu solicitud se ha enviado a los propietarios").Once().Return(nil)
+
+		require.NoError(t, hs["\faccess"](ctx, stranger))
+		require.NoError(t, hs["\faccess"](ctx, stranger))
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should tell users with a role they aren't allowed to do it", func(t *testing.T) {
+		hs, mockedBot, _, _ := generateHandlersAndMocks(t, cfg)
+		mockedBot.On("Send", mock.Anything, "3456", "Sorry, you are not allowed to do that").Once().Return(nil)
+
+		require.NoError(t, hs["/stop"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: "3456"}))
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should only reply when access can't be requested", func(t *testing.T) {
+		replyCfg := cfg
+		replyCfg.UnauthorizedAction = "reply"
+		hs, mockedBot, _, _ := generateHandlersAndMocks(t, replyCfg)
+		mockedBot.On("Send", mock.Anything, "7777", "Sorry, you are not allowed to use this bot").Once().Return(nil)
+
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: "7777", Text: "testing"}))
+		require.NoError(t, hs["\faccess"](ctx, stranger))
+
+		mockedBot.AssertExpectations(t)
+	})
+}
+
+func TestHandleAuditUnauthorized(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 5, 3, 10, 20, 30, 0, time.UTC)
+	cfg := config.AppConfig{
+		Admins:               []int{adminID},
+		BroadcastChannel:     broadcastChannel,
+		UnauthorizedAction:   "request",
+		UnauthorizedInterval: time.Hour,
+	}
+	l := audit.NewFileLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	hs, mockedBot, _, _ := generateHandlersAndMocks(t, cfg,
+		bot.WithAuditLog(l),
+		bot.WithClock(func() time.Time { return now }),
+	)
+	stranger := bot.TelegramMessage{IsPrivate: true, SenderID: "7777", SenderUsername: "stranger", Text: "testing"}
+
+	t.Run("it should record every denied update and access request of users without a role", func(t *testing.T) {
+		mockedBot.On("Send", mock.Anything, "7777", "Sorry, you are not allowed to use this bot", mock.Anything).
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, strconv.Itoa(adminID), "User 7777 (@stranger) asks for access to the bot").
+			Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, "7777", "Your request has been sent to the owners").Once().Return(nil)
+
+		require.NoError(t, hs[tb.OnText](ctx, stranger))
+		require.NoError(t, hs["/stop"](ctx, stranger))
+		require.NoError(t, hs["\faccess"](ctx, stranger))
+		require.NoError(t, hs["\faccess"](ctx, stranger))
+
+		entries, err := l.Last(0)
+
+		require.NoError(t, err)
+		require.Equal(t, []audit.Entry{
+			{Time: now, SenderID: "7777", Username: "stranger", Action: "text", Outcome: audit.OutcomeDenied},
+			{Time: now, SenderID: "7777", Username: "stranger", Action: "/stop", Outcome: audit.OutcomeDenied},
+			{Time: now, SenderID: "7777", Username: "stranger", Action: "access", Outcome: audit.OutcomeOK},
+			{Time: now, SenderID: "7777", Username: "stranger", Action: "access", Outcome: audit.OutcomeDenied},
+		}, entries)
+		mockedBot.AssertExpectations(t)
+	})
+}
+
+func TestHandleAudit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 5, 3, 10, 20, 30, 0, time.UTC)
+	cfg := config.AppConfig{Admins: []int{adminID}, Reviewers: []int{3456}, BroadcastChannel: broadcastChannel}
+	l := audit.NewFileLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg,
+		bot.WithAuditLog(l),
+		bot.WithClock(func() time.Time { return now }),
+	)
+	owner := strconv.Itoa(adminID)
+	send := func(t *testing.T, text string) {
+		mockedBot.On("Send", mock.Anything, owner, text).Once().Return(nil)
+	}
+
+	t.Run("it should record the interactions of users with a role", func(t *testing.T) {
+		var published *message.Message
+
+		mockedQueue.On("Publish", pubsub.TextTopic.String(), mock.Anything).Once().
+			Run(func(args mock.Arguments) { published, _ = args.Get(1).(*message.Message) }).Return(nil)
+
+		require.NoError(t, hs[tb.OnText](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner, Text: "testing"}))
+		require.NoError(t, hs["/stop"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: "3456", Payload: "twitter"}))
+
+		entries, err := l.Last(0)
+
+		require.NoError(t, err)
+		require.Equal(t, []audit.Entry{
+			{
+				Time:        now,
+				SenderID:    owner,
+				Action:      "text",
+				ContentHash: "80a798ca500a793306f9c5515e1d7f66bf7574bfe1dde3a9df227076df9f6580",
+				MessageUUID: published.UUID,
+				Outcome:     audit.OutcomeOK,
+			},
+			{Time: now, SenderID: "3456", Action: "/stop", Args: "twitter", Outcome: audit.OutcomeDenied},
+		}, entries)
+	})
+
+	t.Run("it should list the last entries", func(t *testing.T) {
+		send(t, "2022-05-03 10:20:30 12345 text 80a798ca500a ok\n2022-05-03 10:20:30 3456 /stop twitter denied")
+		send(t, "2022-05-03 10:20:30 12345 /audit ok")
+		send(t, "Usage: /audit [number|csv|json]")
+
+		require.NoError(t, hs["/audit"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner}))
+		require.NoError(t, hs["/audit"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner, Payload: "1"}))
+		require.NoError(t, hs["/audit"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner, Payload: "all"}))
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should export the log as a file", func(t *testing.T) {
+		mockedBot.On("Send", mock.Anything, owner, mock.MatchedBy(func(d bot.TelegramDocument) bool {
+			return d.FileName == "audit.csv" && strings.HasPrefix(string(d.Content), "time,sender_id,username,") &&
+				strings.Count(string(d.Content), "\n") == 6
+		})).Once().Return(nil)
+
+		require.NoError(t, hs["/audit"](ctx, bot.TelegramMessage{IsPrivate: true, SenderID: owner, Payload: "CSV"}))
+
+		mockedBot.AssertExpectations(t)
+	})
+}
+
 func generateHandlerAndMockedBot(
 	t *testing.T,
 	toHandle string,
 	cfg config.AppConfig,
 ) (bot.TelegramHandler, *mb.TelegramBot, *mq.Queue) {
-	allHandlers := []string{"/start", "/help", "/stop", tb.OnPhoto, tb.OnText}
+	hs, mockedBot, mockedQueue, _ := generateHandlersAndMocks(t, cfg)
 
-	var (
-		handler bot.TelegramHandler
-		ok      bool
-	)
+	return hs[toHandle], mockedBot, mockedQueue
+}
+
+func generateHandlersAndMocks(
+	t *testing.T,
+	cfg config.AppConfig,
+	options ...bot.Option,
+) (map[string]bot.TelegramHandler, *mb.TelegramBot, *mq.Queue, *mb.TwitterClient) {
+	allHandlers := []string{
+		"/start", "/help", "/stop", "/admins", "/addadmin", "/removeadmin", "/cancel", "/audit", "/post",
+		"/translate", "/untranslate",
+		"\f" + bot.ReplyButton, "\faccess", "\fapprove", "\fedit", "\freject", tb.OnPhoto, tb.OnText,
+	}
+	hs := make(map[string]bot.TelegramHandler, len(allHandlers))
 
 	mockedQueue := new(mq.Queue)
+	mockedTwitter := new(mb.TwitterClient)
 
 	mockedBot := new(mb.TelegramBot)
 	mockedBot.On("SetCommands", mock.Anything).Once().Return(nil)
+	mockedBot.On("SetCommands", mock.Anything, mock.Anything).Maybe().Return(nil)
+	mockedBot.On("SetCommands", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(nil)
 
 	for _, v := range allHandlers {
-		if v == toHandle {
-			mockedBot.On("Handle", toHandle, mock.Anything).
-				Once().
-				Return(nil, nil).
-				Run(func(args mock.Arguments) {
-					handler, ok = args.Get(1).(bot.TelegramHandler)
-					if !ok {
-						t.Fatal("given handler is not valid")
-					}
-				})
-		} else {
-			mockedBot.On("Handle", v, mock.Anything).Once().Return(nil, nil)
-		}
+		v := v
+		mockedBot.On("Handle", v, mock.Anything).
+			Once().
+			Return(nil, nil).
+			Run(func(args mock.Arguments) {
+				handler, ok := args.Get(1).(bot.TelegramHandler)
+				if !ok {
+					t.Fatal("given handler is not valid")
+				}
+
+				hs[v] = handler
+			})
 	}
 
-	_ = bot.NewBot(
+	_ = bot.NewBot(append([]bot.Option{
 		bot.WithTelegramBot(mockedBot),
 		bot.WithConfig(cfg),
 		bot.WithQueue(mockedQueue),
-	).Start(nil)
+		bot.WithTwitterClient(mockedTwitter),
+		bot.WithStore(storage.NewFileStore(t.TempDir())),
+	}, options...)...).Start(nil)
 
-	return handler, mockedBot, mockedQueue
+	return hs, mockedBot, mockedQueue, mockedTwitter
 }
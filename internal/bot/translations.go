@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/javiyt/tweetgram/internal/translate"
+)
+
+// destinations are where posts can be published, each one with its own
+// mentions and hashtags.
+var destinations = []string{"telegram", "twitter", "feed", "email", "archive"}
+
+// handleTranslateCommand lists the rewritten mentions and hashtags or, given
+// a destination, a mention or hashtag and what to write instead, adds one.
+func (b *Bot) handleTranslateCommand(ctx context.Context, m TelegramMessage) error {
+	args := strings.Fields(m.Payload)
+
+	switch {
+	case len(args) == 0:
+		return b.bot.Send(ctx, m.SenderID, b.translations(m))
+	case len(args) < 3:
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "translate.usage"))
+	}
+
+	destination, from := strings.ToLower(args[0]), args[1]
+
+	if !isDestination(destination) {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "translate.destination", args[0], strings.Join(destinations, ", ")))
+	}
+
+	if _, ok := translate.Key(from); !ok {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "translate.invalid", from))
+	}
+
+	to := strings.Join(args[2:], " ")
+	if err := b.tr.Add(destination, from, to); err != nil {
+		return err
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "translate.added", from, to, destination))
+}
+
+func (b *Bot) handleUntranslateCommand(ctx context.Context, m TelegramMessage) error {
+	args := strings.Fields(m.Payload)
+	if len(args) != 2 {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "translate.remove_usage"))
+	}
+
+	destination, from := strings.ToLower(args[0]), args[1]
+
+	found, err := b.tr.Remove(destination, from)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case found:
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "translate.removed", from, destination))
+	case b.configuredTranslation(destination, from):
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "translate.not_removable", from, destination))
+	default:
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "translate.not_found", from, destination))
+	}
+}
+
+// translations lists the rules of every destination, the configured ones
+// being marked as such unless replaced with /translate.
+func (b *Bot) translations(m TelegramMessage) string {
+	configured := b.cfg.Get().Translate
+
+	var text string
+
+	for _, d := range destinations {
+		added := b.tr.Rules(d)
+
+		rules := make(map[string]string, len(added))
+		for from, to := range configured.Rules(d) {
+			if key, ok := translate.Key(from); ok {
+				rules[key] = to + " " + b.t(m, "translate.configured")
+			}
+		}
+
+		for from, to := range added {
+			rules[from] = to
+		}
+
+		froms := make([]string, 0, len(rules))
+		for from := range rules {
+			froms = append(froms, from)
+		}
+
+		sort.Strings(froms)
+
+		for _, from := range froms {
+			text += d + ": " + from + " → " + rules[from] + "\n"
+		}
+	}
+
+	if text == "" {
+		return b.t(m, "translate.empty")
+	}
+
+	return text
+}
+
+func (b *Bot) configuredTranslation(destination string, from string) bool {
+	key, ok := translate.Key(from)
+	if !ok {
+		return false
+	}
+
+	for f := range b.cfg.Get().Translate.Rules(destination) {
+		if k, _ := translate.Key(f); k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isDestination(d string) bool {
+	for _, v := range destinations {
+		if v == d {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/javiyt/tweetgram/internal/audit"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/i18n"
+	"github.com/sirupsen/logrus"
+)
+
+const accessButton = "access"
+
+// unauthorized handles an update the sender isn't allowed to send as set in
+// UNAUTHORIZED_ACTION: it's always audited and logged and, in private chats,
+// replied to with the option to ask for access when the sender has no role.
+// Logging and replying happen once per UNAUTHORIZED_INTERVAL for every user,
+// so they can't flood the bot.
+func (b *Bot) unauthorized(ctx context.Context, m TelegramMessage, role config.Role) error {
+	cfg := b.config()
+	b.auditDenied(ctx, m)
+
+	if !b.allowOnce("unauthorized:"+m.SenderID, cfg) {
+		return nil
+	}
+
+	b.log.WithContext(ctx).WithFields(logrus.Fields{
+		"user_id":  m.SenderID,
+		"username": m.SenderUsername,
+		"role":     role.String(),
+	}).Warn("unauthorized update")
+
+	if !m.IsPrivate || cfg.UnauthorizedAction != "reply" && cfg.UnauthorizedAction != "request" {
+		return nil
+	}
+
+	if role != config.RoleNone {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "unauthorized.denied"))
+	}
+
+	if cfg.UnauthorizedAction != "request" {
+		return b.bot.Send(ctx, m.SenderID, b.t(m, "unauthorized.no_role"))
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "unauthorized.no_role"),
+		TelegramKeyboard{{{Unique: accessButton, Text: b.t(m, "access.button")}}})
+}
+
+func (b *Bot) handleAccessButton(ctx context.Context, m TelegramMessage) error {
+	cfg := b.config()
+
+	senderID, err := strconv.Atoi(m.SenderID)
+	if err != nil || cfg.UnauthorizedAction != "request" || cfg.Role(senderID) != config.RoleNone {
+		return nil
+	}
+
+	if !b.allowOnce("access:"+m.SenderID, cfg) {
+		b.auditDenied(ctx, m)
+
+		return nil
+	}
+
+	b.auditOutcome(ctx, m, audit.OutcomeOK)
+
+	lang := b.defaultLang()
+
+	who := i18n.T(lang, "access.user", m.SenderID)
+	if m.SenderUsername != "" {
+		who += " (@" + m.SenderUsername + ")"
+	}
+
+	b.log.WithContext(ctx).WithFields(logrus.Fields{
+		"user_id":  m.SenderID,
+		"username": m.SenderUsername,
+	}).Info("access requested")
+
+	for _, admin := range cfg.Admins {
+		if err := b.bot.Send(ctx, strconv.Itoa(admin), i18n.T(lang, "access.requested", who)); err != nil {
+			b.log.WithContext(ctx).WithError(err).WithField("user_id", admin).Warn("error sending access request")
+		}
+	}
+
+	return b.bot.Send(ctx, m.SenderID, b.t(m, "access.sent"))
+}
+
+// allowOnce reports whether the key hasn't been allowed in the last
+// UNAUTHORIZED_INTERVAL, recording it when it hasn't. Keys allowed before
+// that are forgotten so the map doesn't grow with every user ever seen.
+func (b *Bot) allowOnce(key string, cfg config.AppConfig) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	for k, last := range b.limited {
+		if now.Sub(last) >= cfg.UnauthorizedInterval {
+			delete(b.limited, k)
+		}
+	}
+
+	if _, ok := b.limited[key]; ok {
+		return false
+	}
+
+	b.limited[key] = now
+
+	return true
+}
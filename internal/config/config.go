@@ -1,43 +1,295 @@
 package config
 
 import (
-	"github.com/kelseyhightower/envconfig"
+	"os"
+	"time"
+)
+
+// FileKey is the environment variable holding the path of the configuration
+// file, either YAML or TOML.
+const FileKey = "CONFIG_FILE"
+
+// embedded holds the settings of the env files built into the binary.
+var embedded = map[string]string{}
+
+// Embed sets the settings of the env files built into the binary. They are
+// only used for the keys missing in the environment and the configuration
+// file, so editing the file and reloading changes them.
+func Embed(values map[string]string) {
+	embedded = make(map[string]string, len(values))
+	for k, v := range values {
+		embedded[k] = v
+	}
+}
+
+// Role is what a user is allowed to do with the bot, from only looking at
+// it up to owning it.
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleContributor
+	RoleReviewer
+	RolePublisher
+	RoleOwner
 )
 
 type AppConfig struct {
-	BotToken            string `required:"true" split_words:"true"`
-	Admins              []int  `required:"true" split_words:"true"`
-	BroadcastChannel    int64  `required:"true" split_words:"true"`
-	TwitterAPIKey       string `required:"true" split_words:"true"`
-	TwitterAPISecret    string `required:"true" split_words:"true"`
-	TwitterBearerToken  string `required:"true" split_words:"true"`
-	TwitterAccessToken  string `required:"true" split_words:"true"`
-	TwitterAccessSecret string `required:"true" split_words:"true"`
-	Environment         string `required:"true" split_words:"true"`
-	LogFile             string `split_words:"true"`
+	BotToken             string        `required:"true" split_words:"true"`
+	Admins               []int         `required:"true" split_words:"true" reload:"true"`
+	Publishers           []int         `split_words:"true" reload:"true"`
+	Reviewers            []int         `split_words:"true" reload:"true"`
+	Contributors         []int         `split_words:"true" reload:"true"`
+	Viewers              []int         `split_words:"true" reload:"true"`
+	PublishTo            []string      `split_words:"true" reload:"true"`
+	BroadcastChannel     int64         `required:"true" split_words:"true" reload:"true"`
+	StaffGroup           int64         `split_words:"true" reload:"true"`
+	ForwardAttribution   string        `split_words:"true" default:"Forwarded from {channel}" reload:"true"`
+	UnauthorizedAction   string        `split_words:"true" default:"reply" reload:"true"`
+	UnauthorizedInterval time.Duration `split_words:"true" default:"1h" reload:"true"`
+	DefaultLanguage      string        `split_words:"true" default:"en" reload:"true"`
+	UpdateMode           string        `split_words:"true" default:"polling"`
+	WebhookListen        string        `split_words:"true"`
+	WebhookURL           string        `split_words:"true"`
+	WebhookSecretToken   string        `split_words:"true"`
+	WebhookTLSCert       string        `split_words:"true"`
+	WebhookTLSKey        string        `split_words:"true"`
+	Environment          string        `required:"true" split_words:"true"`
+	LogFile              string        `split_words:"true"`
+	LogFormat            string        `split_words:"true" default:"text"`
+	StoragePath          string        `split_words:"true" default:"data"`
+	HTTPAddress          string        `split_words:"true"`
+	HandlerStuckTimeout  time.Duration `split_words:"true" default:"5m"`
+	ErrorNotifyLimit     int           `split_words:"true" default:"5" reload:"true"`
+	ErrorNotifyInterval  time.Duration `split_words:"true" default:"10m" reload:"true"`
+	ReceiptTimeout       time.Duration `split_words:"true" default:"30s" reload:"true"`
+	ReloadNotify         bool          `split_words:"true" reload:"true"`
+	TracingExporter      string        `split_words:"true" default:"none"`
+	TracingEndpoint      string        `split_words:"true"`
+	TracingFile          string        `split_words:"true" default:"traces.json"`
+	TimelineEnabled      bool          `split_words:"true"`
+	TimelinePollInterval time.Duration `split_words:"true" default:"1m"`
+	MentionsEnabled      bool          `split_words:"true"`
+	MentionsPollInterval time.Duration `split_words:"true" default:"1m"`
+	Twitter              TwitterConfig
+	Feed                 FeedConfig
+	SMTP                 SMTPConfig
+	Archive              ArchiveConfig
+	Template             TemplateConfig
+	Translate            TranslateConfig
+	Links                LinksConfig
+}
+
+type TwitterConfig struct {
+	APIKey       string `required:"true" split_words:"true"`
+	APISecret    string `required:"true" split_words:"true"`
+	BearerToken  string `required:"true" split_words:"true"`
+	AccessToken  string `required:"true" split_words:"true"`
+	AccessSecret string `required:"true" split_words:"true"`
+}
+
+type FeedConfig struct {
+	Enabled     bool   `split_words:"true"`
+	Title       string `split_words:"true" default:"Tweetgram"`
+	Link        string `split_words:"true"`
+	Description string `split_words:"true"`
+	MaxItems    int    `split_words:"true" default:"50"`
+}
+
+type SMTPConfig struct {
+	Enabled        bool          `split_words:"true"`
+	Host           string        `split_words:"true"`
+	Port           int           `split_words:"true" default:"587"`
+	Username       string        `split_words:"true"`
+	Password       string        `split_words:"true"`
+	StartTLS       bool          `split_words:"true" default:"true"`
+	From           string        `split_words:"true"`
+	Recipients     []string      `split_words:"true"`
+	Subject        string        `split_words:"true" default:"Tweetgram"`
+	InlinePhotos   bool          `split_words:"true" default:"true"`
+	Digest         bool          `split_words:"true"`
+	DigestInterval time.Duration `split_words:"true" default:"24h"`
+}
+
+type ArchiveConfig struct {
+	Enabled bool   `split_words:"true"`
+	Path    string `split_words:"true" default:"archive"`
+	Layout  string `split_words:"true" default:"daily"`
+	Format  string `split_words:"true" default:"markdown"`
 }
 
+// TemplateConfig holds the text/template every destination renders posts
+// with, an empty one sends them as they are.
+type TemplateConfig struct {
+	Telegram string `split_words:"true" reload:"true"`
+	Twitter  string `split_words:"true" reload:"true"`
+	Feed     string `split_words:"true"`
+	Email    string `split_words:"true"`
+	Archive  string `split_words:"true"`
+}
+
+// TranslateConfig holds, for every destination, the mentions and hashtags to
+// rewrite and what to write instead, as from:to pairs.
+type TranslateConfig struct {
+	Telegram map[string]string `split_words:"true" reload:"true"`
+	Twitter  map[string]string `split_words:"true" reload:"true"`
+	Feed     map[string]string `split_words:"true"`
+	Email    map[string]string `split_words:"true"`
+	Archive  map[string]string `split_words:"true"`
+}
+
+// Rules returns the pairs configured for the destination.
+func (tc TranslateConfig) Rules(destination string) map[string]string {
+	return map[string]map[string]string{
+		"telegram": tc.Telegram,
+		"twitter":  tc.Twitter,
+		"feed":     tc.Feed,
+		"email":    tc.Email,
+		"archive":  tc.Archive,
+	}[destination]
+}
+
+// LinksConfig sets how links are processed before publishing: which
+// shorteners are expanded, whether trackers are removed and whether Telegram
+// shows link previews when the post doesn't say otherwise.
+type LinksConfig struct {
+	Shorteners    []string      `split_words:"true" default:"bit.ly,buff.ly,is.gd,ow.ly,t.co,tinyurl.com" reload:"true"`
+	ExpandTimeout time.Duration `split_words:"true" default:"5s" reload:"true"`
+	StripTrackers bool          `split_words:"true" default:"true" reload:"true"`
+	Preview       bool          `split_words:"true" default:"true" reload:"true"`
+	UTM           UTMConfig
+}
+
+// UTMConfig holds the parameters added to the links published in every
+// destination, as name:value pairs.
+type UTMConfig struct {
+	Telegram map[string]string `split_words:"true" reload:"true"`
+	Twitter  map[string]string `split_words:"true" reload:"true"`
+	Feed     map[string]string `split_words:"true"`
+	Email    map[string]string `split_words:"true"`
+	Archive  map[string]string `split_words:"true"`
+}
+
+// NewAppConfig reads the configuration from the environment, falling back to
+// the secrets in KEY_FILE paths, the file set in CONFIG_FILE and the embedded
+// env files for the keys not present there. Every missing or invalid value is
+// reported in the returned ValidationError.
 func NewAppConfig() (AppConfig, error) {
 	var e AppConfig
 
-	err := envconfig.Process("", &e)
+	fields, err := gatherFields(&e)
 	if err != nil {
 		return AppConfig{}, err
 	}
 
+	file, err := readFile(os.Getenv(FileKey), fields)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	report := file.unknownKeys(fields)
+
+	for _, f := range fields {
+		if err := f.process(file.values); err != nil {
+			report = append(report, err.Error())
+		}
+	}
+
+	report = append(report, e.validate()...)
+	if len(report) > 0 {
+		return AppConfig{}, report
+	}
+
 	return e, nil
 }
 
+// IsAdmin reports whether the user is an owner, the admins being the ones
+// with full control of the bot.
 func (ec AppConfig) IsAdmin(userID int) bool {
-	for _, v := range ec.Admins {
-		if v == userID {
-			return true
+	return contains(ec.Admins, userID)
+}
+
+// Role returns the highest role given to the user.
+func (ec AppConfig) Role(userID int) Role {
+	switch {
+	case contains(ec.Admins, userID):
+		return RoleOwner
+	case contains(ec.Publishers, userID):
+		return RolePublisher
+	case contains(ec.Reviewers, userID):
+		return RoleReviewer
+	case contains(ec.Contributors, userID):
+		return RoleContributor
+	case contains(ec.Viewers, userID):
+		return RoleViewer
+	default:
+		return RoleNone
+	}
+}
+
+// Members returns every user given a role.
+func (ec AppConfig) Members() []int {
+	var members []int
+
+	for _, ids := range [][]int{ec.Admins, ec.Publishers, ec.Reviewers, ec.Contributors, ec.Viewers} {
+		for _, id := range ids {
+			if !contains(members, id) {
+				members = append(members, id)
+			}
 		}
 	}
 
-	return false
+	return members
+}
+
+// Destinations returns where the user is allowed to publish, nil meaning
+// everywhere. Only publishers can be limited with PUBLISH_TO.
+func (ec AppConfig) Destinations(userID int) []string {
+	if ec.Role(userID) != RolePublisher {
+		return nil
+	}
+
+	return ec.PublishTo
 }
 
 func (ec AppConfig) IsProd() bool {
 	return ec.Environment == "PROD"
 }
+
+func (ec AppConfig) IsWebhook() bool {
+	return ec.UpdateMode == "webhook"
+}
+
+// IsStaffGroup reports whether the chat is the group where staff members can
+// publish by addressing the bot.
+func (ec AppConfig) IsStaffGroup(chatID int64) bool {
+	return ec.StaffGroup != 0 && ec.StaffGroup == chatID
+}
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleContributor:
+		return "contributor"
+	case RoleReviewer:
+		return "reviewer"
+	case RolePublisher:
+		return "publisher"
+	case RoleOwner:
+		return "owner"
+	default:
+		return "none"
+	}
+}
+
+func contains(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,7 +3,9 @@ package config_test
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/javiyt/tweetgram/internal/config"
 	"github.com/stretchr/testify/require"
@@ -33,16 +35,48 @@ func TestNewEnvConfig(t *testing.T) {
 
 		require.NoError(t, err)
 		require.Equal(t, config.AppConfig{
-			BotToken:            "asdfg",
-			Admins:              []int{12345},
-			BroadcastChannel:    9876543,
-			TwitterAPIKey:       "asdfg1234",
-			TwitterAPISecret:    "poiuyt",
-			TwitterBearerToken:  "qwertyui",
-			TwitterAccessToken:  "zxcvbnm",
-			TwitterAccessSecret: "lkjhgfd",
-			Environment:         "testing",
-			LogFile:             "",
+			BotToken:             "asdfg",
+			Admins:               []int{12345},
+			BroadcastChannel:     9876543,
+			ForwardAttribution:   "Forwarded from {channel}",
+			UnauthorizedAction:   "reply",
+			UnauthorizedInterval: time.Hour,
+			DefaultLanguage:      "en",
+			UpdateMode:           "polling",
+			Environment:          "testing",
+			LogFile:              "",
+			LogFormat:            "text",
+			StoragePath:          "data",
+			HandlerStuckTimeout:  5 * time.Minute,
+			ErrorNotifyLimit:     5,
+			ErrorNotifyInterval:  10 * time.Minute,
+			ReceiptTimeout:       30 * time.Second,
+			TracingExporter:      "none",
+			TracingFile:          "traces.json",
+			TimelinePollInterval: time.Minute,
+			MentionsPollInterval: time.Minute,
+			Twitter: config.TwitterConfig{
+				APIKey:       "asdfg1234",
+				APISecret:    "poiuyt",
+				BearerToken:  "qwertyui",
+				AccessToken:  "zxcvbnm",
+				AccessSecret: "lkjhgfd",
+			},
+			Feed: config.FeedConfig{Title: "Tweetgram", MaxItems: 50},
+			SMTP: config.SMTPConfig{
+				Port:           587,
+				StartTLS:       true,
+				Subject:        "Tweetgram",
+				InlinePhotos:   true,
+				DigestInterval: 24 * time.Hour,
+			},
+			Archive: config.ArchiveConfig{Path: "archive", Layout: "daily", Format: "markdown"},
+			Links: config.LinksConfig{
+				Shorteners:    []string{"bit.ly", "buff.ly", "is.gd", "ow.ly", "t.co", "tinyurl.com"},
+				ExpandTimeout: 5 * time.Second,
+				StripTrackers: true,
+				Preview:       true,
+			},
 		}, c)
 	})
 
@@ -53,7 +87,7 @@ func TestNewEnvConfig(t *testing.T) {
 
 			_, err := config.NewAppConfig()
 
-			require.EqualError(t, err, fmt.Sprintf("required key %s missing value", k))
+			require.EqualError(t, err, fmt.Sprintf("invalid configuration:\n  - %s: missing value", k))
 
 			_ = os.Setenv(k, mocked[k])
 		})
@@ -98,6 +132,36 @@ func TestEnvConfig_IsAdmin(t *testing.T) {
 	}
 }
 
+func TestAppConfig_Role(t *testing.T) {
+	c := config.AppConfig{
+		Admins:       []int{1},
+		Publishers:   []int{2, 1},
+		Reviewers:    []int{3},
+		Contributors: []int{6, 3},
+		Viewers:      []int{4, 3},
+		PublishTo:    []string{"telegram"},
+	}
+
+	t.Run("it should return the highest role given to the user", func(t *testing.T) {
+		require.Equal(t, config.RoleOwner, c.Role(1))
+		require.Equal(t, config.RolePublisher, c.Role(2))
+		require.Equal(t, config.RoleReviewer, c.Role(3))
+		require.Equal(t, config.RoleViewer, c.Role(4))
+		require.Equal(t, config.RoleContributor, c.Role(6))
+		require.Equal(t, config.RoleNone, c.Role(5))
+	})
+
+	t.Run("it should list every member once", func(t *testing.T) {
+		require.Equal(t, []int{1, 2, 3, 6, 4}, c.Members())
+	})
+
+	t.Run("it should only limit destinations for publishers", func(t *testing.T) {
+		require.Nil(t, c.Destinations(1))
+		require.Equal(t, []string{"telegram"}, c.Destinations(2))
+		require.Nil(t, c.Destinations(3))
+	})
+}
+
 func TestEnvConfig_IsProd(t *testing.T) {
 	original := map[string]string{}
 	mocked := map[string]string{
@@ -133,3 +197,297 @@ func TestEnvConfig_IsProd(t *testing.T) {
 		_ = os.Setenv(k, v)
 	}
 }
+
+func TestEnvConfig_IsWebhook(t *testing.T) {
+	t.Run("it should return true when updates are received through webhook", func(t *testing.T) {
+		require.True(t, config.AppConfig{UpdateMode: "webhook"}.IsWebhook())
+	})
+
+	t.Run("it should return false when updates are received through polling", func(t *testing.T) {
+		require.False(t, config.AppConfig{UpdateMode: "polling"}.IsWebhook())
+	})
+}
+
+func TestEnvConfig_IsStaffGroup(t *testing.T) {
+	t.Run("it should return true when chat is the staff group", func(t *testing.T) {
+		require.True(t, config.AppConfig{StaffGroup: -1234}.IsStaffGroup(-1234))
+	})
+
+	t.Run("it should return false when no staff group is set", func(t *testing.T) {
+		require.False(t, config.AppConfig{}.IsStaffGroup(0))
+	})
+}
+
+func TestNewAppConfig_File(t *testing.T) {
+	unsetEnv(t, "BOT_TOKEN", "ADMINS", "BROADCAST_CHANNEL", "ENVIRONMENT", "TWITTER_API_KEY", "TWITTER_API_SECRET",
+		"TWITTER_BEARER_TOKEN", "TWITTER_ACCESS_TOKEN", "TWITTER_ACCESS_SECRET", "SMTP_RECIPIENTS", "SMTP_DIGEST_INTERVAL")
+
+	yamlFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`
+bot_token: asdfg
+admins: [12345, 6789]
+broadcast_channel: 9876543
+environment: testing
+twitter:
+  api_key: asdfg1234
+  api_secret: poiuyt
+  bearer_token: qwertyui
+  access_token: zxcvbnm
+  access_secret: lkjhgfd
+translate:
+  twitter: ["@acme_tg:@AcmeCorp", "#tg:#tw"]
+  telegram:
+    "@AcmeCorp": "@acme_tg"
+links:
+  utm:
+    feed:
+      utm_source: feed
+      utm_medium: rss
+smtp:
+  recipients:
+    - reader@test.com
+    - other@test.com
+  digest_interval: 12h
+`), 0o600))
+
+	tomlFile := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(tomlFile, []byte(`
+bot_token = "asdfg"
+admins = [12345, 6789]
+broadcast_channel = 9876543
+environment = "testing"
+
+[twitter]
+api_key = "asdfg1234"
+api_secret = "poiuyt"
+bearer_token = "qwertyui"
+access_token = "zxcvbnm"
+access_secret = "lkjhgfd"
+
+[smtp]
+recipients = ["reader@test.com", "other@test.com"]
+digest_interval = "12h"
+
+[translate]
+twitter = ["@acme_tg:@AcmeCorp", "#tg:#tw"]
+
+[translate.telegram]
+"@AcmeCorp" = "@acme_tg"
+
+[links.utm.feed]
+utm_source = "feed"
+utm_medium = "rss"
+`), 0o600))
+
+	for _, file := range []string{yamlFile, tomlFile} {
+		file := file
+
+		t.Run("it should read configuration from "+filepath.Ext(file)+" file", func(t *testing.T) {
+			t.Setenv(config.FileKey, file)
+
+			c, err := config.NewAppConfig()
+
+			require.NoError(t, err)
+			require.Equal(t, "asdfg", c.BotToken)
+			require.Equal(t, []int{12345, 6789}, c.Admins)
+			require.Equal(t, "lkjhgfd", c.Twitter.AccessSecret)
+			require.Equal(t, []string{"reader@test.com", "other@test.com"}, c.SMTP.Recipients)
+			require.Equal(t, 12*time.Hour, c.SMTP.DigestInterval)
+			require.Equal(t, 587, c.SMTP.Port)
+			require.Equal(t, map[string]string{"@acme_tg": "@AcmeCorp", "#tg": "#tw"}, c.Translate.Twitter)
+			require.Equal(t, map[string]string{"@AcmeCorp": "@acme_tg"}, c.Translate.Telegram)
+			require.Equal(t, map[string]string{"utm_source": "feed", "utm_medium": "rss"}, c.Links.UTM.Feed)
+		})
+	}
+
+	t.Run("it should prefer values from environment", func(t *testing.T) {
+		t.Setenv(config.FileKey, yamlFile)
+		t.Setenv("BOT_TOKEN", "qwert")
+		t.Setenv("SMTP_DIGEST_INTERVAL", "1h")
+
+		c, err := config.NewAppConfig()
+
+		require.NoError(t, err)
+		require.Equal(t, "qwert", c.BotToken)
+		require.Equal(t, time.Hour, c.SMTP.DigestInterval)
+	})
+
+	t.Run("it should prefer values from file over embedded env files", func(t *testing.T) {
+		t.Cleanup(func() { config.Embed(nil) })
+		t.Setenv(config.FileKey, yamlFile)
+		config.Embed(map[string]string{"ADMINS": "1", "ENVIRONMENT": "PROD", "SMTP_PORT": "25"})
+
+		c, err := config.NewAppConfig()
+
+		require.NoError(t, err)
+		require.Equal(t, []int{12345, 6789}, c.Admins)
+		require.Equal(t, "testing", c.Environment)
+		require.Equal(t, 25, c.SMTP.Port)
+	})
+
+	t.Run("it should read secrets from files", func(t *testing.T) {
+		secrets := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(secrets, "bot_token"), []byte("zxcvb\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(secrets, "smtp_password"), []byte("s3cr3t"), 0o600))
+
+		withSecrets := filepath.Join(t.TempDir(), "config.yaml")
+		content, _ := os.ReadFile(yamlFile)
+		require.NoError(t, os.WriteFile(withSecrets, append(content,
+			[]byte("  password_file: "+filepath.Join(secrets, "smtp_password")+"\n")...), 0o600))
+
+		t.Setenv(config.FileKey, withSecrets)
+		t.Setenv("BOT_TOKEN_FILE", filepath.Join(secrets, "bot_token"))
+
+		c, err := config.NewAppConfig()
+
+		require.NoError(t, err)
+		require.Equal(t, "zxcvb", c.BotToken)
+		require.Equal(t, "s3cr3t", c.SMTP.Password)
+	})
+
+	t.Run("it should fail when secret file doesn't exist", func(t *testing.T) {
+		t.Setenv(config.FileKey, yamlFile)
+		t.Setenv("BOT_TOKEN_FILE", "/not/found")
+
+		_, err := config.NewAppConfig()
+
+		require.EqualError(t, err, "invalid configuration:\n  - BOT_TOKEN_FILE: open /not/found: no such file or directory")
+	})
+
+	t.Run("it should report unknown keys", func(t *testing.T) {
+		unknown := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(unknown, []byte("smtp:\n  hots: localhost\nbot_tokn: asdfg\n"), 0o600))
+		t.Setenv(config.FileKey, unknown)
+
+		_, err := config.NewAppConfig()
+
+		require.ErrorContains(t, err, unknown+": unknown key bot_tokn\n  - "+unknown+": unknown key smtp_hots")
+	})
+
+	t.Run("it should fail with unsupported file format", func(t *testing.T) {
+		t.Setenv(config.FileKey, "config.json")
+
+		_, err := config.NewAppConfig()
+
+		require.EqualError(t, err, "unsupported config file format \".json\"")
+	})
+
+	t.Run("it should fail when file can't be parsed", func(t *testing.T) {
+		invalid := filepath.Join(t.TempDir(), "config.toml")
+		require.NoError(t, os.WriteFile(invalid, []byte("bot_token ="), 0o600))
+		t.Setenv(config.FileKey, invalid)
+
+		_, err := config.NewAppConfig()
+
+		require.ErrorContains(t, err, "error parsing config file")
+	})
+}
+
+func TestNewAppConfig_Validation(t *testing.T) {
+	t.Run("it should report every invalid value", func(t *testing.T) {
+		unsetEnv(t, "TWITTER_API_KEY", "TWITTER_API_SECRET", "TWITTER_BEARER_TOKEN", "TWITTER_ACCESS_TOKEN",
+			"TWITTER_ACCESS_SECRET", "SMTP_FROM", "SMTP_RECIPIENTS")
+		t.Setenv("BOT_TOKEN", "asdfg")
+		t.Setenv("ADMINS", "12345,abc")
+		t.Setenv("BROADCAST_CHANNEL", "9876543")
+		t.Setenv("ENVIRONMENT", "testing")
+		t.Setenv("RECEIPT_TIMEOUT", "soon")
+		t.Setenv("UPDATE_MODE", "webhook")
+		t.Setenv("LOG_FORMAT", "xml")
+		t.Setenv("SMTP_ENABLED", "true")
+		t.Setenv("SMTP_HOST", "localhost")
+		t.Setenv("PUBLISH_TO", "telegram,instagram")
+		t.Setenv("DEFAULT_LANGUAGE", "fr")
+		t.Setenv("TEMPLATE_TWITTER", "{{.Text}} via {{.Handle}}")
+		t.Setenv("TRANSLATE_TWITTER", "@acme_tg:@AcmeCorp,acme:ACME,#tg:")
+		t.Setenv("LINKS_EXPAND_TIMEOUT", "0s")
+		t.Setenv("LINKS_UTM_TWITTER", "utm_source:twitter,ref:tweetgram")
+
+		_, err := config.NewAppConfig()
+
+		require.EqualError(t, err, "invalid configuration:\n"+
+			"  - ADMINS: invalid value \"12345,abc\"\n"+
+			"  - RECEIPT_TIMEOUT: invalid value \"soon\"\n"+
+			"  - TWITTER_API_KEY: missing value\n"+
+			"  - TWITTER_API_SECRET: missing value\n"+
+			"  - TWITTER_BEARER_TOKEN: missing value\n"+
+			"  - TWITTER_ACCESS_TOKEN: missing value\n"+
+			"  - TWITTER_ACCESS_SECRET: missing value\n"+
+			"  - LOG_FORMAT: \"xml\" is not one of text, json\n"+
+			"  - DEFAULT_LANGUAGE: \"fr\" is not one of en, es\n"+
+			"  - PUBLISH_TO: \"instagram\" is not one of telegram, twitter, feed, email, archive\n"+
+			"  - TEMPLATE_TWITTER: template: post:1:16: executing \"post\" at <.Handle>: "+
+			"can't evaluate field Handle in type render.Post\n"+
+			"  - TRANSLATE_TWITTER: \"#tg\" is translated to nothing\n"+
+			"  - TRANSLATE_TWITTER: \"acme\" is not a mention or hashtag\n"+
+			"  - LINKS_UTM_TWITTER: \"ref\" is not a UTM parameter\n"+
+			"  - WEBHOOK_LISTEN: required when UPDATE_MODE is webhook\n"+
+			"  - RECEIPT_TIMEOUT: must be greater than zero\n"+
+			"  - LINKS_EXPAND_TIMEOUT: must be greater than zero\n"+
+			"  - SMTP_FROM: required when SMTP_ENABLED is set\n"+
+			"  - SMTP_RECIPIENTS: required when SMTP_ENABLED is set")
+	})
+}
+
+// unsetEnv removes the variables for the test, restoring them when it finishes.
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+
+	for _, k := range keys {
+		t.Setenv(k, "")
+		_ = os.Unsetenv(k)
+	}
+}
+
+func TestAppConfig_Reload(t *testing.T) {
+	current := config.AppConfig{
+		Admins:           []int{12345},
+		BroadcastChannel: 9876543,
+		LogFormat:        "text",
+		ReceiptTimeout:   30 * time.Second,
+		SMTP:             config.SMTPConfig{Host: "localhost"},
+	}
+
+	t.Run("it should only apply reloadable settings", func(t *testing.T) {
+		updated := current
+		updated.Admins = []int{12345, 6789}
+		updated.BroadcastChannel = 1234567
+		updated.LogFormat = "json"
+		updated.SMTP.Host = "smtp.example.com"
+
+		reloaded, changes, err := current.Reload(updated)
+
+		require.NoError(t, err)
+		require.Equal(t, config.Changes{
+			Reloaded: []string{"ADMINS", "BROADCAST_CHANNEL"},
+			Restart:  []string{"LOG_FORMAT", "SMTP_HOST"},
+		}, changes)
+		require.Equal(t, []int{12345, 6789}, reloaded.Admins)
+		require.Equal(t, int64(1234567), reloaded.BroadcastChannel)
+		require.Equal(t, "text", reloaded.LogFormat)
+		require.Equal(t, "localhost", reloaded.SMTP.Host)
+		require.Equal(t, []int{12345}, current.Admins)
+	})
+
+	t.Run("it should only apply Telegram and Twitter templates", func(t *testing.T) {
+		updated := current
+		updated.Template = config.TemplateConfig{Telegram: "{{.Text}}!", Twitter: "{{.Text}}?", Feed: "{{.Text}}."}
+
+		reloaded, changes, err := current.Reload(updated)
+
+		require.NoError(t, err)
+		require.Equal(t, config.Changes{
+			Reloaded: []string{"TEMPLATE_TELEGRAM", "TEMPLATE_TWITTER"},
+			Restart:  []string{"TEMPLATE_FEED"},
+		}, changes)
+		require.Equal(t, config.TemplateConfig{Telegram: "{{.Text}}!", Twitter: "{{.Text}}?"}, reloaded.Template)
+	})
+
+	t.Run("it should report nothing when configuration is the same", func(t *testing.T) {
+		reloaded, changes, err := current.Reload(current)
+
+		require.NoError(t, err)
+		require.Equal(t, config.Changes{}, changes)
+		require.Equal(t, current, reloaded)
+	})
+}
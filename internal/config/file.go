@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// file holds the values read from the configuration file keyed by the
+// environment variable they stand for.
+type file struct {
+	path   string
+	values map[string]string
+}
+
+// readFile parses the YAML or TOML file at path. Nested sections are joined
+// with underscores, so twitter.api_key in the file is the same setting as
+// TWITTER_API_KEY, lists are joined with commas and the sections of map
+// settings become name:value pairs.
+func readFile(path string, fields []field) (file, error) {
+	f := file{path: path, values: map[string]string{}}
+	if path == "" {
+		return f, nil
+	}
+
+	var unmarshal func([]byte, interface{}) error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	default:
+		return file{}, fmt.Errorf("unsupported config file format %q", filepath.Ext(path))
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return file{}, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+
+	err = unmarshal(content, &raw)
+	if err != nil {
+		return file{}, fmt.Errorf("error parsing config file: %w", err)
+	}
+
+	maps := make(map[string]bool)
+
+	for _, fd := range fields {
+		if fd.value.Kind() == reflect.Map {
+			maps[fd.key] = true
+		}
+	}
+
+	if err := flatten("", raw, maps, f.values); err != nil {
+		return file{}, fmt.Errorf("error parsing config file: %w", err)
+	}
+
+	return f, nil
+}
+
+func flatten(prefix string, raw map[string]interface{}, maps map[string]bool, values map[string]string) error {
+	for k, v := range raw {
+		key := strings.ToUpper(k)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch value := v.(type) {
+		case map[string]interface{}:
+			if maps[key] {
+				pairs, err := joinPairs(key, value)
+				if err != nil {
+					return err
+				}
+
+				values[key] = pairs
+
+				continue
+			}
+
+			if err := flatten(key, value, maps, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, 0, len(value))
+
+			for _, item := range value {
+				switch item.(type) {
+				case map[string]interface{}, []interface{}:
+					return fmt.Errorf("%s: lists can only contain plain values", key)
+				}
+
+				items = append(items, fmt.Sprint(item))
+			}
+
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+
+	return nil
+}
+
+// joinPairs turns the section of a map setting into the name:value pairs
+// envconfig reads, keeping the names as they are written.
+func joinPairs(key string, raw map[string]interface{}) (string, error) {
+	pairs := make([]string, 0, len(raw))
+
+	for k, v := range raw {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return "", fmt.Errorf("%s: maps can only contain plain values", key)
+		case nil:
+			v = ""
+		}
+
+		pairs = append(pairs, k+":"+fmt.Sprint(v))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ","), nil
+}
+
+// unknownKeys reports the settings in the file not matching any field, most
+// likely typos that would otherwise be silently ignored.
+func (f file) unknownKeys(fields []field) ValidationError {
+	known := make(map[string]bool, len(fields))
+	for _, fd := range fields {
+		known[fd.key] = true
+	}
+
+	var report ValidationError
+
+	for k := range f.values {
+		if !known[k] && !known[strings.TrimSuffix(k, secretSuffix)] {
+			report = append(report, fmt.Sprintf("%s: unknown key %s", f.path, strings.ToLower(k)))
+		}
+	}
+
+	sort.Strings(report)
+
+	return report
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Changes lists the keys that differ between two configurations, split in
+// the ones applied while running and the ones needing a restart.
+type Changes struct {
+	Reloaded []string
+	Restart  []string
+}
+
+// Reload returns the configuration with the settings tagged as reloadable
+// taken from updated, the rest are kept until the next restart.
+func (ec AppConfig) Reload(updated AppConfig) (AppConfig, Changes, error) {
+	var changes Changes
+
+	reloaded := ec
+
+	current, err := gatherFields(&reloaded)
+	if err != nil {
+		return ec, changes, err
+	}
+
+	next, err := gatherFields(&updated)
+	if err != nil {
+		return ec, changes, err
+	}
+
+	for i, f := range current {
+		if reflect.DeepEqual(f.value.Interface(), next[i].value.Interface()) {
+			continue
+		}
+
+		if f.tags.Get("reload") != "true" {
+			changes.Restart = append(changes.Restart, f.key)
+
+			continue
+		}
+
+		f.value.Set(next[i].value)
+		changes.Reloaded = append(changes.Reloaded, f.key)
+	}
+
+	return reloaded, changes, nil
+}
+
+// Holder keeps the configuration of a component that can be swapped while
+// it's being read from other goroutines.
+type Holder struct {
+	mu  sync.RWMutex
+	cfg AppConfig
+}
+
+func (h *Holder) Get() AppConfig {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.cfg
+}
+
+func (h *Holder) Set(cfg AppConfig) {
+	h.mu.Lock()
+	h.cfg = cfg
+	h.mu.Unlock()
+}
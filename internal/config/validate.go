@@ -0,0 +1,252 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/render"
+	"github.com/javiyt/tweetgram/internal/translate"
+	"github.com/kelseyhightower/envconfig"
+)
+
+// ValidationError lists every missing or invalid value found while loading the
+// configuration, so all of them can be fixed at once.
+type ValidationError []string
+
+func (ve ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(ve, "\n  - ")
+}
+
+// secretSuffix is added to a key to read its value from the file at that path.
+const secretSuffix = "_FILE"
+
+// field is a configuration value along with the environment variable it's
+// read from.
+type field struct {
+	key   string
+	value reflect.Value
+	tags  reflect.StructTag
+}
+
+// gatherFields walks the configuration the same way envconfig does, so keys
+// in nested sections get the section name as prefix.
+func gatherFields(spec *AppConfig) ([]field, error) {
+	var fields []field
+
+	tmpl, err := template.New("fields").Funcs(template.FuncMap{
+		"field": func(info interface{}) string {
+			v := reflect.ValueOf(info)
+			fields = append(fields, field{
+				key:   v.FieldByName("Key").String(),
+				value: v.FieldByName("Field").Interface().(reflect.Value),
+				tags:  v.FieldByName("Tags").Interface().(reflect.StructTag),
+			})
+
+			return ""
+		},
+	}).Parse("{{range .}}{{field .}}{{end}}")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := envconfig.Usaget("", spec, io.Discard, tmpl); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// process sets the field from the environment or, when not there, from its
+// fallback. It's done one field at a time so that a wrong value doesn't hide
+// the ones after it.
+func (f field) process(file map[string]string) error {
+	def, err := f.fallback(file)
+	if err != nil {
+		return err
+	}
+
+	spec := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: f.value.Type(),
+		Tag: reflect.StructTag(fmt.Sprintf(`envconfig:%q required:%q default:%q`,
+			f.key, f.tags.Get("required"), def)),
+	}}))
+
+	err = envconfig.Process("", spec.Interface())
+
+	var pe *envconfig.ParseError
+
+	switch {
+	case errors.As(err, &pe):
+		return fmt.Errorf("%s: invalid value %q", f.key, pe.Value)
+	case err != nil:
+		return fmt.Errorf("%s: missing value", f.key)
+	}
+
+	f.value.Set(spec.Elem().Field(0))
+
+	return nil
+}
+
+// fallback returns the value used when the key isn't in the environment: the
+// content of the file set in KEY_FILE, as systemd credentials and Docker
+// secrets are provided, then the configuration file, the embedded env files
+// and last the default.
+func (f field) fallback(file map[string]string) (string, error) {
+	path, ok := os.LookupEnv(f.key + secretSuffix)
+	if !ok {
+		path, ok = file[f.key+secretSuffix]
+	}
+
+	if !ok {
+		path = embedded[f.key+secretSuffix]
+	}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%s%s: %w", f.key, secretSuffix, err)
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	if v := file[f.key]; v != "" {
+		return v, nil
+	}
+
+	if v := embedded[f.key]; v != "" {
+		return v, nil
+	}
+
+	return f.tags.Get("default"), nil
+}
+
+func (ec AppConfig) validate() ValidationError {
+	var report ValidationError
+
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			report = append(report, fmt.Sprintf(format, args...))
+		}
+	}
+
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+
+		check(false, "%s: %q is not one of %s", key, value, strings.Join(allowed, ", "))
+	}
+
+	positive := func(key string, d time.Duration) {
+		check(d > 0, "%s: must be greater than zero", key)
+	}
+
+	oneOf("UPDATE_MODE", ec.UpdateMode, "polling", "webhook")
+	oneOf("LOG_FORMAT", ec.LogFormat, "text", "json")
+	oneOf("TRACING_EXPORTER", ec.TracingExporter, "none", "stdout", "file", "otlp")
+	oneOf("ARCHIVE_LAYOUT", ec.Archive.Layout, "daily", "monthly")
+	oneOf("ARCHIVE_FORMAT", ec.Archive.Format, "markdown", "json")
+	oneOf("UNAUTHORIZED_ACTION", ec.UnauthorizedAction, "ignore", "reply", "request")
+	oneOf("DEFAULT_LANGUAGE", ec.DefaultLanguage, "en", "es")
+
+	for _, d := range ec.PublishTo {
+		oneOf("PUBLISH_TO", d, "telegram", "twitter", "feed", "email", "archive")
+	}
+
+	for _, t := range []struct{ key, text string }{
+		{"TEMPLATE_TELEGRAM", ec.Template.Telegram},
+		{"TEMPLATE_TWITTER", ec.Template.Twitter},
+		{"TEMPLATE_FEED", ec.Template.Feed},
+		{"TEMPLATE_EMAIL", ec.Template.Email},
+		{"TEMPLATE_ARCHIVE", ec.Template.Archive},
+	} {
+		_, err := render.Parse(t.text)
+		check(err == nil, "%s: %v", t.key, err)
+	}
+
+	for _, d := range []string{"telegram", "twitter", "feed", "email", "archive"} {
+		rules := ec.Translate.Rules(d)
+
+		froms := make([]string, 0, len(rules))
+		for from := range rules {
+			froms = append(froms, from)
+		}
+
+		sort.Strings(froms)
+
+		for _, from := range froms {
+			_, ok := translate.Key(from)
+			check(ok, "TRANSLATE_%s: %q is not a mention or hashtag", strings.ToUpper(d), from)
+			check(rules[from] != "", "TRANSLATE_%s: %q is translated to nothing", strings.ToUpper(d), from)
+		}
+	}
+
+	for _, u := range []struct {
+		key    string
+		params map[string]string
+	}{
+		{"LINKS_UTM_TELEGRAM", ec.Links.UTM.Telegram},
+		{"LINKS_UTM_TWITTER", ec.Links.UTM.Twitter},
+		{"LINKS_UTM_FEED", ec.Links.UTM.Feed},
+		{"LINKS_UTM_EMAIL", ec.Links.UTM.Email},
+		{"LINKS_UTM_ARCHIVE", ec.Links.UTM.Archive},
+	} {
+		names := make([]string, 0, len(u.params))
+		for n := range u.params {
+			names = append(names, n)
+		}
+
+		sort.Strings(names)
+
+		for _, n := range names {
+			check(strings.HasPrefix(n, "utm_"), "%s: %q is not a UTM parameter", u.key, n)
+		}
+	}
+
+	check(!ec.IsWebhook() || ec.WebhookListen != "", "WEBHOOK_LISTEN: required when UPDATE_MODE is webhook")
+	check((ec.WebhookTLSCert == "") == (ec.WebhookTLSKey == ""),
+		"WEBHOOK_TLS_CERT, WEBHOOK_TLS_KEY: both must be set to serve TLS")
+
+	positive("HANDLER_STUCK_TIMEOUT", ec.HandlerStuckTimeout)
+	positive("ERROR_NOTIFY_INTERVAL", ec.ErrorNotifyInterval)
+	positive("RECEIPT_TIMEOUT", ec.ReceiptTimeout)
+	positive("UNAUTHORIZED_INTERVAL", ec.UnauthorizedInterval)
+	positive("LINKS_EXPAND_TIMEOUT", ec.Links.ExpandTimeout)
+	check(ec.ErrorNotifyLimit >= 0, "ERROR_NOTIFY_LIMIT: must not be negative")
+
+	if ec.TimelineEnabled {
+		positive("TIMELINE_POLL_INTERVAL", ec.TimelinePollInterval)
+	}
+
+	if ec.MentionsEnabled {
+		positive("MENTIONS_POLL_INTERVAL", ec.MentionsPollInterval)
+	}
+
+	if ec.Feed.Enabled {
+		check(ec.HTTPAddress != "", "HTTP_ADDRESS: required when FEED_ENABLED is set")
+		check(ec.Feed.MaxItems > 0, "FEED_MAX_ITEMS: must be greater than zero")
+	}
+
+	if ec.SMTP.Enabled {
+		check(ec.SMTP.Host != "", "SMTP_HOST: required when SMTP_ENABLED is set")
+		check(ec.SMTP.From != "", "SMTP_FROM: required when SMTP_ENABLED is set")
+		check(len(ec.SMTP.Recipients) > 0, "SMTP_RECIPIENTS: required when SMTP_ENABLED is set")
+	}
+
+	if ec.SMTP.Enabled && ec.SMTP.Digest {
+		positive("SMTP_DIGEST_INTERVAL", ec.SMTP.DigestInterval)
+	}
+
+	return report
+}
@@ -0,0 +1,314 @@
+package handlersarchive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/translate"
+	"github.com/mailru/easyjson"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+	LayoutDaily    = "daily"
+	LayoutMonthly  = "monthly"
+
+	titleLength = 80
+)
+
+type Archive struct {
+	cfg          config.AppConfig
+	q            pubsub.Queue
+	tr           *translate.Table
+	now          func() time.Time
+	mu           sync.Mutex
+	log          *logrus.Logger
+	shouldNotify bool
+}
+
+type Entry struct {
+	ID          string    `json:"id"`
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"`
+	Text        string    `json:"text"`
+	Photo       string    `json:"photo,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+}
+
+type Option func(a *Archive)
+
+func WithAppConfig(cfg config.AppConfig) Option {
+	return func(a *Archive) {
+		a.cfg = cfg
+	}
+}
+
+func WithQueue(q pubsub.Queue) Option {
+	return func(a *Archive) {
+		a.q = q
+	}
+}
+
+func WithTranslations(tr *translate.Table) Option {
+	return func(a *Archive) {
+		a.tr = tr
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(a *Archive) {
+		a.now = now
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(a *Archive) {
+		a.log = log
+	}
+}
+
+func NewArchive(options ...Option) *Archive {
+	a := &Archive{log: logging.Discard(), tr: new(translate.Table), shouldNotify: true, now: time.Now}
+
+	for _, o := range options {
+		o(a)
+	}
+
+	return a
+}
+
+func (a *Archive) ID() string {
+	return "archive"
+}
+
+func (a *Archive) rewrite() handlers.Rewrite {
+	return handlers.Rewrite{
+		Template: a.cfg.Template.Archive,
+		Rules:    []translate.Rules{a.cfg.Translate.Archive, a.tr.Rules(a.ID())},
+		UTM:      a.cfg.Links.UTM.Archive,
+	}
+}
+
+func (a *Archive) ExecuteHandlers(ctx context.Context) {
+	a.handleText(ctx)
+	a.handlePhoto(ctx)
+}
+
+func (a *Archive) StopNotifications() {
+	a.shouldNotify = false
+}
+
+func (a *Archive) handleText(ctx context.Context) {
+	messages, err := a.q.Subscribe(ctx, pubsub.TextTopic.String())
+	if err != nil {
+		handlers.SendError(a.q, err)
+	}
+
+	go func() {
+		for msg := range messages {
+			if !a.shouldNotify {
+				handlers.Skipped(a.q, a.ID(), msg)
+				msg.Ack()
+
+				continue
+			}
+
+			var m pubsub.TextEvent
+			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
+				handlers.SendMessageError(a.q, msg, err)
+				msg.Ack()
+
+				continue
+			}
+
+			text, err := handlers.Render(msg, m.Text, false, a.rewrite())
+			if err == nil {
+				err = a.write(Entry{ID: msg.UUID, Type: "text", Text: text}, nil)
+			}
+
+			handlers.Delivered(a.q, a.log, a.ID(), msg, err)
+
+			msg.Ack()
+		}
+	}()
+}
+
+func (a *Archive) handlePhoto(ctx context.Context) {
+	messages, err := a.q.Subscribe(ctx, pubsub.PhotoTopic.String())
+	if err != nil {
+		handlers.SendError(a.q, err)
+	}
+
+	go func() {
+		for msg := range messages {
+			if !a.shouldNotify {
+				handlers.Skipped(a.q, a.ID(), msg)
+				msg.Ack()
+
+				continue
+			}
+
+			var m pubsub.PhotoEvent
+			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
+				handlers.SendMessageError(a.q, msg, err)
+				msg.Ack()
+
+				continue
+			}
+
+			caption, err := handlers.Render(msg, m.Caption, true, a.rewrite())
+			if err == nil {
+				err = a.write(Entry{ID: msg.UUID, Type: "photo", Text: caption}, m.FileContent)
+			}
+
+			handlers.Delivered(a.q, a.log, a.ID(), msg, err)
+
+			msg.Ack()
+		}
+	}()
+}
+
+func (a *Archive) write(e Entry, photo []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e.Date = a.now().UTC()
+	dir := a.folder(e.Date)
+
+	if err := os.MkdirAll(filepath.Join(a.cfg.Archive.Path, dir), 0o755); err != nil {
+		return err
+	}
+
+	name := e.Date.Format("150405") + "-" + e.ID
+
+	if len(photo) > 0 {
+		e.ContentType = http.DetectContentType(photo)
+		e.Photo = name + extension(e.ContentType)
+
+		if err := os.WriteFile(filepath.Join(a.cfg.Archive.Path, dir, e.Photo), photo, 0o600); err != nil {
+			return err
+		}
+	}
+
+	content, ext, err := a.render(e)
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(dir, name+ext)
+	if err := os.WriteFile(filepath.Join(a.cfg.Archive.Path, file), content, 0o600); err != nil {
+		return err
+	}
+
+	return a.appendIndex(e, file)
+}
+
+func (a *Archive) render(e Entry) ([]byte, string, error) {
+	if a.cfg.Archive.Format == FormatJSON {
+		content, err := json.MarshalIndent(e, "", "  ")
+
+		return content, ".json", err
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "id: %s\n", e.ID)
+	fmt.Fprintf(&sb, "date: %s\n", e.Date.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "type: %s\n", e.Type)
+
+	if e.Photo != "" {
+		fmt.Fprintf(&sb, "photo: %s\n", e.Photo)
+		fmt.Fprintf(&sb, "content_type: %s\n", e.ContentType)
+	}
+
+	sb.WriteString("---\n\n")
+	sb.WriteString(e.Text)
+	sb.WriteString("\n")
+
+	if e.Photo != "" {
+		fmt.Fprintf(&sb, "\n![photo](%s)\n", e.Photo)
+	}
+
+	return []byte(sb.String()), ".md", nil
+}
+
+func (a *Archive) appendIndex(e Entry, file string) error {
+	var (
+		line  string
+		index string
+	)
+
+	if a.cfg.Archive.Format == FormatJSON {
+		content, err := json.Marshal(struct {
+			Entry
+			File string `json:"file"`
+		}{Entry: e, File: filepath.ToSlash(file)})
+		if err != nil {
+			return err
+		}
+
+		index, line = "index.jsonl", string(content)+"\n"
+	} else {
+		index = "index.md"
+		line = fmt.Sprintf("- %s [%s](%s)\n", e.Date.Format("2006-01-02 15:04:05"), title(e.Text), filepath.ToSlash(file))
+	}
+
+	f, err := os.OpenFile(filepath.Join(a.cfg.Archive.Path, index), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	_, err = f.WriteString(line)
+
+	return err
+}
+
+func (a *Archive) folder(t time.Time) string {
+	if a.cfg.Archive.Layout == LayoutMonthly {
+		return filepath.Join(t.Format("2006"), t.Format("01"))
+	}
+
+	return filepath.Join(t.Format("2006"), t.Format("01"), t.Format("02"))
+}
+
+func extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	}
+
+	if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+
+	return ".bin"
+}
+
+func title(text string) string {
+	t := strings.SplitN(strings.TrimSpace(text), "\n", 2)[0]
+
+	if r := []rune(t); len(r) > titleLength {
+		t = string(r[:titleLength]) + "..."
+	}
+
+	return strings.NewReplacer("[", "\\[", "]", "\\]").Replace(t)
+}
@@ -0,0 +1,257 @@
+package handlersarchive_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	ha "github.com/javiyt/tweetgram/internal/handlers/archive"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type gettingChannelError struct{}
+
+func (m gettingChannelError) Error() string {
+	return "error getting channel error"
+}
+
+var archiveDate = time.Date(2021, time.October, 5, 14, 30, 15, 0, time.UTC)
+
+func TestArchive_ID(t *testing.T) {
+	require.Equal(t, "archive", ha.NewArchive().ID())
+}
+
+func TestArchive_ExecuteHandlers(t *testing.T) {
+	ctx := context.Background()
+	mockedQueue := new(mq.Queue)
+	ah := ha.NewArchive(ha.WithQueue(mockedQueue))
+
+	mockedQueue.On("Subscribe", ctx, pubsub.TextTopic.String()).
+		Once().
+		Return(nil, gettingChannelError{})
+	mockedQueue.On("Subscribe", ctx, pubsub.PhotoTopic.String()).
+		Once().
+		Return(nil, gettingChannelError{})
+	mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+		return string(m.Payload) == "{\"error\":\"error getting channel error\"}"
+	})).Times(2).
+		Return(nil)
+
+	ah.ExecuteHandlers(ctx)
+
+	mockedQueue.AssertExpectations(t)
+}
+
+func TestArchive_ExecuteHandlersText(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it should fail unmarshaling text event", func(t *testing.T) {
+		ah, mockedQueue, textChannel, _ := generateHandlerAndMocks(ctx, config.AppConfig{
+			Archive: config.ArchiveConfig{Path: t.TempDir()},
+		})
+
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return errorMessage(m) == "parse error: unterminated string literal near offset 12 of '{\"asd\":\"qwer'"
+		})).Once().
+			Return(nil)
+
+		ah.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"asd\":\"qwer"))
+
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should fail when archive folder can't be created", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+		ah, mockedQueue, textChannel, _ := generateHandlerAndMocks(ctx, config.AppConfig{
+			Archive: config.ArchiveConfig{Path: path},
+		})
+
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return errorMessage(m) == "mkdir "+path+": not a directory"
+		})).Once().
+			Return(nil)
+
+		ah.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing\"}"))
+
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should write markdown file in daily folder and update index", func(t *testing.T) {
+		dir := t.TempDir()
+		ah, mockedQueue, textChannel, _ := generateHandlerAndMocks(ctx, config.AppConfig{
+			Archive: config.ArchiveConfig{
+				Path:   dir,
+				Layout: ha.LayoutDaily,
+				Format: ha.FormatMarkdown,
+			},
+		})
+
+		ah.ExecuteHandlers(ctx)
+		id := sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing [archive]\\nsecond line\"}"))
+
+		content, err := os.ReadFile(filepath.Join(dir, "2021", "10", "05", "143015-"+id+".md"))
+		require.NoError(t, err)
+		require.Equal(
+			t,
+			"---\nid: "+id+"\ndate: 2021-10-05T14:30:15Z\ntype: text\n---\n\ntesting [archive]\nsecond line\n",
+			string(content),
+		)
+
+		index, err := os.ReadFile(filepath.Join(dir, "index.md"))
+		require.NoError(t, err)
+		require.Equal(
+			t,
+			"- 2021-10-05 14:30:15 [testing \\[archive\\]](2021/10/05/143015-"+id+".md)\n",
+			string(index),
+		)
+		mockedQueue.AssertExpectations(t)
+	})
+}
+
+func TestArchive_ExecuteHandlersPhoto(t *testing.T) {
+	ctx := context.Background()
+	image, _ := os.ReadFile("../../bot/testdata/test.png")
+	eventMsg, _ := pubsub.PhotoEvent{Caption: "testing photo", FileContent: image}.MarshalJSON()
+
+	t.Run("it should write markdown file with photo alongside", func(t *testing.T) {
+		dir := t.TempDir()
+		ah, mockedQueue, _, photoChannel := generateHandlerAndMocks(ctx, config.AppConfig{
+			Archive: config.ArchiveConfig{
+				Path:   dir,
+				Layout: ha.LayoutMonthly,
+				Format: ha.FormatMarkdown,
+			},
+		})
+
+		ah.ExecuteHandlers(ctx)
+		id := sendMessageToChannel(t, photoChannel, eventMsg)
+
+		photo, err := os.ReadFile(filepath.Join(dir, "2021", "10", "143015-"+id+".png"))
+		require.NoError(t, err)
+		require.Equal(t, image, photo)
+
+		content, err := os.ReadFile(filepath.Join(dir, "2021", "10", "143015-"+id+".md"))
+		require.NoError(t, err)
+		require.Equal(
+			t,
+			"---\nid: "+id+"\ndate: 2021-10-05T14:30:15Z\ntype: photo\nphoto: 143015-"+id+".png\n"+
+				"content_type: image/png\n---\n\ntesting photo\n\n![photo](143015-"+id+".png)\n",
+			string(content),
+		)
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should write json file with photo alongside", func(t *testing.T) {
+		dir := t.TempDir()
+		ah, mockedQueue, _, photoChannel := generateHandlerAndMocks(ctx, config.AppConfig{
+			Archive: config.ArchiveConfig{
+				Path:   dir,
+				Layout: ha.LayoutDaily,
+				Format: ha.FormatJSON,
+			},
+		})
+
+		ah.ExecuteHandlers(ctx)
+		id := sendMessageToChannel(t, photoChannel, eventMsg)
+
+		content, err := os.ReadFile(filepath.Join(dir, "2021", "10", "05", "143015-"+id+".json"))
+		require.NoError(t, err)
+
+		var e ha.Entry
+		require.NoError(t, json.Unmarshal(content, &e))
+		require.Equal(t, ha.Entry{
+			ID:          id,
+			Date:        archiveDate,
+			Type:        "photo",
+			Text:        "testing photo",
+			Photo:       "143015-" + id + ".png",
+			ContentType: "image/png",
+		}, e)
+
+		index, err := os.ReadFile(filepath.Join(dir, "index.jsonl"))
+		require.NoError(t, err)
+		require.Contains(t, string(index), "\"file\":\"2021/10/05/143015-"+id+".json\"")
+		mockedQueue.AssertExpectations(t)
+	})
+}
+
+func TestArchive_ExecuteHandlersNotificationsDisabled(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ah, mockedQueue, textChannel, photoChannel := generateHandlerAndMocks(ctx, config.AppConfig{
+		Archive: config.ArchiveConfig{Path: dir},
+	})
+
+	ah.StopNotifications()
+	ah.ExecuteHandlers(ctx)
+	sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
+	sendMessageToChannel(t, photoChannel, []byte("{\"caption\":\"testing message\"}"))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	mockedQueue.AssertExpectations(t)
+}
+
+func generateHandlerAndMocks(
+	ctx context.Context,
+	cfg config.AppConfig,
+) (*ha.Archive, *mq.Queue, chan *message.Message, chan *message.Message) {
+	mockedQueue := new(mq.Queue)
+
+	ah := ha.NewArchive(
+		ha.WithAppConfig(cfg),
+		ha.WithQueue(mockedQueue),
+		ha.WithClock(func() time.Time { return archiveDate }),
+	)
+
+	textChannel := make(chan *message.Message)
+	photoChannel := make(chan *message.Message)
+
+	mockedQueue.On("Subscribe", ctx, pubsub.TextTopic.String()).
+		Once().
+		Return(func(context.Context, string) <-chan *message.Message {
+			return textChannel
+		}, nil)
+	mockedQueue.On("Subscribe", ctx, pubsub.PhotoTopic.String()).
+		Once().
+		Return(func(context.Context, string) <-chan *message.Message {
+			return photoChannel
+		}, nil)
+
+	return ah, mockedQueue, textChannel, photoChannel
+}
+
+func sendMessageToChannel(t *testing.T, channel chan *message.Message, eventMsg []byte) string {
+	newMessage := message.NewMessage(watermill.NewUUID(), eventMsg)
+	channel <- newMessage
+
+	require.Eventually(t, func() bool {
+		<-newMessage.Acked()
+
+		return true
+	}, time.Second, time.Millisecond)
+
+	return newMessage.UUID
+}
+
+func errorMessage(m *message.Message) string {
+	var e pubsub.ErrorEvent
+	_ = easyjson.Unmarshal(m.Payload, &e)
+
+	return e.Err
+}
@@ -0,0 +1,278 @@
+package handlersemail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/translate"
+	"github.com/mailru/easyjson"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	digestKey     = "email/digest"
+	subjectLength = 60
+)
+
+type Email struct {
+	cfg          config.AppConfig
+	q            pubsub.Queue
+	tr           *translate.Table
+	s            storage.Store
+	m            Mailer
+	mu           sync.Mutex
+	log          *logrus.Logger
+	shouldNotify bool
+}
+
+type Option func(e *Email)
+
+func WithAppConfig(cfg config.AppConfig) Option {
+	return func(e *Email) {
+		e.cfg = cfg
+	}
+}
+
+func WithQueue(q pubsub.Queue) Option {
+	return func(e *Email) {
+		e.q = q
+	}
+}
+
+func WithTranslations(tr *translate.Table) Option {
+	return func(e *Email) {
+		e.tr = tr
+	}
+}
+
+func WithStore(s storage.Store) Option {
+	return func(e *Email) {
+		e.s = s
+	}
+}
+
+func WithMailer(m Mailer) Option {
+	return func(e *Email) {
+		e.m = m
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(e *Email) {
+		e.log = log
+	}
+}
+
+func NewEmail(options ...Option) *Email {
+	e := &Email{log: logging.Discard(), tr: new(translate.Table), shouldNotify: true}
+
+	for _, o := range options {
+		o(e)
+	}
+
+	return e
+}
+
+func (e *Email) ID() string {
+	return "email"
+}
+
+func (e *Email) rewrite() handlers.Rewrite {
+	return handlers.Rewrite{
+		Template: e.cfg.Template.Email,
+		Rules:    []translate.Rules{e.cfg.Translate.Email, e.tr.Rules(e.ID())},
+		UTM:      e.cfg.Links.UTM.Email,
+	}
+}
+
+func (e *Email) ExecuteHandlers(ctx context.Context) {
+	e.handleText(ctx)
+	e.handlePhoto(ctx)
+
+	if e.cfg.SMTP.Digest && e.cfg.SMTP.DigestInterval > 0 {
+		go e.scheduleDigest(ctx)
+	}
+}
+
+func (e *Email) StopNotifications() {
+	e.shouldNotify = false
+}
+
+func (e *Email) SendDigest() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var posts []post
+	if err := e.s.Load(digestKey, &posts); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if len(posts) == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("%s: %d new posts", e.cfg.SMTP.Subject, len(posts))
+	if len(posts) == 1 {
+		subject = fmt.Sprintf("%s: 1 new post", e.cfg.SMTP.Subject)
+	}
+
+	if err := e.send(subject, posts); err != nil {
+		return err
+	}
+
+	return e.s.Delete(digestKey)
+}
+
+func (e *Email) handleText(ctx context.Context) {
+	messages, err := e.q.Subscribe(ctx, pubsub.TextTopic.String())
+	if err != nil {
+		handlers.SendError(e.q, err)
+	}
+
+	go func() {
+		for msg := range messages {
+			if !e.shouldNotify {
+				handlers.Skipped(e.q, e.ID(), msg)
+				msg.Ack()
+
+				continue
+			}
+
+			var m pubsub.TextEvent
+			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
+				handlers.SendMessageError(e.q, msg, err)
+				msg.Ack()
+
+				continue
+			}
+
+			text, err := handlers.Render(msg, m.Text, false, e.rewrite())
+			if err == nil {
+				err = e.deliver(post{ID: msg.UUID, Text: text})
+			}
+
+			handlers.Delivered(e.q, e.log, e.ID(), msg, err)
+
+			msg.Ack()
+		}
+	}()
+}
+
+func (e *Email) handlePhoto(ctx context.Context) {
+	messages, err := e.q.Subscribe(ctx, pubsub.PhotoTopic.String())
+	if err != nil {
+		handlers.SendError(e.q, err)
+	}
+
+	go func() {
+		for msg := range messages {
+			if !e.shouldNotify {
+				handlers.Skipped(e.q, e.ID(), msg)
+				msg.Ack()
+
+				continue
+			}
+
+			var m pubsub.PhotoEvent
+			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
+				handlers.SendMessageError(e.q, msg, err)
+				msg.Ack()
+
+				continue
+			}
+
+			caption, err := handlers.Render(msg, m.Caption, true, e.rewrite())
+			if err == nil {
+				p := post{ID: msg.UUID, Text: caption}
+				if len(m.FileContent) > 0 {
+					p.Photo = m.FileContent
+					p.ContentType = http.DetectContentType(m.FileContent)
+				}
+
+				err = e.deliver(p)
+			}
+
+			handlers.Delivered(e.q, e.log, e.ID(), msg, err)
+
+			msg.Ack()
+		}
+	}()
+}
+
+func (e *Email) deliver(p post) error {
+	p.Published = time.Now().UTC()
+
+	if !e.cfg.SMTP.Digest {
+		return e.send(e.cfg.SMTP.Subject+": "+postSubject(p.Text), []post{p})
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var posts []post
+	if err := e.s.Load(digestKey, &posts); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	return e.s.Save(digestKey, append(posts, p))
+}
+
+func (e *Email) send(subject string, posts []post) error {
+	msg, err := mail{
+		from:    e.cfg.SMTP.From,
+		to:      e.cfg.SMTP.Recipients,
+		subject: subject,
+		date:    time.Now(),
+		posts:   posts,
+		inline:  e.cfg.SMTP.InlinePhotos,
+	}.bytes()
+	if err != nil {
+		return err
+	}
+
+	return e.m.Send(e.cfg.SMTP.From, e.cfg.SMTP.Recipients, msg)
+}
+
+func (e *Email) scheduleDigest(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.SMTP.DigestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !e.shouldNotify {
+				continue
+			}
+
+			if err := e.SendDigest(); err != nil {
+				handlers.SendError(e.q, err)
+			}
+		}
+	}
+}
+
+func postSubject(text string) string {
+	subject := strings.SplitN(strings.TrimSpace(text), "\n", 2)[0]
+
+	if r := []rune(subject); len(r) > subjectLength {
+		return string(r[:subjectLength]) + "..."
+	}
+
+	return subject
+}
@@ -0,0 +1,354 @@
+package handlersemail_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	he "github.com/javiyt/tweetgram/internal/handlers/email"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	mm "github.com/javiyt/tweetgram/mocks/handlers/email"
+	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type gettingChannelError struct{}
+
+func (m gettingChannelError) Error() string {
+	return "error getting channel error"
+}
+
+type sendingMailError struct{}
+
+func (m sendingMailError) Error() string {
+	return "error sending mail"
+}
+
+func TestEmail_ID(t *testing.T) {
+	require.Equal(t, "email", he.NewEmail().ID())
+}
+
+func TestEmail_ExecuteHandlers(t *testing.T) {
+	ctx := context.Background()
+	mockedQueue := new(mq.Queue)
+	eh := he.NewEmail(he.WithQueue(mockedQueue))
+
+	mockedQueue.On("Subscribe", ctx, pubsub.TextTopic.String()).
+		Once().
+		Return(nil, gettingChannelError{})
+	mockedQueue.On("Subscribe", ctx, pubsub.PhotoTopic.String()).
+		Once().
+		Return(nil, gettingChannelError{})
+	mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+		return string(m.Payload) == "{\"error\":\"error getting channel error\"}"
+	})).Times(2).
+		Return(nil)
+
+	eh.ExecuteHandlers(ctx)
+
+	mockedQueue.AssertExpectations(t)
+}
+
+func TestEmail_ExecuteHandlersText(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.AppConfig{
+		SMTP: config.SMTPConfig{
+			From:       "bot@test.com",
+			Recipients: []string{"reader@test.com"},
+			Subject:    "Tweetgram",
+		},
+	}
+
+	t.Run("it should fail unmarshaling text event", func(t *testing.T) {
+		eh, mockedQueue, _, textChannel, _ := generateHandlerAndMocks(ctx, cfg, nil)
+
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return errorMessage(m) == "parse error: unterminated string literal near offset 12 of '{\"asd\":\"qwer'"
+		})).Once().
+			Return(nil)
+
+		eh.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"asd\":\"qwer"))
+
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should fail when mail could not be sent", func(t *testing.T) {
+		eh, mockedQueue, mockedMailer, textChannel, _ := generateHandlerAndMocks(ctx, cfg, nil)
+
+		mockedMailer.On("Send", "bot@test.com", []string{"reader@test.com"}, mock.Anything).
+			Once().
+			Return(sendingMailError{})
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return errorMessage(m) == "error sending mail"
+		})).Once().
+			Return(nil)
+
+		eh.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedMailer.AssertExpectations(t)
+	})
+
+	t.Run("it should send text as html mail", func(t *testing.T) {
+		eh, mockedQueue, mockedMailer, textChannel, _ := generateHandlerAndMocks(ctx, cfg, nil)
+
+		mockedMailer.On("Send", "bot@test.com", []string{"reader@test.com"}, mock.MatchedBy(func(msg []byte) bool {
+			m, body := parseMail(t, msg)
+
+			return m.Header.Get("Subject") == "Tweetgram: testing <message>" &&
+				m.Header.Get("To") == "reader@test.com" &&
+				body == "<html><body><p>testing &lt;message&gt;<br>second line</p></body></html>"
+		})).Once().Return(nil)
+
+		eh.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing <message>\\nsecond line\"}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedMailer.AssertExpectations(t)
+	})
+}
+
+func TestEmail_ExecuteHandlersPhoto(t *testing.T) {
+	ctx := context.Background()
+	image, _ := os.ReadFile("../../bot/testdata/test.png")
+	eventMsg, _ := pubsub.PhotoEvent{Caption: "testing photo", FileContent: image}.MarshalJSON()
+
+	for _, inline := range []bool{true, false} {
+		inline := inline
+		cfg := config.AppConfig{
+			SMTP: config.SMTPConfig{
+				From:         "bot@test.com",
+				Recipients:   []string{"reader@test.com"},
+				Subject:      "Tweetgram",
+				InlinePhotos: inline,
+			},
+		}
+
+		t.Run("it should send photo with the mail", func(t *testing.T) {
+			eh, mockedQueue, mockedMailer, _, photoChannel := generateHandlerAndMocks(ctx, cfg, nil)
+
+			mockedMailer.On("Send", "bot@test.com", []string{"reader@test.com"}, mock.MatchedBy(func(msg []byte) bool {
+				mediaType, parts := parseMultipartMail(t, msg)
+
+				expectedType, disposition := "multipart/mixed", "attachment"
+				if inline {
+					expectedType, disposition = "multipart/related", "inline"
+				}
+
+				return mediaType == expectedType &&
+					len(parts) == 2 &&
+					parts[1].header.Get("Content-Type") == "image/png" &&
+					bytes.Equal(parts[1].content, image) &&
+					bytes.HasPrefix([]byte(parts[1].header.Get("Content-Disposition")), []byte(disposition))
+			})).Once().Return(nil)
+
+			eh.ExecuteHandlers(ctx)
+			sendMessageToChannel(t, photoChannel, eventMsg)
+
+			mockedQueue.AssertExpectations(t)
+			mockedMailer.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEmail_SendDigest(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.AppConfig{
+		SMTP: config.SMTPConfig{
+			From:       "bot@test.com",
+			Recipients: []string{"reader@test.com"},
+			Subject:    "Tweetgram",
+			Digest:     true,
+		},
+	}
+
+	t.Run("it should not send mail when no posts pending", func(t *testing.T) {
+		eh, _, mockedMailer, _, _ := generateHandlerAndMocks(ctx, cfg, storage.NewFileStore(t.TempDir()))
+
+		require.NoError(t, eh.SendDigest())
+		mockedMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("it should keep pending posts when digest could not be sent", func(t *testing.T) {
+		eh, mockedQueue, mockedMailer, textChannel, _ := generateHandlerAndMocks(
+			ctx,
+			cfg,
+			storage.NewFileStore(t.TempDir()),
+		)
+
+		mockedMailer.On("Send", "bot@test.com", []string{"reader@test.com"}, mock.Anything).
+			Once().
+			Return(sendingMailError{})
+		mockedMailer.On("Send", "bot@test.com", []string{"reader@test.com"}, mock.MatchedBy(func(msg []byte) bool {
+			m, _ := parseMail(t, msg)
+
+			return m.Header.Get("Subject") == "Tweetgram: 1 new post"
+		})).Once().Return(nil)
+
+		eh.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"first\"}"))
+
+		require.EqualError(t, eh.SendDigest(), "error sending mail")
+		require.NoError(t, eh.SendDigest())
+		require.NoError(t, eh.SendDigest())
+
+		mockedQueue.AssertExpectations(t)
+		mockedMailer.AssertExpectations(t)
+	})
+
+	t.Run("it should send all pending posts in a single mail", func(t *testing.T) {
+		eh, mockedQueue, mockedMailer, textChannel, _ := generateHandlerAndMocks(
+			ctx,
+			cfg,
+			storage.NewFileStore(t.TempDir()),
+		)
+
+		mockedMailer.On("Send", "bot@test.com", []string{"reader@test.com"}, mock.MatchedBy(func(msg []byte) bool {
+			m, body := parseMail(t, msg)
+
+			return m.Header.Get("Subject") == "Tweetgram: 2 new posts" &&
+				body == "<html><body><p>first</p><hr><p>second</p><hr></body></html>"
+		})).Once().Return(nil)
+
+		eh.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"first\"}"))
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"second\"}"))
+
+		require.NoError(t, eh.SendDigest())
+
+		mockedQueue.AssertExpectations(t)
+		mockedMailer.AssertExpectations(t)
+	})
+}
+
+func TestEmail_ExecuteHandlersNotificationsDisabled(t *testing.T) {
+	ctx := context.Background()
+	eh, mockedQueue, mockedMailer, textChannel, photoChannel := generateHandlerAndMocks(ctx, config.AppConfig{}, nil)
+
+	eh.StopNotifications()
+	eh.ExecuteHandlers(ctx)
+	sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
+	sendMessageToChannel(t, photoChannel, []byte("{\"caption\":\"testing message\"}"))
+
+	mockedQueue.AssertExpectations(t)
+	mockedMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+type mailPart struct {
+	header  textproto.MIMEHeader
+	content []byte
+}
+
+func parseMail(t *testing.T, msg []byte) (*mail.Message, string) {
+	t.Helper()
+
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	require.NoError(t, err)
+
+	dec := new(mime.WordDecoder)
+	subject, _ := dec.DecodeHeader(m.Header.Get("Subject"))
+	m.Header["Subject"] = []string{subject}
+
+	body, _ := io.ReadAll(quotedprintable.NewReader(m.Body))
+
+	return m, string(body)
+}
+
+func parseMultipartMail(t *testing.T, msg []byte) (string, []mailPart) {
+	t.Helper()
+
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	var parts []mailPart
+
+	mr := multipart.NewReader(m.Body, params["boundary"])
+
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+
+		var content []byte
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			content, _ = io.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+		} else {
+			content, _ = io.ReadAll(p)
+		}
+
+		parts = append(parts, mailPart{header: p.Header, content: content})
+	}
+
+	return mediaType, parts
+}
+
+func generateHandlerAndMocks(
+	ctx context.Context,
+	cfg config.AppConfig,
+	store storage.Store,
+) (*he.Email, *mq.Queue, *mm.Mailer, chan *message.Message, chan *message.Message) {
+	mockedQueue := new(mq.Queue)
+	mockedMailer := new(mm.Mailer)
+
+	eh := he.NewEmail(
+		he.WithAppConfig(cfg),
+		he.WithQueue(mockedQueue),
+		he.WithMailer(mockedMailer),
+		he.WithStore(store),
+	)
+
+	textChannel := make(chan *message.Message)
+	photoChannel := make(chan *message.Message)
+
+	mockedQueue.On("Subscribe", ctx, pubsub.TextTopic.String()).
+		Once().
+		Return(func(context.Context, string) <-chan *message.Message {
+			return textChannel
+		}, nil)
+	mockedQueue.On("Subscribe", ctx, pubsub.PhotoTopic.String()).
+		Once().
+		Return(func(context.Context, string) <-chan *message.Message {
+			return photoChannel
+		}, nil)
+
+	return eh, mockedQueue, mockedMailer, textChannel, photoChannel
+}
+
+func sendMessageToChannel(t *testing.T, channel chan *message.Message, eventMsg []byte) {
+	newMessage := message.NewMessage(watermill.NewUUID(), eventMsg)
+	channel <- newMessage
+
+	require.Eventually(t, func() bool {
+		<-newMessage.Acked()
+
+		return true
+	}, time.Second, time.Millisecond)
+}
+
+func errorMessage(m *message.Message) string {
+	var e pubsub.ErrorEvent
+	_ = easyjson.Unmarshal(m.Payload, &e)
+
+	return e.Err
+}
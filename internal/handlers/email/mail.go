@@ -0,0 +1,170 @@
+package handlersemail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const base64LineLength = 76
+
+type post struct {
+	ID          string    `json:"id"`
+	Text        string    `json:"text"`
+	Published   time.Time `json:"published"`
+	Photo       []byte    `json:"photo,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+}
+
+type mail struct {
+	from    string
+	to      []string
+	subject string
+	date    time.Time
+	posts   []post
+	inline  bool
+}
+
+func (m mail) bytes() ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	hasPhotos := false
+	for _, p := range m.posts {
+		hasPhotos = hasPhotos || len(p.Photo) > 0
+	}
+
+	mw := multipart.NewWriter(buf)
+
+	contentType := "text/html; charset=UTF-8"
+
+	switch {
+	case hasPhotos && m.inline:
+		contentType = "multipart/related; boundary=" + mw.Boundary()
+	case hasPhotos:
+		contentType = "multipart/mixed; boundary=" + mw.Boundary()
+	}
+
+	fmt.Fprintf(buf, "From: %s\r\n", m.from)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", m.subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", m.date.Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+
+	if !hasPhotos {
+		fmt.Fprintf(buf, "Content-Type: %s\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", contentType)
+
+		if err := writeQuotedPrintable(buf, m.html()); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	fmt.Fprintf(buf, "Content-Type: %s\r\n\r\n", contentType)
+
+	if err := m.writeParts(mw); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m mail) writeParts(mw *multipart.Writer) error {
+	body, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeQuotedPrintable(body, m.html()); err != nil {
+		return err
+	}
+
+	for _, p := range m.posts {
+		if len(p.Photo) == 0 {
+			continue
+		}
+
+		header := textproto.MIMEHeader{
+			"Content-Type":              {p.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+		}
+
+		if m.inline {
+			header.Set("Content-ID", "<"+p.ID+">")
+			header.Set("Content-Disposition", "inline; filename=\""+p.ID+"\"")
+		} else {
+			header.Set("Content-Disposition", "attachment; filename=\""+p.ID+"\"")
+		}
+
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+
+		if err := writeBase64(part, p.Photo); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+func (m mail) html() string {
+	var sb strings.Builder
+
+	sb.WriteString("<html><body>")
+
+	for _, p := range m.posts {
+		sb.WriteString("<p>")
+		sb.WriteString(strings.ReplaceAll(html.EscapeString(p.Text), "\n", "<br>"))
+		sb.WriteString("</p>")
+
+		if len(p.Photo) > 0 && m.inline {
+			sb.WriteString("<p><img src=\"cid:" + p.ID + "\"></p>")
+		}
+
+		if len(m.posts) > 1 {
+			sb.WriteString("<hr>")
+		}
+	}
+
+	sb.WriteString("</body></html>")
+
+	return sb.String()
+}
+
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+func writeBase64(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+
+	for len(encoded) > base64LineLength {
+		if _, err := w.Write([]byte(encoded[:base64LineLength] + "\r\n")); err != nil {
+			return err
+		}
+
+		encoded = encoded[base64LineLength:]
+	}
+
+	_, err := w.Write([]byte(encoded + "\r\n"))
+
+	return err
+}
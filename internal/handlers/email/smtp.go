@@ -0,0 +1,98 @@
+package handlersemail
+
+import (
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+type Mailer interface {
+	Send(from string, to []string, msg []byte) error
+}
+
+type SMTPMailer struct {
+	host      string
+	port      int
+	username  string
+	password  string
+	startTLS  bool
+	tlsConfig *tls.Config
+}
+
+type SMTPOption func(m *SMTPMailer)
+
+func WithCredentials(username, password string) SMTPOption {
+	return func(m *SMTPMailer) {
+		m.username = username
+		m.password = password
+	}
+}
+
+func WithStartTLS(tlsConfig *tls.Config) SMTPOption {
+	return func(m *SMTPMailer) {
+		m.startTLS = true
+		m.tlsConfig = tlsConfig
+	}
+}
+
+func NewSMTPMailer(host string, port int, options ...SMTPOption) *SMTPMailer {
+	m := &SMTPMailer{host: host, port: port}
+
+	for _, o := range options {
+		o(m)
+	}
+
+	return m
+}
+
+func (m *SMTPMailer) Send(from string, to []string, msg []byte) error {
+	c, err := smtp.Dial(net.JoinHostPort(m.host, strconv.Itoa(m.port)))
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = c.Close() }()
+
+	if m.startTLS {
+		tlsConfig := m.tlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: m.host, MinVersion: tls.VersionTLS12}
+		}
+
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if m.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,159 @@
+package handlersemail_test
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	he "github.com/javiyt/tweetgram/internal/handlers/email"
+	"github.com/stretchr/testify/require"
+)
+
+type smtpServer struct {
+	l        net.Listener
+	mu       sync.Mutex
+	commands []string
+	data     []string
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	t.Run("it should fail when server is not reachable", func(t *testing.T) {
+		l, _ := net.Listen("tcp", "127.0.0.1:0")
+		port := l.Addr().(*net.TCPAddr).Port
+		_ = l.Close()
+
+		require.Error(t, he.NewSMTPMailer("127.0.0.1", port).Send("from@test.com", []string{"to@test.com"}, nil))
+	})
+
+	t.Run("it should fail when server doesn't support STARTTLS", func(t *testing.T) {
+		s := newSMTPServer(t)
+
+		err := he.NewSMTPMailer("127.0.0.1", s.port(), he.WithStartTLS(nil)).
+			Send("from@test.com", []string{"to@test.com"}, []byte("message"))
+
+		require.EqualError(t, err, "502 \"command not implemented\"")
+	})
+
+	t.Run("it should send message to every recipient", func(t *testing.T) {
+		s := newSMTPServer(t)
+
+		err := he.NewSMTPMailer("localhost", s.port(), he.WithCredentials("user", "password")).
+			Send("from@test.com", []string{"first@test.com", "second@test.com"}, []byte("Subject: test\r\n\r\nbody"))
+
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"EHLO localhost",
+			"AUTH PLAIN AHVzZXIAcGFzc3dvcmQ=",
+			"MAIL FROM:<from@test.com> BODY=8BITMIME",
+			"RCPT TO:<first@test.com>",
+			"RCPT TO:<second@test.com>",
+			"DATA",
+			"QUIT",
+		}, s.received())
+		require.Equal(t, []string{"Subject: test\r\n\r\nbody\r\n"}, s.messages())
+	})
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpServer{l: l}
+
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.l.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commands
+}
+
+func (s *smtpServer) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP test server")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		switch cmd {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250-8BITMIME")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 authenticated")
+		case "MAIL", "RCPT":
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			s.readData(r)
+			reply("250 queued as " + strconv.Itoa(len(s.messages())))
+		case "QUIT":
+			reply("221 bye")
+
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) readData(r *bufio.Reader) {
+	var sb strings.Builder
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil || line == ".\r\n" {
+			break
+		}
+
+		sb.WriteString(line)
+	}
+
+	s.mu.Lock()
+	s.data = append(s.data, sb.String())
+	s.mu.Unlock()
+}
@@ -2,19 +2,81 @@ package handlerserror
 
 import (
 	"context"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/i18n"
+	"github.com/javiyt/tweetgram/internal/logging"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
 	"github.com/mailru/easyjson"
 	"github.com/sirupsen/logrus"
 )
 
 type ErrorHandler struct {
-	log *logrus.Logger
-	q   pubsub.Queue
+	log          *logrus.Logger
+	q            pubsub.Queue
+	bot          bot.TelegramBot
+	cfg          config.Holder
+	s            storage.Store
+	now          func() time.Time
+	mu           sync.Mutex
+	limits       map[string]*notifyLimit
+	shouldNotify bool
 }
 
-func NewErrorHandler(log *logrus.Logger, q pubsub.Queue) *ErrorHandler {
-	return &ErrorHandler{log: log, q: q}
+// notifyLimit keeps track of the failures notified to a chat in the current
+// interval and the ones left out once the limit was reached.
+type notifyLimit struct {
+	since      time.Time
+	sent       int
+	suppressed int
+}
+
+type Option func(eh *ErrorHandler)
+
+func WithTelegramBot(tb bot.TelegramBot) Option {
+	return func(eh *ErrorHandler) {
+		eh.bot = tb
+	}
+}
+
+func WithAppConfig(cfg config.AppConfig) Option {
+	return func(eh *ErrorHandler) {
+		eh.cfg.Set(cfg)
+	}
+}
+
+// WithStore lets failures reach the admins added through /addadmin too.
+func WithStore(s storage.Store) Option {
+	return func(eh *ErrorHandler) {
+		eh.s = s
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(eh *ErrorHandler) {
+		eh.now = now
+	}
+}
+
+func NewErrorHandler(log *logrus.Logger, q pubsub.Queue, options ...Option) *ErrorHandler {
+	eh := &ErrorHandler{
+		log:          log,
+		q:            q,
+		now:          time.Now,
+		limits:       make(map[string]*notifyLimit),
+		shouldNotify: true,
+	}
+
+	for _, o := range options {
+		o(eh)
+	}
+
+	return eh
 }
 
 func (eh *ErrorHandler) ID() string {
@@ -38,12 +100,127 @@ func (eh *ErrorHandler) ExecuteHandlers(ctx context.Context) {
 				continue
 			}
 
-			eh.log.Error(m.Err)
+			eh.log.WithContext(msg.Context()).WithFields(eventFields(m)).Error(m.Err)
+			eh.notifyAdmins(msg.Context(), m)
 			msg.Ack()
 		}
 	}()
 }
 
+func eventFields(m pubsub.ErrorEvent) logrus.Fields {
+	fields := logrus.Fields{}
+
+	for k, v := range map[string]string{
+		"handler":                  m.Handler,
+		"topic":                    m.Topic,
+		"message_uuid":             m.MessageUUID,
+		logging.CorrelationIDField: m.CorrelationID,
+		"sender_id":                m.SenderID,
+	} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+
+	if m.PublishedAt != nil {
+		fields["published_at"] = *m.PublishedAt
+	}
+
+	return fields
+}
+
+// Reload swaps the configuration, so new admins and notification limits apply
+// to the next failures.
+func (eh *ErrorHandler) Reload(cfg config.AppConfig) {
+	eh.cfg.Set(cfg)
+}
+
 func (eh *ErrorHandler) StopNotifications() {
-	return
+	eh.shouldNotify = false
+}
+
+// notifyAdmins tells the staff member who sent the failed message, or every
+// admin when the sender is unknown or has no role, which destination couldn't
+// be reached. Only errors raised while delivering a message are notified.
+func (eh *ErrorHandler) notifyAdmins(ctx context.Context, m pubsub.ErrorEvent) {
+	if !eh.shouldNotify || eh.bot == nil || m.Handler == "" {
+		return
+	}
+
+	chats, lang := eh.recipients(ctx, m)
+
+	for _, to := range chats {
+		suppressed, ok := eh.allow(to)
+		if !ok {
+			continue
+		}
+
+		text := i18n.T(lang, "errors.failed", m.Handler, m.Err)
+		if suppressed > 0 {
+			text += "\n\n" + i18n.T(lang, "errors.suppressed", suppressed)
+		}
+
+		if err := eh.bot.Send(ctx, to, text); err != nil {
+			eh.log.WithContext(ctx).WithField("admin", to).Error(err)
+		}
+	}
+}
+
+// recipients returns who is notified of the failure and in which language:
+// the sender's when it's them, the default one when it's every admin.
+func (eh *ErrorHandler) recipients(ctx context.Context, m pubsub.ErrorEvent) ([]string, string) {
+	cfg := eh.cfg.Get()
+
+	if eh.s != nil {
+		staff, err := bot.StaffConfig(cfg, eh.s)
+		if err != nil {
+			eh.log.WithContext(ctx).WithError(err).Warn("error loading admins")
+		}
+
+		cfg = staff
+	}
+
+	if sender, err := strconv.Atoi(m.SenderID); err == nil && cfg.Role(sender) != config.RoleNone {
+		return []string{m.SenderID}, i18n.Language(m.Language, cfg.DefaultLanguage)
+	}
+
+	to := make([]string, 0, len(cfg.Admins))
+	for _, admin := range cfg.Admins {
+		to = append(to, strconv.Itoa(admin))
+	}
+
+	return to, i18n.Language("", cfg.DefaultLanguage)
+}
+
+// allow reports whether another failure can be notified to the chat in the
+// current interval, along with the failures left out in the previous one.
+func (eh *ErrorHandler) allow(chat string) (int, bool) {
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+
+	now := eh.now()
+	cfg := eh.cfg.Get()
+
+	l, ok := eh.limits[chat]
+	if !ok {
+		l = &notifyLimit{since: now}
+		eh.limits[chat] = l
+	}
+
+	var suppressed int
+
+	if now.Sub(l.since) >= cfg.ErrorNotifyInterval {
+		suppressed = l.suppressed
+		*l = notifyLimit{since: now}
+	}
+
+	if l.sent >= cfg.ErrorNotifyLimit {
+		l.suppressed++
+
+		return 0, false
+	}
+
+	l.sent++
+
+	return suppressed, true
 }
@@ -7,11 +7,15 @@ import (
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
 	hse "github.com/javiyt/tweetgram/internal/handlers/error"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	mb "github.com/javiyt/tweetgram/mocks/bot"
 	mq "github.com/javiyt/tweetgram/mocks/pubsub"
 	"github.com/sirupsen/logrus"
 	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -79,6 +83,29 @@ func TestError_ExecuteHandlers(t *testing.T) {
 		assertLogMessage(t, hook, "an error message")
 		mockedQueue.AssertExpectations(t)
 	})
+
+	t.Run("it should log failed message details", func(t *testing.T) {
+		hook, mockedQueue, th, errorChannel := generateMocksAndErrorChannel()
+		mockedQueue.On("Subscribe", ctx, pubsub.ErrorTopic.String()).
+			Once().
+			Return(func(context.Context, string) <-chan *message.Message {
+				return errorChannel
+			}, nil)
+
+		th.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, errorChannel, []byte("{\"error\":\"an error message\",\"handler\":\"twitter\","+
+			"\"topic\":\"text\",\"messageUuid\":\"1234\",\"correlationId\":\"5678\"}"))
+
+		entry := hook.LastEntry()
+		require.Equal(t, logrus.Fields{
+			"handler":        "twitter",
+			"topic":          "text",
+			"message_uuid":   "1234",
+			"correlation_id": "5678",
+		}, entry.Data)
+		assertLogMessage(t, hook, "an error message")
+		mockedQueue.AssertExpectations(t)
+	})
 }
 
 func TestErrorHandler_ExecuteHandlersNotificationsDisabled(t *testing.T) {
@@ -101,6 +128,126 @@ func TestErrorHandler_ExecuteHandlersNotificationsDisabled(t *testing.T) {
 	})
 }
 
+func TestErrorHandler_NotifyAdmins(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.AppConfig{
+		Admins:              []int{1234, 5678},
+		Publishers:          []int{4321},
+		ErrorNotifyLimit:    2,
+		ErrorNotifyInterval: time.Minute,
+	}
+
+	store := storage.NewFileStore(t.TempDir())
+	require.NoError(t, store.Save("bot/admins", []int{9012}))
+
+	generateHandler := func(now *time.Time) (*mb.TelegramBot, chan *message.Message) {
+		mockedLogger, _ := logrusTest.NewNullLogger()
+		mockedQueue := new(mq.Queue)
+		mockedBot := new(mb.TelegramBot)
+		errorChannel := make(chan *message.Message)
+
+		mockedQueue.On("Subscribe", ctx, pubsub.ErrorTopic.String()).
+			Once().
+			Return(func(context.Context, string) <-chan *message.Message {
+				return errorChannel
+			}, nil)
+
+		hse.NewErrorHandler(mockedLogger, mockedQueue,
+			hse.WithAppConfig(cfg),
+			hse.WithTelegramBot(mockedBot),
+			hse.WithStore(store),
+			hse.WithClock(func() time.Time { return *now }),
+		).ExecuteHandlers(ctx)
+
+		return mockedBot, errorChannel
+	}
+
+	t.Run("it should notify admin who sent the message", func(t *testing.T) {
+		now := time.Now()
+		mockedBot, errorChannel := generateHandler(&now)
+		mockedBot.On("Send", mock.Anything, "5678", "Publishing to twitter failed: couldn't send tweet").
+			Once().
+			Return(nil)
+
+		sendMessageToChannel(t, errorChannel,
+			[]byte("{\"error\":\"couldn't send tweet\",\"handler\":\"twitter\",\"senderId\":\"5678\"}"))
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should notify publisher who sent the message", func(t *testing.T) {
+		now := time.Now()
+		mockedBot, errorChannel := generateHandler(&now)
+		mockedBot.On("Send", mock.Anything, "4321", "Publishing to twitter failed: couldn't send tweet").
+			Once().
+			Return(nil)
+
+		sendMessageToChannel(t, errorChannel,
+			[]byte("{\"error\":\"couldn't send tweet\",\"handler\":\"twitter\",\"senderId\":\"4321\"}"))
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should notify the sender in their language", func(t *testing.T) {
+		now := time.Now()
+		mockedBot, errorChannel := generateHandler(&now)
+		mockedBot.On("Send", mock.Anything, "4321", "Error al publicar en twitter: couldn't send tweet").
+			Once().
+			Return(nil)
+
+		sendMessageToChannel(t, errorChannel, []byte("{\"error\":\"couldn't send tweet\",\"handler\":\"twitter\","+
+			"\"senderId\":\"4321\",\"language\":\"es\"}"))
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should notify every admin when sender is unknown", func(t *testing.T) {
+		now := time.Now()
+		mockedBot, errorChannel := generateHandler(&now)
+		for _, admin := range []string{"1234", "5678", "9012"} {
+			mockedBot.On("Send", mock.Anything, admin, "Publishing to twitter failed: couldn't send tweet").
+				Once().
+				Return(nil)
+		}
+
+		sendMessageToChannel(t, errorChannel, []byte("{\"error\":\"couldn't send tweet\",\"handler\":\"twitter\"}"))
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should not notify errors not related to a delivery", func(t *testing.T) {
+		now := time.Now()
+		mockedBot, errorChannel := generateHandler(&now)
+
+		sendMessageToChannel(t, errorChannel, []byte("{\"error\":\"error getting channel error\"}"))
+
+		mockedBot.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("it should limit notifications sent in the interval", func(t *testing.T) {
+		now := time.Now()
+		mockedBot, errorChannel := generateHandler(&now)
+		mockedBot.On("Send", mock.Anything, "1234", "Publishing to feed failed: error accessing store").
+			Twice().
+			Return(nil)
+		mockedBot.On("Send", mock.Anything, "1234",
+			"Publishing to feed failed: error accessing store\n\n2 more failures were not notified").
+			Once().
+			Return(nil)
+
+		for i := 0; i < 4; i++ {
+			sendMessageToChannel(t, errorChannel,
+				[]byte("{\"error\":\"error accessing store\",\"handler\":\"feed\",\"senderId\":\"1234\"}"))
+		}
+
+		now = now.Add(time.Minute)
+		sendMessageToChannel(t, errorChannel,
+			[]byte("{\"error\":\"error accessing store\",\"handler\":\"feed\",\"senderId\":\"1234\"}"))
+
+		mockedBot.AssertExpectations(t)
+	})
+}
+
 func generateMocksAndErrorChannel() (*logrusTest.Hook, *mq.Queue, *hse.ErrorHandler, chan *message.Message) {
 	mockedLogger, hook := logrusTest.NewNullLogger()
 	mockedQueue := new(mq.Queue)
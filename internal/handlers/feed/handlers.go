@@ -0,0 +1,236 @@
+package handlersfeed
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/translate"
+	"github.com/mailru/easyjson"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	itemsKey     = "feed/items"
+	mediaKeyBase = "feed/media/"
+)
+
+type Feed struct {
+	cfg          config.AppConfig
+	q            pubsub.Queue
+	tr           *translate.Table
+	s            storage.Store
+	mu           sync.Mutex
+	log          *logrus.Logger
+	shouldNotify bool
+}
+
+type Item struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	Published time.Time `json:"published"`
+	MediaType string    `json:"mediaType,omitempty"`
+	MediaSize int64     `json:"mediaSize,omitempty"`
+}
+
+type media struct {
+	ContentType string `json:"contentType"`
+	Content     []byte `json:"content"`
+}
+
+type Option func(f *Feed)
+
+func WithAppConfig(cfg config.AppConfig) Option {
+	return func(f *Feed) {
+		f.cfg = cfg
+	}
+}
+
+func WithQueue(q pubsub.Queue) Option {
+	return func(f *Feed) {
+		f.q = q
+	}
+}
+
+func WithTranslations(tr *translate.Table) Option {
+	return func(f *Feed) {
+		f.tr = tr
+	}
+}
+
+func WithStore(s storage.Store) Option {
+	return func(f *Feed) {
+		f.s = s
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(f *Feed) {
+		f.log = log
+	}
+}
+
+func NewFeed(options ...Option) *Feed {
+	f := &Feed{log: logging.Discard(), tr: new(translate.Table), shouldNotify: true}
+
+	for _, o := range options {
+		o(f)
+	}
+
+	return f
+}
+
+func (f *Feed) ID() string {
+	return "feed"
+}
+
+func (f *Feed) rewrite() handlers.Rewrite {
+	return handlers.Rewrite{
+		Template: f.cfg.Template.Feed,
+		Rules:    []translate.Rules{f.cfg.Translate.Feed, f.tr.Rules(f.ID())},
+		UTM:      f.cfg.Links.UTM.Feed,
+	}
+}
+
+func (f *Feed) ExecuteHandlers(ctx context.Context) {
+	f.handleText(ctx)
+	f.handlePhoto(ctx)
+}
+
+func (f *Feed) StopNotifications() {
+	f.shouldNotify = false
+}
+
+func (f *Feed) Items() ([]Item, error) {
+	var items []Item
+	if err := f.s.Load(itemsKey, &items); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (f *Feed) handleText(ctx context.Context) {
+	messages, err := f.q.Subscribe(ctx, pubsub.TextTopic.String())
+	if err != nil {
+		handlers.SendError(f.q, err)
+	}
+
+	go func() {
+		for msg := range messages {
+			if !f.shouldNotify {
+				handlers.Skipped(f.q, f.ID(), msg)
+				msg.Ack()
+
+				continue
+			}
+
+			var m pubsub.TextEvent
+			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
+				handlers.SendMessageError(f.q, msg, err)
+				msg.Ack()
+
+				continue
+			}
+
+			text, err := handlers.Render(msg, m.Text, false, f.rewrite())
+			if err == nil {
+				err = f.record(Item{ID: msg.UUID, Text: text}, nil)
+			}
+
+			handlers.Delivered(f.q, f.log, f.ID(), msg, err)
+
+			msg.Ack()
+		}
+	}()
+}
+
+func (f *Feed) handlePhoto(ctx context.Context) {
+	messages, err := f.q.Subscribe(ctx, pubsub.PhotoTopic.String())
+	if err != nil {
+		handlers.SendError(f.q, err)
+	}
+
+	go func() {
+		for msg := range messages {
+			if !f.shouldNotify {
+				handlers.Skipped(f.q, f.ID(), msg)
+				msg.Ack()
+
+				continue
+			}
+
+			var m pubsub.PhotoEvent
+			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
+				handlers.SendMessageError(f.q, msg, err)
+				msg.Ack()
+
+				continue
+			}
+
+			caption, err := handlers.Render(msg, m.Caption, true, f.rewrite())
+			if err == nil {
+				m.Caption = caption
+				err = f.recordPhoto(msg, m)
+			}
+
+			handlers.Delivered(f.q, f.log, f.ID(), msg, err)
+
+			msg.Ack()
+		}
+	}()
+}
+
+func (f *Feed) recordPhoto(msg *message.Message, m pubsub.PhotoEvent) error {
+	if len(m.FileContent) == 0 {
+		return f.record(Item{ID: msg.UUID, Text: m.Caption}, nil)
+	}
+
+	md := &media{ContentType: http.DetectContentType(m.FileContent), Content: m.FileContent}
+
+	return f.record(Item{
+		ID:        msg.UUID,
+		Text:      m.Caption,
+		MediaType: md.ContentType,
+		MediaSize: int64(len(md.Content)),
+	}, md)
+}
+
+func (f *Feed) record(item Item, md *media) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	items, err := f.Items()
+	if err != nil {
+		return err
+	}
+
+	if md != nil {
+		if err := f.s.Save(mediaKeyBase+item.ID, md); err != nil {
+			return err
+		}
+	}
+
+	item.Published = time.Now().UTC()
+	items = append([]Item{item}, items...)
+
+	if f.cfg.Feed.MaxItems > 0 && len(items) > f.cfg.Feed.MaxItems {
+		for _, old := range items[f.cfg.Feed.MaxItems:] {
+			if old.MediaType != "" {
+				_ = f.s.Delete(mediaKeyBase + old.ID)
+			}
+		}
+
+		items = items[:f.cfg.Feed.MaxItems]
+	}
+
+	return f.s.Save(itemsKey, items)
+}
@@ -0,0 +1,252 @@
+package handlersfeed_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	hf "github.com/javiyt/tweetgram/internal/handlers/feed"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	ms "github.com/javiyt/tweetgram/mocks/storage"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type gettingChannelError struct{}
+
+func (m gettingChannelError) Error() string {
+	return "error getting channel error"
+}
+
+type storeError struct{}
+
+func (m storeError) Error() string {
+	return "error accessing store"
+}
+
+func TestFeed_ID(t *testing.T) {
+	require.Equal(t, "feed", hf.NewFeed().ID())
+}
+
+func TestFeed_ExecuteHandlers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it should fail getting channel for text and photo notifications", func(t *testing.T) {
+		mockedQueue := new(mq.Queue)
+		fh := hf.NewFeed(hf.WithQueue(mockedQueue))
+
+		mockedQueue.On("Subscribe", ctx, pubsub.TextTopic.String()).
+			Once().
+			Return(nil, gettingChannelError{})
+		mockedQueue.On("Subscribe", ctx, pubsub.PhotoTopic.String()).
+			Once().
+			Return(nil, gettingChannelError{})
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return string(m.Payload) == "{\"error\":\"error getting channel error\"}"
+		})).Times(2).
+			Return(nil)
+
+		fh.ExecuteHandlers(ctx)
+
+		mockedQueue.AssertExpectations(t)
+	})
+}
+
+func TestFeed_ExecuteHandlersText(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it should fail unmarshaling text event", func(t *testing.T) {
+		fh, mockedQueue, textChannel, _ := generateHandlerAndMocks(
+			ctx,
+			storage.NewFileStore(t.TempDir()),
+			config.AppConfig{Feed: config.FeedConfig{MaxItems: 10}},
+		)
+
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return errorMessage(m) == "parse error: unterminated string literal near offset 12 of '{\"asd\":\"qwer'"
+		})).Once().
+			Return(nil)
+
+		fh.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"asd\":\"qwer"))
+
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should fail when items could not be stored", func(t *testing.T) {
+		mockedStore := new(ms.Store)
+		fh, mockedQueue, textChannel, _ := generateHandlerAndMocks(
+			ctx,
+			mockedStore,
+			config.AppConfig{Feed: config.FeedConfig{MaxItems: 10}},
+		)
+
+		mockedStore.On("Load", "feed/items", mock.Anything).Once().Return(storeError{})
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return errorMessage(m) == "error accessing store"
+		})).Once().
+			Return(nil)
+
+		fh.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedStore.AssertExpectations(t)
+	})
+
+	t.Run("it should record text messages newest first up to max items", func(t *testing.T) {
+		fh, mockedQueue, textChannel, _ := generateHandlerAndMocks(
+			ctx,
+			storage.NewFileStore(t.TempDir()),
+			config.AppConfig{Feed: config.FeedConfig{MaxItems: 2}},
+		)
+
+		fh.ExecuteHandlers(ctx)
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"first\"}"))
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"second\"}"))
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"third\"}"))
+
+		items, err := fh.Items()
+
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.Equal(t, "third", items[0].Text)
+		require.Equal(t, "second", items[1].Text)
+		require.False(t, items[0].Published.IsZero())
+		mockedQueue.AssertExpectations(t)
+	})
+}
+
+func TestFeed_ExecuteHandlersPhoto(t *testing.T) {
+	ctx := context.Background()
+	image, _ := os.ReadFile("../../bot/testdata/test.png")
+
+	t.Run("it should record photo messages with media content", func(t *testing.T) {
+		store := storage.NewFileStore(t.TempDir())
+		fh, mockedQueue, _, photoChannel := generateHandlerAndMocks(
+			ctx,
+			store,
+			config.AppConfig{Feed: config.FeedConfig{MaxItems: 10}},
+		)
+
+		fh.ExecuteHandlers(ctx)
+
+		eventMsg, _ := pubsub.PhotoEvent{Caption: "testing photo", FileContent: image}.MarshalJSON()
+		sendMessageToChannel(t, photoChannel, eventMsg)
+
+		items, err := fh.Items()
+
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		require.Equal(t, "testing photo", items[0].Text)
+		require.Equal(t, "image/png", items[0].MediaType)
+		require.Equal(t, int64(len(image)), items[0].MediaSize)
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should remove media of items exceeding max items", func(t *testing.T) {
+		mockedStore := new(ms.Store)
+		fh, mockedQueue, _, photoChannel := generateHandlerAndMocks(
+			ctx,
+			mockedStore,
+			config.AppConfig{Feed: config.FeedConfig{MaxItems: 1}},
+		)
+
+		mockedStore.On("Load", "feed/items", mock.Anything).Once().
+			Return(nil).
+			Run(func(args mock.Arguments) {
+				items, _ := args.Get(1).(*[]hf.Item)
+				*items = []hf.Item{{ID: "old", MediaType: "image/png"}}
+			})
+		mockedStore.On("Save", mock.MatchedBy(func(key string) bool {
+			return key != "feed/items"
+		}), mock.Anything).Once().Return(nil)
+		mockedStore.On("Delete", "feed/media/old").Once().Return(nil)
+		mockedStore.On("Save", "feed/items", mock.MatchedBy(func(items []hf.Item) bool {
+			return len(items) == 1 && items[0].Text == "testing photo"
+		})).Once().Return(nil)
+
+		fh.ExecuteHandlers(ctx)
+
+		eventMsg, _ := pubsub.PhotoEvent{Caption: "testing photo", FileContent: image}.MarshalJSON()
+		sendMessageToChannel(t, photoChannel, eventMsg)
+
+		mockedQueue.AssertExpectations(t)
+		mockedStore.AssertExpectations(t)
+	})
+}
+
+func TestFeed_ExecuteHandlersNotificationsDisabled(t *testing.T) {
+	ctx := context.Background()
+	fh, mockedQueue, textChannel, photoChannel := generateHandlerAndMocks(
+		ctx,
+		storage.NewFileStore(t.TempDir()),
+		config.AppConfig{Feed: config.FeedConfig{MaxItems: 10}},
+	)
+
+	fh.StopNotifications()
+	fh.ExecuteHandlers(ctx)
+	sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
+	sendMessageToChannel(t, photoChannel, []byte("{\"caption\":\"testing message\"}"))
+
+	items, err := fh.Items()
+
+	require.NoError(t, err)
+	require.Empty(t, items)
+	mockedQueue.AssertExpectations(t)
+}
+
+func generateHandlerAndMocks(
+	ctx context.Context,
+	store storage.Store,
+	cfg config.AppConfig,
+) (*hf.Feed, *mq.Queue, chan *message.Message, chan *message.Message) {
+	mockedQueue := new(mq.Queue)
+
+	fh := hf.NewFeed(
+		hf.WithAppConfig(cfg),
+		hf.WithQueue(mockedQueue),
+		hf.WithStore(store),
+	)
+
+	textChannel := make(chan *message.Message)
+	photoChannel := make(chan *message.Message)
+
+	mockedQueue.On("Subscribe", ctx, pubsub.TextTopic.String()).
+		Once().
+		Return(func(context.Context, string) <-chan *message.Message {
+			return textChannel
+		}, nil)
+	mockedQueue.On("Subscribe", ctx, pubsub.PhotoTopic.String()).
+		Once().
+		Return(func(context.Context, string) <-chan *message.Message {
+			return photoChannel
+		}, nil)
+
+	return fh, mockedQueue, textChannel, photoChannel
+}
+
+func sendMessageToChannel(t *testing.T, channel chan *message.Message, eventMsg []byte) {
+	newMessage := message.NewMessage(watermill.NewUUID(), eventMsg)
+	channel <- newMessage
+
+	require.Eventually(t, func() bool {
+		<-newMessage.Acked()
+
+		return true
+	}, time.Second, time.Millisecond)
+}
+
+func errorMessage(m *message.Message) string {
+	var e pubsub.ErrorEvent
+	_ = easyjson.Unmarshal(m.Payload, &e)
+
+	return e.Err
+}
@@ -0,0 +1,227 @@
+package handlersfeed
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/storage"
+)
+
+const titleLength = 80
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Content atomContent `xml:"content"`
+	Links   []atomLink  `xml:"link"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+func (f *Feed) Routes() map[string]http.Handler {
+	return map[string]http.Handler{
+		"/feed.rss":  http.HandlerFunc(f.serveRSS),
+		"/feed.atom": http.HandlerFunc(f.serveAtom),
+		"/media/":    http.HandlerFunc(f.serveMedia),
+	}
+}
+
+func (f *Feed) serveRSS(w http.ResponseWriter, _ *http.Request) {
+	items, err := f.Items()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	feed := rss{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       f.cfg.Feed.Title,
+			Link:        f.cfg.Feed.Link,
+			Description: f.cfg.Feed.Description,
+		},
+	}
+
+	if len(items) > 0 {
+		feed.Channel.LastBuildDate = items[0].Published.Format(time.RFC1123Z)
+	}
+
+	for _, item := range items {
+		ri := rssItem{
+			Title:       itemTitle(item.Text),
+			Description: item.Text,
+			GUID:        rssGUID{Value: item.ID},
+			PubDate:     item.Published.Format(time.RFC1123Z),
+		}
+
+		if item.MediaType != "" {
+			ri.Enclosure = &rssEnclosure{URL: f.mediaURL(item.ID), Length: item.MediaSize, Type: item.MediaType}
+		}
+
+		feed.Channel.Items = append(feed.Channel.Items, ri)
+	}
+
+	writeXML(w, "application/rss+xml; charset=utf-8", feed)
+}
+
+func (f *Feed) serveAtom(w http.ResponseWriter, _ *http.Request) {
+	items, err := f.Items()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	feed := atomFeed{
+		ID:      f.baseURL() + "/feed.atom",
+		Title:   f.cfg.Feed.Title,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.cfg.Feed.Link},
+			{Href: f.baseURL() + "/feed.atom", Rel: "self"},
+		},
+	}
+
+	if len(items) > 0 {
+		feed.Updated = items[0].Published.Format(time.RFC3339)
+	}
+
+	for _, item := range items {
+		ae := atomEntry{
+			ID:      "urn:uuid:" + item.ID,
+			Title:   itemTitle(item.Text),
+			Updated: item.Published.Format(time.RFC3339),
+			Content: atomContent{Type: "text", Value: item.Text},
+		}
+
+		if item.MediaType != "" {
+			ae.Links = append(ae.Links, atomLink{
+				Href:   f.mediaURL(item.ID),
+				Rel:    "enclosure",
+				Type:   item.MediaType,
+				Length: item.MediaSize,
+			})
+		}
+
+		feed.Entries = append(feed.Entries, ae)
+	}
+
+	writeXML(w, "application/atom+xml; charset=utf-8", feed)
+}
+
+func (f *Feed) serveMedia(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/media/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	var md media
+
+	err := f.s.Load(mediaKeyBase+id, &md)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", md.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(md.Content)))
+	_, _ = w.Write(md.Content)
+}
+
+func (f *Feed) mediaURL(id string) string {
+	return f.baseURL() + "/media/" + id
+}
+
+func (f *Feed) baseURL() string {
+	return strings.TrimSuffix(f.cfg.Feed.Link, "/")
+}
+
+func itemTitle(text string) string {
+	title := strings.SplitN(strings.TrimSpace(text), "\n", 2)[0]
+
+	if r := []rune(title); len(r) > titleLength {
+		return string(r[:titleLength]) + "..."
+	}
+
+	return title
+}
+
+func writeXML(w http.ResponseWriter, contentType string, v interface{}) {
+	content, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(content)
+}
@@ -0,0 +1,101 @@
+package handlersfeed_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/javiyt/tweetgram/internal/config"
+	hf "github.com/javiyt/tweetgram/internal/handlers/feed"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	ms "github.com/javiyt/tweetgram/mocks/storage"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeed_Routes(t *testing.T) {
+	ctx := context.Background()
+	image, _ := os.ReadFile("../../bot/testdata/test.png")
+
+	store := storage.NewFileStore(t.TempDir())
+	fh, _, textChannel, photoChannel := generateHandlerAndMocks(ctx, store, config.AppConfig{
+		Feed: config.FeedConfig{
+			Title:       "Tweetgram feed",
+			Link:        "https://feed.example.com/",
+			Description: "Published posts",
+			MaxItems:    10,
+		},
+	})
+
+	fh.ExecuteHandlers(ctx)
+	sendMessageToChannel(t, textChannel, []byte("{\"text\":\"a text post\\nwith two lines\"}"))
+
+	eventMsg, _ := pubsub.PhotoEvent{Caption: "a photo post", FileContent: image}.MarshalJSON()
+	sendMessageToChannel(t, photoChannel, eventMsg)
+
+	items, _ := fh.Items()
+	routes := fh.Routes()
+
+	t.Run("it should serve rss feed", func(t *testing.T) {
+		rec := serve(routes["/feed.rss"], "/feed.rss")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/rss+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Contains(t, rec.Body.String(), "<title>Tweetgram feed</title>")
+		require.Contains(t, rec.Body.String(), "<description>Published posts</description>")
+		require.Contains(t, rec.Body.String(), "<title>a text post</title>")
+		require.Contains(t, rec.Body.String(), "<enclosure url=\"https://feed.example.com/media/"+items[0].ID+
+			"\" length=\""+strconv.Itoa(len(image))+"\" type=\"image/png\"></enclosure>")
+	})
+
+	t.Run("it should serve atom feed", func(t *testing.T) {
+		rec := serve(routes["/feed.atom"], "/feed.atom")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/atom+xml; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Contains(t, rec.Body.String(), "<feed xmlns=\"http://www.w3.org/2005/Atom\">")
+		require.Contains(t, rec.Body.String(), "<id>urn:uuid:"+items[1].ID+"</id>")
+		require.Contains(t, rec.Body.String(), "<link href=\"https://feed.example.com/feed.atom\" rel=\"self\"></link>")
+		require.Contains(t, rec.Body.String(), "<link href=\"https://feed.example.com/media/"+items[0].ID+
+			"\" rel=\"enclosure\" type=\"image/png\" length=\""+strconv.Itoa(len(image))+"\"></link>")
+	})
+
+	t.Run("it should serve stored media", func(t *testing.T) {
+		rec := serve(routes["/media/"], "/media/"+items[0].ID)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+		require.Equal(t, image, rec.Body.Bytes())
+	})
+
+	t.Run("it should return not found when media doesn't exist", func(t *testing.T) {
+		for _, path := range []string{"/media/", "/media/unknown", "/media/a/b"} {
+			require.Equal(t, http.StatusNotFound, serve(routes["/media/"], path).Code)
+		}
+	})
+}
+
+func TestFeed_RoutesStoreError(t *testing.T) {
+	mockedStore := new(ms.Store)
+	mockedStore.On("Load", mock.Anything, mock.Anything).Return(storeError{})
+
+	routes := hf.NewFeed(hf.WithStore(mockedStore)).Routes()
+
+	for path, h := range routes {
+		rec := serve(h, path+"id")
+
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		require.Equal(t, "error accessing store\n", rec.Body.String())
+	}
+}
+
+func serve(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return rec
+}
@@ -2,11 +2,19 @@ package handlers
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/links"
+	"github.com/javiyt/tweetgram/internal/metrics"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/render"
+	"github.com/javiyt/tweetgram/internal/translate"
 	"github.com/mailru/easyjson"
+	"github.com/sirupsen/logrus"
 )
 
 type EventHandler interface {
@@ -15,6 +23,17 @@ type EventHandler interface {
 	StopNotifications()
 }
 
+// reloader is implemented by the handlers with settings that can change while
+// running.
+type reloader interface {
+	Reload(config.AppConfig)
+}
+
+type statusReporter interface {
+	Ping() error
+	Status(id string) error
+}
+
 type Manager struct {
 	q  pubsub.Queue
 	hs []EventHandler
@@ -26,7 +45,8 @@ func NewHandlersManager(q pubsub.Queue, hs ...EventHandler) *Manager {
 
 func (hm *Manager) StartHandlers(ctx context.Context) {
 	for _, v := range hm.hs {
-		v.ExecuteHandlers(ctx)
+		metrics.Paused(v.ID(), false)
+		v.ExecuteHandlers(withHandlerID(ctx, v.ID()))
 	}
 
 	hm.StopNotifications(ctx)
@@ -52,6 +72,7 @@ func (hm *Manager) StopNotifications(ctx context.Context) {
 				for i := range hm.hs {
 					if m.Handler == hm.hs[i].ID() || m.Handler == "" {
 						hm.hs[i].StopNotifications()
+						metrics.Paused(hm.hs[i].ID(), true)
 					}
 				}
 			}
@@ -61,7 +82,155 @@ func (hm *Manager) StopNotifications(ctx context.Context) {
 	}()
 }
 
+// Reload hands the configuration to the handlers supporting it.
+func (hm *Manager) Reload(cfg config.AppConfig) {
+	for _, h := range hm.hs {
+		if r, ok := h.(reloader); ok {
+			r.Reload(cfg)
+		}
+	}
+}
+
+// Status reports why the queue and every handler aren't ready, nil when they
+// are. They are only checked when the queue is a Monitor.
+func (hm *Manager) Status() map[string]error {
+	r, ok := hm.q.(statusReporter)
+	if !ok {
+		return map[string]error{}
+	}
+
+	status := map[string]error{"queue": r.Ping()}
+
+	for _, h := range hm.hs {
+		status[h.ID()] = r.Status(h.ID())
+	}
+
+	return status
+}
+
 func SendError(q pubsub.Queue, err error) {
 	eb, _ := easyjson.Marshal(pubsub.ErrorEvent{Err: err.Error()})
 	_ = q.Publish(pubsub.ErrorTopic.String(), message.NewMessage(watermill.NewUUID(), eb))
 }
+
+// Delivered records the outcome of the handler delivering msg: it's counted,
+// logged, reported back to the admin who sent it along with the URLs of the
+// published posts and, when it failed, published to the error topic.
+func Delivered(q pubsub.Queue, log *logrus.Logger, handler string, msg *message.Message, err error, urls ...string) {
+	metrics.Delivered(handler, err)
+
+	r := pubsub.ResultEvent{Handler: handler}
+	if err != nil {
+		r.Err = err.Error()
+	}
+
+	for _, u := range urls {
+		if u != "" {
+			r.URLs = append(r.URLs, u)
+		}
+	}
+
+	publishResult(q, msg, r)
+
+	if err != nil {
+		SendMessageError(q, msg, err)
+
+		return
+	}
+
+	log.WithContext(msg.Context()).WithField("handler", handler).Info("message delivered")
+}
+
+// Rewrite is how a destination changes posts before publishing them: the
+// mentions and hashtags rewritten, the UTM parameters added to links and the
+// template applied.
+type Rewrite struct {
+	Template string
+	Rules    []translate.Rules
+	UTM      map[string]string
+}
+
+// Render rewrites the text of msg for the destination, the template getting
+// the author and publication date msg carries.
+func Render(msg *message.Message, text string, photo bool, rw Rewrite) (string, error) {
+	text = links.Tag(translate.Apply(text, rw.Rules...), rw.UTM)
+
+	date, err := time.Parse(time.RFC3339Nano, msg.Metadata.Get(pubsub.PublishedAtKey))
+	if err != nil {
+		date = time.Now().UTC()
+	}
+
+	return render.Text(rw.Template, render.NewPost(text, msg.Metadata.Get(pubsub.AuthorKey), date, photo))
+}
+
+// Skipped reports back to the admin who sent msg that the handler didn't
+// deliver it because its notifications are stopped.
+func Skipped(q pubsub.Queue, handler string, msg *message.Message) {
+	publishResult(q, msg, pubsub.ResultEvent{Handler: handler, Skipped: true, Reason: "notifications stopped"})
+}
+
+// addressedTo reports whether msg can be delivered by the handler, messages
+// sent by users only allowed to publish to some destinations list them.
+func addressedTo(msg *message.Message, handler string) bool {
+	destinations := msg.Metadata.Get(pubsub.DestinationsKey)
+	if destinations == "" {
+		return true
+	}
+
+	for _, d := range strings.Split(destinations, ",") {
+		if d == handler {
+			return true
+		}
+	}
+
+	return false
+}
+
+func publishResult(q pubsub.Queue, msg *message.Message, r pubsub.ResultEvent) {
+	if msg.Metadata.Get(pubsub.SenderIDKey) == "" {
+		return
+	}
+
+	r.MessageUUID = msg.UUID
+	rb, _ := easyjson.Marshal(r)
+
+	rm := message.NewMessage(watermill.NewUUID(), rb)
+	rm.SetContext(msg.Context())
+
+	for _, k := range []string{pubsub.CorrelationIDKey, pubsub.SenderIDKey, pubsub.MessageIDKey, pubsub.LanguageKey} {
+		rm.Metadata.Set(k, msg.Metadata.Get(k))
+	}
+
+	_ = q.Publish(pubsub.ResultTopic.String(), rm)
+}
+
+// SendMessageError publishes err along with the details of the message being
+// processed when it happened, so it can be traced back to its origin.
+func SendMessageError(q pubsub.Queue, msg *message.Message, err error) {
+	ctx := msg.Context()
+	e := pubsub.ErrorEvent{
+		Err:           err.Error(),
+		MessageUUID:   msg.UUID,
+		CorrelationID: msg.Metadata.Get(pubsub.CorrelationIDKey),
+		SenderID:      msg.Metadata.Get(pubsub.SenderIDKey),
+		Language:      msg.Metadata.Get(pubsub.LanguageKey),
+	}
+
+	e.Handler, _ = ctx.Value(handlerIDKey{}).(string)
+	e.Topic, _ = ctx.Value(topicKey{}).(string)
+
+	if t, err := time.Parse(time.RFC3339Nano, msg.Metadata.Get(pubsub.PublishedAtKey)); err == nil {
+		e.PublishedAt = &t
+	}
+
+	eb, _ := easyjson.Marshal(e)
+
+	em := message.NewMessage(watermill.NewUUID(), eb)
+	em.SetContext(ctx)
+
+	if e.CorrelationID != "" {
+		em.Metadata.Set(pubsub.CorrelationIDKey, e.CorrelationID)
+	}
+
+	_ = q.Publish(pubsub.ErrorTopic.String(), em)
+}
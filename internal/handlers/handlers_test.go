@@ -0,0 +1,329 @@
+package handlers_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/metrics"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/translate"
+	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	"github.com/mailru/easyjson"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type subscribeError struct{}
+
+func (m subscribeError) Error() string {
+	return "error subscribing"
+}
+
+type textHandler struct {
+	q        pubsub.Queue
+	received chan *message.Message
+}
+
+func (h *textHandler) ID() string {
+	return "text"
+}
+
+func (h *textHandler) ExecuteHandlers(ctx context.Context) {
+	messages, err := h.q.Subscribe(ctx, pubsub.TextTopic.String())
+	if err != nil {
+		return
+	}
+
+	go func() {
+		for msg := range messages {
+			h.received <- msg
+		}
+	}()
+}
+
+func (h *textHandler) StopNotifications() {}
+
+func TestManager_Status(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it should report every handler ready", func(t *testing.T) {
+		q := handlers.NewMonitor(gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), time.Minute)
+		h := &textHandler{q: q, received: make(chan *message.Message, 1)}
+
+		hm := handlers.NewHandlersManager(q, h)
+		hm.StartHandlers(ctx)
+
+		msg := message.NewMessage(watermill.NewUUID(), []byte("{\"text\":\"testing\"}"))
+		require.NoError(t, q.Publish(pubsub.TextTopic.String(), msg))
+		(<-h.received).Ack()
+
+		require.Equal(t, map[string]error{"queue": nil, "text": nil}, hm.Status())
+	})
+
+	t.Run("it should report handler failing to subscribe", func(t *testing.T) {
+		mockedQueue := new(mq.Queue)
+		q := handlers.NewMonitor(mockedQueue, time.Minute)
+		h := &textHandler{q: q}
+
+		mockedQueue.On("Subscribe", mock.Anything, pubsub.TextTopic.String()).Once().Return(nil, subscribeError{})
+		mockedQueue.On("Subscribe", ctx, pubsub.CommandTopic.String()).Once().Return(nil, subscribeError{})
+
+		hm := handlers.NewHandlersManager(q, h)
+		hm.StartHandlers(ctx)
+
+		status := hm.Status()
+		require.NoError(t, status["queue"])
+		require.EqualError(t, status["text"], "subscription to TextTopic failed: error subscribing")
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should report handler stuck processing a message", func(t *testing.T) {
+		q := handlers.NewMonitor(gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), time.Millisecond)
+		h := &textHandler{q: q, received: make(chan *message.Message, 1)}
+
+		hm := handlers.NewHandlersManager(q, h)
+		hm.StartHandlers(ctx)
+
+		go func() {
+			_ = q.Publish(pubsub.TextTopic.String(), message.NewMessage(watermill.NewUUID(), nil))
+		}()
+
+		msg := <-h.received
+
+		require.Eventually(t, func() bool {
+			err := hm.Status()["text"]
+
+			return err != nil && strings.HasPrefix(err.Error(), "stuck processing TextTopic message since")
+		}, time.Second, time.Millisecond)
+
+		msg.Ack()
+
+		require.Eventually(t, func() bool {
+			return hm.Status()["text"] == nil
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("it should report closed queue", func(t *testing.T) {
+		q := handlers.NewMonitor(gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), time.Minute)
+		h := &textHandler{q: q}
+
+		hm := handlers.NewHandlersManager(q, h)
+		hm.StartHandlers(ctx)
+
+		require.NoError(t, q.Close())
+
+		require.Eventually(t, func() bool {
+			status := hm.Status()
+
+			return status["queue"] != nil && status["queue"].Error() == "queue closed" &&
+				status["text"] != nil && status["text"].Error() == "subscription to TextTopic closed"
+		}, time.Second, time.Millisecond)
+	})
+}
+
+func TestMonitor_Publish(t *testing.T) {
+	q := handlers.NewMonitor(gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), time.Minute)
+	published := metrics.EventsPublished.WithLabelValues(pubsub.PhotoTopic.String())
+	before := testutil.ToFloat64(published)
+
+	require.NoError(t, q.Publish(
+		pubsub.PhotoTopic.String(),
+		message.NewMessage(watermill.NewUUID(), nil),
+		message.NewMessage(watermill.NewUUID(), nil),
+	))
+
+	require.Equal(t, before+2, testutil.ToFloat64(published))
+}
+
+func TestMonitor_Tracing(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	defer func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	q := handlers.NewMonitor(gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), time.Minute)
+	h := &textHandler{q: q, received: make(chan *message.Message, 1)}
+
+	hm := handlers.NewHandlersManager(q, h)
+	hm.StartHandlers(ctx)
+
+	parent, span := tp.Tracer("testing").Start(ctx, "telegram update")
+	msg := message.NewMessage(watermill.NewUUID(), nil)
+	msg.SetContext(parent)
+
+	require.NoError(t, q.Publish(pubsub.TextTopic.String(), msg))
+
+	received := <-h.received
+	received.Ack()
+	span.End()
+
+	require.Equal(t, span.SpanContext().TraceID(), trace.SpanContextFromContext(received.Context()).TraceID())
+	require.Eventually(t, func() bool {
+		for _, s := range recorder.Ended() {
+			if s.Name() == "process TextTopic" {
+				return s.Parent().TraceID() == span.SpanContext().TraceID()
+			}
+		}
+
+		return false
+	}, time.Second, time.Millisecond)
+}
+
+func TestMonitor_CorrelationID(t *testing.T) {
+	ctx := context.Background()
+	q := handlers.NewMonitor(gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), time.Minute)
+	h := &textHandler{q: q, received: make(chan *message.Message, 1)}
+
+	hm := handlers.NewHandlersManager(q, h)
+	hm.StartHandlers(ctx)
+
+	msg := message.NewMessage(watermill.NewUUID(), nil)
+	msg.SetContext(logging.WithCorrelationID(ctx, "update-1234"))
+
+	require.NoError(t, q.Publish(pubsub.TextTopic.String(), msg))
+
+	received := <-h.received
+	defer received.Ack()
+
+	t.Run("it should carry correlation id to the handler", func(t *testing.T) {
+		require.Equal(t, "update-1234", received.Metadata.Get(pubsub.CorrelationIDKey))
+		require.Equal(t, logrus.Fields{
+			"handler":        "text",
+			"topic":          pubsub.TextTopic.String(),
+			"message_uuid":   msg.UUID,
+			"correlation_id": "update-1234",
+		}, logging.Fields(received.Context()))
+	})
+
+	t.Run("it should publish error with message details", func(t *testing.T) {
+		mockedQueue := new(mq.Queue)
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			var e pubsub.ErrorEvent
+			if err := easyjson.Unmarshal(m.Payload, &e); err != nil {
+				return false
+			}
+
+			return e.Err == "error subscribing" &&
+				e.Handler == "text" &&
+				e.Topic == pubsub.TextTopic.String() &&
+				e.MessageUUID == msg.UUID &&
+				e.CorrelationID == "update-1234" &&
+				e.PublishedAt != nil &&
+				m.Metadata.Get(pubsub.CorrelationIDKey) == "update-1234"
+		})).Once().
+			Return(nil)
+
+		handlers.SendMessageError(mockedQueue, received, subscribeError{})
+
+		mockedQueue.AssertExpectations(t)
+	})
+}
+
+func TestMonitor_Destinations(t *testing.T) {
+	ctx := context.Background()
+	q := handlers.NewMonitor(gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), time.Minute)
+	h := &textHandler{q: q, received: make(chan *message.Message, 1)}
+
+	results, err := q.Subscribe(ctx, pubsub.ResultTopic.String())
+	require.NoError(t, err)
+
+	hm := handlers.NewHandlersManager(q, h)
+	hm.StartHandlers(ctx)
+
+	t.Run("it should skip messages not addressed to the handler", func(t *testing.T) {
+		msg := message.NewMessage(watermill.NewUUID(), nil)
+		msg.Metadata.Set(pubsub.SenderIDKey, "1234")
+		msg.Metadata.Set(pubsub.DestinationsKey, "telegram,feed")
+
+		require.NoError(t, q.Publish(pubsub.TextTopic.String(), msg))
+
+		result := <-results
+		result.Ack()
+
+		var r pubsub.ResultEvent
+		require.NoError(t, easyjson.Unmarshal(result.Payload, &r))
+		require.Equal(t, pubsub.ResultEvent{
+			MessageUUID: msg.UUID,
+			Handler:     "text",
+			Skipped:     true,
+			Reason:      "not allowed to sender",
+		}, r)
+		require.Empty(t, h.received)
+	})
+
+	t.Run("it should deliver messages addressed to the handler", func(t *testing.T) {
+		msg := message.NewMessage(watermill.NewUUID(), nil)
+		msg.Metadata.Set(pubsub.DestinationsKey, "telegram,text")
+
+		require.NoError(t, q.Publish(pubsub.TextTopic.String(), msg))
+
+		received := <-h.received
+		received.Ack()
+
+		require.Equal(t, msg.UUID, received.UUID)
+	})
+}
+
+func TestRender(t *testing.T) {
+	msg := message.NewMessage(watermill.NewUUID(), nil)
+	msg.Metadata.Set(pubsub.AuthorKey, "admin")
+	msg.Metadata.Set(pubsub.PublishedAtKey, "2024-03-01T10:00:00Z")
+
+	t.Run("it should render the post with the message metadata", func(t *testing.T) {
+		text, err := handlers.Render(msg, "testing", false, handlers.Rewrite{
+			Template: `{{.Text}} by {{.Author}} on {{.Date.Format "2006-01-02"}}`,
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, "testing by admin on 2024-03-01", text)
+	})
+
+	t.Run("it should rewrite mentions and hashtags before rendering", func(t *testing.T) {
+		text, err := handlers.Render(msg, "@acme_tg #tg", false, handlers.Rewrite{
+			Template: "{{.Text}} {{join .Hashtags \" \"}}",
+			Rules:    []translate.Rules{{"@acme_tg": "@AcmeCorp", "#tg": "#tw"}, {"#tg": "#twitter"}},
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, "@AcmeCorp #twitter twitter", text)
+	})
+
+	t.Run("it should add the UTM parameters to the links", func(t *testing.T) {
+		text, err := handlers.Render(msg, "read https://example.com/post?utm_source=old", false, handlers.Rewrite{
+			UTM: map[string]string{"utm_source": "telegram", "utm_medium": "social"},
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, "read https://example.com/post?utm_medium=social&utm_source=telegram", text)
+	})
+
+	t.Run("it should leave the text as it is without template", func(t *testing.T) {
+		text, err := handlers.Render(msg, "testing", true, handlers.Rewrite{})
+
+		require.NoError(t, err)
+		require.Equal(t, "testing", text)
+	})
+}
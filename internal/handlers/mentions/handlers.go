@@ -0,0 +1,184 @@
+package handlersmentions
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/i18n"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/tracing"
+	"github.com/javiyt/tweetgram/internal/twitter"
+	"github.com/sirupsen/logrus"
+)
+
+const lastIDKey = "mentions/last_id"
+
+type MentionsClient interface {
+	Mentions(ctx context.Context, sinceID int64) ([]twitter.Tweet, error)
+}
+
+type Mentions struct {
+	bot          bot.TelegramBot
+	cfg          config.Holder
+	q            pubsub.Queue
+	s            storage.Store
+	mc           MentionsClient
+	mu           sync.Mutex
+	log          *logrus.Logger
+	shouldNotify bool
+}
+
+type Option func(m *Mentions)
+
+func WithTelegramBot(tb bot.TelegramBot) Option {
+	return func(m *Mentions) {
+		m.bot = tb
+	}
+}
+
+func WithAppConfig(cfg config.AppConfig) Option {
+	return func(m *Mentions) {
+		m.cfg.Set(cfg)
+	}
+}
+
+func WithQueue(q pubsub.Queue) Option {
+	return func(m *Mentions) {
+		m.q = q
+	}
+}
+
+func WithStore(s storage.Store) Option {
+	return func(m *Mentions) {
+		m.s = s
+	}
+}
+
+func WithMentionsClient(mc MentionsClient) Option {
+	return func(m *Mentions) {
+		m.mc = mc
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(m *Mentions) {
+		m.log = log
+	}
+}
+
+func NewMentions(options ...Option) *Mentions {
+	m := &Mentions{log: logging.Discard(), shouldNotify: true}
+
+	for _, o := range options {
+		o(m)
+	}
+
+	return m
+}
+
+func (m *Mentions) ID() string {
+	return "mentions"
+}
+
+func (m *Mentions) ExecuteHandlers(ctx context.Context) {
+	if m.cfg.Get().MentionsPollInterval > 0 {
+		go m.schedulePoll(ctx)
+	}
+}
+
+// Reload swaps the configuration, so mentions are sent to the new admins. The
+// poll interval only changes after a restart.
+func (m *Mentions) Reload(cfg config.AppConfig) {
+	m.cfg.Set(cfg)
+}
+
+func (m *Mentions) StopNotifications() {
+	m.shouldNotify = false
+}
+
+// Poll fetches the mentions received since the last poll and sends them to
+// every admin with a button to reply from Telegram. The first poll only
+// records the newest mention, so old mentions aren't forwarded.
+func (m *Mentions) Poll(ctx context.Context) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ctx, span := tracing.Tracer().Start(logging.WithCorrelationID(ctx, watermill.NewUUID()), "poll mentions")
+	defer func() { tracing.End(span, err) }()
+
+	var lastID int64
+	if err := m.s.Load(lastIDKey, &lastID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	mentions, err := m.mc.Mentions(ctx, lastID)
+	if err != nil {
+		return err
+	}
+
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].ID < mentions[j].ID })
+
+	if lastID > 0 {
+		for _, t := range mentions {
+			m.forward(ctx, t)
+
+			m.log.WithContext(ctx).WithField("tweet_id", t.ID).Info("mention forwarded to admins")
+		}
+	}
+
+	return m.s.Save(lastIDKey, mentions[len(mentions)-1].ID)
+}
+
+// forward sends the mention, in the default language, to every admin,
+// including the ones added through /addadmin. Admins who can't be reached,
+// like the ones who blocked the bot, are logged and skipped, so the rest don't
+// get the mention again on the next poll.
+func (m *Mentions) forward(ctx context.Context, t twitter.Tweet) {
+	cfg, err := bot.StaffConfig(m.cfg.Get(), m.s)
+	if err != nil {
+		m.log.WithContext(ctx).WithError(err).Warn("error loading admins")
+	}
+
+	lang := i18n.Language("", cfg.DefaultLanguage)
+	text := i18n.T(lang, "mentions.text", t.Author, t.Text, t.URL)
+
+	for _, admin := range cfg.Admins {
+		if err := m.bot.Send(ctx, strconv.Itoa(admin), text, bot.ReplyKeyboard(lang, t.ID, t.Author)); err != nil {
+			m.log.WithContext(ctx).WithError(err).WithField("user_id", admin).Warn("error forwarding mention")
+		}
+	}
+}
+
+func (m *Mentions) schedulePoll(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Get().MentionsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !m.shouldNotify {
+				continue
+			}
+
+			if err := m.Poll(ctx); err != nil {
+				handlers.SendError(m.q, err)
+			}
+		}
+	}
+}
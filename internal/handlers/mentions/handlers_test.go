@@ -0,0 +1,201 @@
+package handlersmentions_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
+	hm "github.com/javiyt/tweetgram/internal/handlers/mentions"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/twitter"
+	mb "github.com/javiyt/tweetgram/mocks/bot"
+	mm "github.com/javiyt/tweetgram/mocks/handlers/mentions"
+	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mentionsError struct{}
+
+func (m mentionsError) Error() string {
+	return "error getting mentions"
+}
+
+type messageNotSendError struct{}
+
+func (m messageNotSendError) Error() string {
+	return "couldn't send message to telegram"
+}
+
+func TestMentions_ID(t *testing.T) {
+	require.Equal(t, "mentions", hm.NewMentions().ID())
+}
+
+func TestMentions_Poll(t *testing.T) {
+	cfg := config.AppConfig{Admins: []int{1234, 5678}}
+	mention := twitter.Tweet{
+		ID:     11,
+		Text:   "@tweetgram hello",
+		URL:    "https://twitter.com/someone/status/11",
+		Author: "someone",
+	}
+	text := "@someone mentioned you:\n\n@tweetgram hello\n\nhttps://twitter.com/someone/status/11"
+
+	t.Run("it should fail when mentions can't be fetched", func(t *testing.T) {
+		mh, mockedBot, mockedClient := generateHandlerAndMocks(t, cfg)
+
+		mockedClient.On("Mentions", mock.Anything, int64(0)).Once().Return(nil, mentionsError{})
+
+		require.EqualError(t, mh.Poll(context.Background()), "error getting mentions")
+		mockedBot.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+
+	t.Run("it should only record newest mention on first poll", func(t *testing.T) {
+		mh, mockedBot, mockedClient := generateHandlerAndMocks(t, cfg)
+
+		mockedClient.On("Mentions", mock.Anything, int64(0)).Once().Return([]twitter.Tweet{{ID: 10}, {ID: 9}}, nil)
+		mockedClient.On("Mentions", mock.Anything, int64(10)).Once().Return(nil, nil)
+
+		require.NoError(t, mh.Poll(context.Background()))
+		require.NoError(t, mh.Poll(context.Background()))
+		mockedBot.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+
+	t.Run("it should keep forwarding to the other admins when one can't be reached", func(t *testing.T) {
+		mh, mockedBot, mockedClient := generateHandlerAndMocks(t, cfg)
+
+		mockedClient.On("Mentions", mock.Anything, int64(0)).Once().Return([]twitter.Tweet{{ID: 10}}, nil)
+		mockedClient.On("Mentions", mock.Anything, int64(10)).Once().Return([]twitter.Tweet{mention}, nil)
+		mockedClient.On("Mentions", mock.Anything, int64(11)).Once().Return(nil, nil)
+		mockedBot.On("Send", mock.Anything, "1234", text, bot.ReplyKeyboard("en", 11, "someone")).
+			Once().Return(messageNotSendError{})
+		mockedBot.On("Send", mock.Anything, "5678", text, bot.ReplyKeyboard("en", 11, "someone")).Once().Return(nil)
+
+		require.NoError(t, mh.Poll(context.Background()))
+		require.NoError(t, mh.Poll(context.Background()))
+		require.NoError(t, mh.Poll(context.Background()))
+		mockedBot.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+
+	t.Run("it should send new mentions in the default language", func(t *testing.T) {
+		spanish := cfg
+		spanish.DefaultLanguage = "es"
+		mh, mockedBot, mockedClient := generateHandlerAndMocks(t, spanish)
+		text := "@someone te ha mencionado:\n\n@tweetgram hello\n\nhttps://twitter.com/someone/status/11"
+		keyboard := bot.TelegramKeyboard{{{Unique: bot.ReplyButton, Text: "Responder", Data: "11|someone"}}}
+
+		mockedClient.On("Mentions", mock.Anything, int64(0)).Once().Return([]twitter.Tweet{{ID: 10}}, nil)
+		mockedClient.On("Mentions", mock.Anything, int64(10)).Once().Return([]twitter.Tweet{mention}, nil)
+		mockedBot.On("Send", mock.Anything, "1234", text, keyboard).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, "5678", text, keyboard).Once().Return(nil)
+
+		require.NoError(t, mh.Poll(context.Background()))
+		require.NoError(t, mh.Poll(context.Background()))
+		mockedBot.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+
+	t.Run("it should send new mentions to every admin with reply button", func(t *testing.T) {
+		mh, mockedBot, mockedClient := generateHandlerAndMocks(t, cfg)
+
+		mockedClient.On("Mentions", mock.Anything, int64(0)).Once().Return([]twitter.Tweet{{ID: 10}}, nil)
+		mockedClient.On("Mentions", mock.Anything, int64(10)).Once().Return([]twitter.Tweet{mention}, nil)
+		mockedClient.On("Mentions", mock.Anything, int64(11)).Once().Return(nil, nil)
+		mockedBot.On("Send", mock.Anything, "1234", text, bot.ReplyKeyboard("en", 11, "someone")).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, "5678", text, bot.ReplyKeyboard("en", 11, "someone")).Once().Return(nil)
+
+		require.NoError(t, mh.Poll(context.Background()))
+		require.NoError(t, mh.Poll(context.Background()))
+		require.NoError(t, mh.Poll(context.Background()))
+		mockedBot.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+}
+
+func TestMentions_PollAddedAdmins(t *testing.T) {
+	mockedBot := new(mb.TelegramBot)
+	mockedClient := new(mm.MentionsClient)
+	store := storage.NewFileStore(t.TempDir())
+	mention := twitter.Tweet{ID: 11, Text: "hello", URL: "https://twitter.com/someone/status/11", Author: "someone"}
+	text := "@someone mentioned you:\n\nhello\n\nhttps://twitter.com/someone/status/11"
+
+	require.NoError(t, store.Save("bot/admins", []int{5678}))
+	require.NoError(t, store.Save("mentions/last_id", int64(10)))
+
+	mh := hm.NewMentions(
+		hm.WithAppConfig(config.AppConfig{Admins: []int{1234}}),
+		hm.WithTelegramBot(mockedBot),
+		hm.WithStore(store),
+		hm.WithMentionsClient(mockedClient),
+	)
+
+	t.Run("it should send new mentions to the admins added through /addadmin", func(t *testing.T) {
+		mockedClient.On("Mentions", mock.Anything, int64(10)).Once().Return([]twitter.Tweet{mention}, nil)
+		mockedBot.On("Send", mock.Anything, "1234", text, bot.ReplyKeyboard("en", 11, "someone")).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, "5678", text, bot.ReplyKeyboard("en", 11, "someone")).Once().Return(nil)
+
+		require.NoError(t, mh.Poll(context.Background()))
+		mockedBot.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+}
+
+func TestMentions_ExecuteHandlers(t *testing.T) {
+	t.Run("it should send error when scheduled poll fails", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockedQueue := new(mq.Queue)
+		mockedClient := new(mm.MentionsClient)
+		mh := hm.NewMentions(
+			hm.WithAppConfig(config.AppConfig{MentionsPollInterval: time.Millisecond}),
+			hm.WithQueue(mockedQueue),
+			hm.WithStore(storage.NewFileStore(t.TempDir())),
+			hm.WithMentionsClient(mockedClient),
+		)
+
+		called := make(chan struct{}, 1)
+
+		mockedClient.On("Mentions", mock.Anything, int64(0)).Return(nil, mentionsError{})
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return string(m.Payload) == "{\"error\":\"error getting mentions\"}"
+		})).Run(func(mock.Arguments) {
+			select {
+			case called <- struct{}{}:
+			default:
+			}
+		}).Return(nil)
+
+		mh.ExecuteHandlers(ctx)
+
+		select {
+		case <-called:
+		case <-time.After(time.Second):
+			require.Fail(t, "mentions weren't polled")
+		}
+	})
+}
+
+func generateHandlerAndMocks(
+	t *testing.T,
+	cfg config.AppConfig,
+) (*hm.Mentions, *mb.TelegramBot, *mm.MentionsClient) {
+	mockedBot := new(mb.TelegramBot)
+	mockedClient := new(mm.MentionsClient)
+
+	mh := hm.NewMentions(
+		hm.WithAppConfig(cfg),
+		hm.WithTelegramBot(mockedBot),
+		hm.WithStore(storage.NewFileStore(t.TempDir())),
+		hm.WithMentionsClient(mockedClient),
+	)
+
+	return mh, mockedBot, mockedClient
+}
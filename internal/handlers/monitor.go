@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/metrics"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	errNacked      = errors.New("message nacked")
+	errQueueClosed = errors.New("queue closed")
+)
+
+type (
+	handlerIDKey struct{}
+	topicKey     struct{}
+)
+
+// Monitor wraps a queue keeping track of the subscriptions made by every
+// handler, so readiness checks can tell when one of them failed or got stuck
+// processing a message.
+type Monitor struct {
+	q          pubsub.Queue
+	stuckAfter time.Duration
+	mu         sync.Mutex
+	subs       map[string][]*subscription
+	closed     bool
+}
+
+type subscription struct {
+	handler   string
+	topic     string
+	err       error
+	closed    bool
+	busySince time.Time
+}
+
+func NewMonitor(q pubsub.Queue, stuckAfter time.Duration) *Monitor {
+	return &Monitor{q: q, stuckAfter: stuckAfter, subs: make(map[string][]*subscription)}
+}
+
+func (m *Monitor) Publish(topic string, messages ...*message.Message) error {
+	for _, msg := range messages {
+		setMetadata(msg)
+
+		ctx, span := tracing.Tracer().Start(
+			msg.Context(),
+			"publish "+topic,
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(attribute.String("messaging.message.id", msg.UUID)),
+		)
+		tracing.Inject(ctx, msg)
+		span.End()
+	}
+
+	if err := m.q.Publish(topic, messages...); err != nil {
+		return err
+	}
+
+	metrics.EventsPublished.WithLabelValues(topic).Add(float64(len(messages)))
+
+	return nil
+}
+
+func (m *Monitor) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	messages, err := m.q.Subscribe(ctx, topic)
+
+	id, ok := ctx.Value(handlerIDKey{}).(string)
+	if !ok {
+		return messages, err
+	}
+
+	s := &subscription{handler: id, topic: topic, err: err}
+
+	m.mu.Lock()
+	m.subs[id] = append(m.subs[id], s)
+	m.mu.Unlock()
+
+	if err != nil {
+		return messages, err
+	}
+
+	out := make(chan *message.Message)
+	go m.forward(s, messages, out)
+
+	return out, nil
+}
+
+func (m *Monitor) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+
+	return m.q.Close()
+}
+
+// Ping returns why the queue can't take messages, so readiness checks don't
+// need to publish one.
+func (m *Monitor) Ping() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errQueueClosed
+	}
+
+	return nil
+}
+
+// Status returns why the given handler isn't ready, or nil when all its
+// subscriptions are working.
+func (m *Monitor) Status(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.subs[id] {
+		switch {
+		case s.err != nil:
+			return fmt.Errorf("subscription to %s failed: %w", s.topic, s.err)
+		case s.closed:
+			return fmt.Errorf("subscription to %s closed", s.topic)
+		case !s.busySince.IsZero() && time.Since(s.busySince) > m.stuckAfter:
+			return fmt.Errorf("stuck processing %s message since %s", s.topic, s.busySince.Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
+
+func (m *Monitor) forward(s *subscription, in <-chan *message.Message, out chan<- *message.Message) {
+	defer close(out)
+
+	for msg := range in {
+		if !addressedTo(msg, s.handler) {
+			publishResult(m, msg, pubsub.ResultEvent{Handler: s.handler, Skipped: true, Reason: "not allowed to sender"})
+			msg.Ack()
+
+			continue
+		}
+
+		m.setBusySince(s, time.Now())
+
+		ctx, span := tracing.Tracer().Start(
+			m.messageContext(s, msg),
+			"process "+s.topic,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.message.id", msg.UUID),
+				attribute.String("handler", s.handler),
+			),
+		)
+		msg.SetContext(ctx)
+
+		out <- msg
+
+		select {
+		case <-msg.Acked():
+			span.End()
+		case <-msg.Nacked():
+			tracing.End(span, errNacked)
+		}
+
+		m.setBusySince(s, time.Time{})
+	}
+
+	m.mu.Lock()
+	s.closed = true
+	m.mu.Unlock()
+}
+
+// messageContext returns the context handlers get with msg, carrying its trace,
+// the subscription it was received from and the fields to log about it.
+func (m *Monitor) messageContext(s *subscription, msg *message.Message) context.Context {
+	ctx := context.WithValue(withHandlerID(tracing.Extract(msg), s.handler), topicKey{}, s.topic)
+
+	return logging.WithFields(ctx, logrus.Fields{
+		"handler":                  s.handler,
+		"topic":                    s.topic,
+		"message_uuid":             msg.UUID,
+		logging.CorrelationIDField: msg.Metadata.Get(pubsub.CorrelationIDKey),
+	})
+}
+
+func (m *Monitor) setBusySince(s *subscription, t time.Time) {
+	m.mu.Lock()
+	s.busySince = t
+	m.mu.Unlock()
+}
+
+// setMetadata stores in msg the correlation ID of the update it comes from, or
+// its own UUID when it wasn't originated by one, and when it was published.
+func setMetadata(msg *message.Message) {
+	if msg.Metadata == nil {
+		msg.Metadata = make(message.Metadata)
+	}
+
+	if msg.Metadata.Get(pubsub.CorrelationIDKey) == "" {
+		id := logging.CorrelationID(msg.Context())
+		if id == "" {
+			id = msg.UUID
+		}
+
+		msg.Metadata.Set(pubsub.CorrelationIDKey, id)
+	}
+
+	if msg.Metadata.Get(pubsub.PublishedAtKey) == "" {
+		msg.Metadata.Set(pubsub.PublishedAtKey, time.Now().UTC().Format(time.RFC3339Nano))
+	}
+}
+
+func withHandlerID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, handlerIDKey{}, id)
+}
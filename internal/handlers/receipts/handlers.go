@@ -0,0 +1,279 @@
+package handlersreceipts
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/audit"
+	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/i18n"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/mailru/easyjson"
+	"github.com/sirupsen/logrus"
+)
+
+type Receipts struct {
+	bot          bot.TelegramBot
+	cfg          config.Holder
+	q            pubsub.Queue
+	audit        audit.Log
+	log          *logrus.Logger
+	destinations []string
+	mu           sync.Mutex
+	pending      map[string]*receipt
+	shouldNotify bool
+}
+
+// receipt gathers the results of every destination for a message sent by an
+// admin until all of them have answered or the timeout expires.
+type receipt struct {
+	ctx       context.Context
+	senderID  string
+	language  string
+	messageID int
+	results   map[string]pubsub.ResultEvent
+	timer     *time.Timer
+	sent      bool
+}
+
+type Option func(r *Receipts)
+
+func WithTelegramBot(tb bot.TelegramBot) Option {
+	return func(r *Receipts) {
+		r.bot = tb
+	}
+}
+
+func WithAppConfig(cfg config.AppConfig) Option {
+	return func(r *Receipts) {
+		r.cfg.Set(cfg)
+	}
+}
+
+func WithQueue(q pubsub.Queue) Option {
+	return func(r *Receipts) {
+		r.q = q
+	}
+}
+
+// WithAuditLog sets where the posts every message resulted in are recorded.
+func WithAuditLog(l audit.Log) Option {
+	return func(r *Receipts) {
+		r.audit = l
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(r *Receipts) {
+		r.log = log
+	}
+}
+
+// WithDestinations sets the handlers expected to report a result for every
+// message, in the order they are listed in the receipt.
+func WithDestinations(ids ...string) Option {
+	return func(r *Receipts) {
+		r.destinations = ids
+	}
+}
+
+func NewReceipts(options ...Option) *Receipts {
+	r := &Receipts{
+		log:          logging.Discard(),
+		pending:      make(map[string]*receipt),
+		shouldNotify: true,
+	}
+
+	for _, o := range options {
+		o(r)
+	}
+
+	return r
+}
+
+func (r *Receipts) ID() string {
+	return "receipts"
+}
+
+func (r *Receipts) ExecuteHandlers(ctx context.Context) {
+	messages, err := r.q.Subscribe(ctx, pubsub.ResultTopic.String())
+	if err != nil {
+		handlers.SendError(r.q, err)
+	}
+
+	go func() {
+		for msg := range messages {
+			if !r.shouldNotify {
+				msg.Ack()
+
+				continue
+			}
+
+			var m pubsub.ResultEvent
+			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
+				handlers.SendMessageError(r.q, msg, err)
+				msg.Ack()
+
+				continue
+			}
+
+			r.add(msg.Context(), msg.Metadata, m)
+
+			msg.Ack()
+		}
+	}()
+}
+
+// Reload swaps the configuration, the new timeout applies to the next receipts.
+func (r *Receipts) Reload(cfg config.AppConfig) {
+	r.cfg.Set(cfg)
+}
+
+func (r *Receipts) StopNotifications() {
+	r.shouldNotify = false
+}
+
+func (r *Receipts) add(ctx context.Context, metadata message.Metadata, m pubsub.ResultEvent) {
+	r.mu.Lock()
+
+	rc, ok := r.pending[m.MessageUUID]
+	if !ok {
+		messageID, _ := strconv.Atoi(metadata.Get(pubsub.MessageIDKey))
+		rc = &receipt{
+			ctx:       ctx,
+			senderID:  metadata.Get(pubsub.SenderIDKey),
+			language:  metadata.Get(pubsub.LanguageKey),
+			messageID: messageID,
+			results:   make(map[string]pubsub.ResultEvent),
+		}
+		rc.timer = time.AfterFunc(r.cfg.Get().ReceiptTimeout, func() { r.send(m.MessageUUID) })
+		r.pending[m.MessageUUID] = rc
+	}
+
+	if rc.sent {
+		r.mu.Unlock()
+		r.log.WithContext(ctx).WithField("handler", m.Handler).Debug("result received after sending receipt")
+
+		return
+	}
+
+	rc.results[m.Handler] = m
+	done := r.complete(rc)
+
+	r.mu.Unlock()
+
+	if done {
+		r.send(m.MessageUUID)
+	}
+}
+
+func (r *Receipts) complete(rc *receipt) bool {
+	for _, id := range r.destinations {
+		if _, ok := rc.results[id]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// send replies to the admin's message with the results received so far. The
+// receipt is kept for another timeout so late results don't start a new one.
+func (r *Receipts) send(uuid string) {
+	r.mu.Lock()
+
+	rc, ok := r.pending[uuid]
+	if !ok || rc.sent {
+		r.mu.Unlock()
+
+		return
+	}
+
+	rc.sent = true
+	rc.timer.Stop()
+	rc.timer = time.AfterFunc(r.cfg.Get().ReceiptTimeout, func() {
+		r.mu.Lock()
+		delete(r.pending, uuid)
+		r.mu.Unlock()
+	})
+
+	text := r.text(rc)
+	entry := r.auditEntry(uuid, rc)
+
+	r.mu.Unlock()
+
+	if r.audit != nil {
+		if err := r.audit.Append(entry); err != nil {
+			r.log.WithContext(rc.ctx).WithError(err).Error("error writing audit log")
+		}
+	}
+
+	if err := r.bot.Send(rc.ctx, rc.senderID, text, bot.TelegramReplyTo(rc.messageID)); err != nil {
+		handlers.SendError(r.q, err)
+	}
+}
+
+// auditEntry records the destinations the message was published to and the
+// links to the resulting posts.
+func (r *Receipts) auditEntry(uuid string, rc *receipt) audit.Entry {
+	e := audit.Entry{
+		Time:        time.Now().UTC(),
+		SenderID:    rc.senderID,
+		Action:      "receipt",
+		MessageUUID: uuid,
+		Outcome:     audit.OutcomeOK,
+	}
+
+	var failed []string
+
+	for _, id := range r.destinations {
+		m, ok := rc.results[id]
+
+		switch {
+		case !ok || m.Err != "":
+			failed = append(failed, id)
+		case !m.Skipped:
+			e.Destinations = append(e.Destinations, id)
+			e.Posts = append(e.Posts, m.URLs...)
+		}
+	}
+
+	if len(failed) > 0 {
+		e.Outcome, e.Err = audit.OutcomeFailed, strings.Join(failed, ",")
+	}
+
+	return e
+}
+
+// text lists the result of every destination in the language of the admin's
+// Telegram app.
+func (r *Receipts) text(rc *receipt) string {
+	lang := i18n.Language(rc.language, r.cfg.Get().DefaultLanguage)
+	lines := make([]string, 0, len(r.destinations))
+
+	for _, id := range r.destinations {
+		m, ok := rc.results[id]
+
+		switch {
+		case !ok:
+			lines = append(lines, i18n.T(lang, "receipts.no_response", id))
+		case m.Err != "":
+			lines = append(lines, i18n.T(lang, "receipts.failed", id, m.Err))
+		case m.Skipped && m.Reason != "":
+			lines = append(lines, i18n.T(lang, "receipts.skipped_reason", id, m.Reason))
+		case m.Skipped:
+			lines = append(lines, i18n.T(lang, "receipts.skipped", id))
+		default:
+			lines = append(lines, strings.Join(append([]string{i18n.T(lang, "receipts.published", id)}, m.URLs...), "\n"))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
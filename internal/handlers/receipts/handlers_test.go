@@ -0,0 +1,210 @@
+package handlersreceipts_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/audit"
+	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
+	hr "github.com/javiyt/tweetgram/internal/handlers/receipts"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	ma "github.com/javiyt/tweetgram/mocks/audit"
+	mb "github.com/javiyt/tweetgram/mocks/bot"
+	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type gettingChannelError struct{}
+
+func (m gettingChannelError) Error() string {
+	return "error getting channel error"
+}
+
+func TestReceipts_ID(t *testing.T) {
+	require.Equal(t, "receipts", hr.NewReceipts().ID())
+}
+
+func TestReceipts_ExecuteHandlers(t *testing.T) {
+	ctx := context.Background()
+	mockedQueue := new(mq.Queue)
+	rh := hr.NewReceipts(hr.WithQueue(mockedQueue))
+
+	mockedQueue.On("Subscribe", ctx, pubsub.ResultTopic.String()).
+		Once().
+		Return(nil, gettingChannelError{})
+	mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+		return string(m.Payload) == "{\"error\":\"error getting channel error\"}"
+	})).Once().
+		Return(nil)
+
+	rh.ExecuteHandlers(ctx)
+
+	mockedQueue.AssertExpectations(t)
+}
+
+func TestReceipts_ExecuteHandlersResult(t *testing.T) {
+	ctx := context.Background()
+	uuid := watermill.NewUUID()
+
+	t.Run("it should reply once every destination reported a result", func(t *testing.T) {
+		mockedAudit := new(ma.Log)
+		rh, mockedBot, resultChannel := generateHandlerAndMocks(ctx, time.Minute, hr.WithAuditLog(mockedAudit))
+		sent := make(chan struct{})
+
+		mockedAudit.On("Append", mock.MatchedBy(func(e audit.Entry) bool {
+			return e.SenderID == "5678" && e.Action == "receipt" && e.MessageUUID == uuid &&
+				reflect.DeepEqual(e.Destinations, []string{"telegram", "twitter"}) &&
+				reflect.DeepEqual(e.Posts, []string{
+					"https://t.me/c/1234/1",
+					"https://twitter.com/i/web/status/1",
+					"https://twitter.com/i/web/status/2",
+				}) &&
+				e.Outcome == audit.OutcomeFailed && e.Err == "email"
+		})).Once().Return(nil)
+
+		mockedBot.On("Send", mock.Anything, "5678", "telegram: published\nhttps://t.me/c/1234/1\n"+
+			"twitter: published\nhttps://twitter.com/i/web/status/1\nhttps://twitter.com/i/web/status/2\n"+
+			"email: failed, error sending mail\nfeed: skipped, notifications stopped",
+			bot.TelegramReplyTo(42),
+		).Once().Run(func(mock.Arguments) { close(sent) }).Return(nil)
+
+		rh.ExecuteHandlers(ctx)
+		sendResultToChannel(t, resultChannel, pubsub.ResultEvent{
+			MessageUUID: uuid,
+			Handler:     "twitter",
+			URLs:        []string{"https://twitter.com/i/web/status/1", "https://twitter.com/i/web/status/2"},
+		})
+		sendResultToChannel(t, resultChannel, pubsub.ResultEvent{
+			MessageUUID: uuid,
+			Handler:     "telegram",
+			URLs:        []string{"https://t.me/c/1234/1"},
+		})
+		sendResultToChannel(t, resultChannel, pubsub.ResultEvent{
+			MessageUUID: uuid,
+			Handler:     "email",
+			Err:         "error sending mail",
+		})
+
+		mockedBot.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		sendResultToChannel(t, resultChannel, pubsub.ResultEvent{
+			MessageUUID: uuid,
+			Handler:     "feed",
+			Skipped:     true,
+			Reason:      "notifications stopped",
+		})
+
+		requireSent(t, sent)
+		mockedBot.AssertExpectations(t)
+		mockedAudit.AssertExpectations(t)
+	})
+
+	t.Run("it should reply with results received when timeout expires", func(t *testing.T) {
+		rh, mockedBot, resultChannel := generateHandlerAndMocks(ctx, 10*time.Millisecond)
+		sent := make(chan struct{})
+
+		mockedBot.On("Send", mock.Anything, "5678", "telegram: published\ntwitter: no response\n"+
+			"email: no response\nfeed: no response",
+			bot.TelegramReplyTo(42),
+		).Once().Run(func(mock.Arguments) { close(sent) }).Return(nil)
+
+		rh.ExecuteHandlers(ctx)
+		sendResultToChannel(t, resultChannel, pubsub.ResultEvent{MessageUUID: uuid, Handler: "telegram"})
+
+		requireSent(t, sent)
+
+		sendResultToChannel(t, resultChannel, pubsub.ResultEvent{MessageUUID: uuid, Handler: "twitter"})
+
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should reply in the language of the admin", func(t *testing.T) {
+		rh, mockedBot, resultChannel := generateHandlerAndMocks(ctx, 10*time.Millisecond)
+		sent := make(chan struct{})
+
+		mockedBot.On("Send", mock.Anything, "5678", "telegram: publicado\ntwitter: sin respuesta\n"+
+			"email: sin respuesta\nfeed: sin respuesta",
+			bot.TelegramReplyTo(42),
+		).Once().Run(func(mock.Arguments) { close(sent) }).Return(nil)
+
+		rh.ExecuteHandlers(ctx)
+
+		payload, err := pubsub.ResultEvent{MessageUUID: uuid, Handler: "telegram"}.MarshalJSON()
+		require.NoError(t, err)
+
+		msg := message.NewMessage(watermill.NewUUID(), payload)
+		msg.Metadata.Set(pubsub.SenderIDKey, "5678")
+		msg.Metadata.Set(pubsub.MessageIDKey, "42")
+		msg.Metadata.Set(pubsub.LanguageKey, "es-ES")
+		resultChannel <- msg
+
+		requireSent(t, sent)
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should not reply when notifications are stopped", func(t *testing.T) {
+		rh, mockedBot, resultChannel := generateHandlerAndMocks(ctx, 10*time.Millisecond)
+
+		rh.StopNotifications()
+		rh.ExecuteHandlers(ctx)
+		sendResultToChannel(t, resultChannel, pubsub.ResultEvent{MessageUUID: uuid, Handler: "telegram"})
+
+		time.Sleep(20 * time.Millisecond)
+		mockedBot.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func generateHandlerAndMocks(
+	ctx context.Context,
+	timeout time.Duration,
+	options ...hr.Option,
+) (*hr.Receipts, *mb.TelegramBot, chan *message.Message) {
+	mockedBot := new(mb.TelegramBot)
+	mockedQueue := new(mq.Queue)
+	resultChannel := make(chan *message.Message)
+
+	rh := hr.NewReceipts(append([]hr.Option{
+		hr.WithAppConfig(config.AppConfig{ReceiptTimeout: timeout}),
+		hr.WithTelegramBot(mockedBot),
+		hr.WithQueue(mockedQueue),
+		hr.WithDestinations("telegram", "twitter", "email", "feed"),
+	}, options...)...)
+
+	mockedQueue.On("Subscribe", ctx, pubsub.ResultTopic.String()).
+		Once().
+		Return(func(context.Context, string) <-chan *message.Message {
+			return resultChannel
+		}, nil)
+
+	return rh, mockedBot, resultChannel
+}
+
+func sendResultToChannel(t *testing.T, channel chan *message.Message, r pubsub.ResultEvent) {
+	payload, err := r.MarshalJSON()
+	require.NoError(t, err)
+
+	newMessage := message.NewMessage(watermill.NewUUID(), payload)
+	newMessage.Metadata.Set(pubsub.SenderIDKey, "5678")
+	newMessage.Metadata.Set(pubsub.MessageIDKey, "42")
+	channel <- newMessage
+
+	require.Eventually(t, func() bool {
+		<-newMessage.Acked()
+
+		return true
+	}, time.Second, time.Millisecond)
+}
+
+func requireSent(t *testing.T, sent chan struct{}) {
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		require.Fail(t, "receipt not sent")
+	}
+}
@@ -3,18 +3,27 @@ package handlerstelegram
 import (
 	"context"
 	"strconv"
+	"strings"
 
+	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/javiyt/tweetgram/internal/bot"
 	"github.com/javiyt/tweetgram/internal/config"
 	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/logging"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/translate"
 	"github.com/mailru/easyjson"
+	"github.com/sirupsen/logrus"
 )
 
+const captionLength = 1024
+
 type Telegram struct {
 	bot          bot.TelegramBot
-	cfg          config.AppConfig
+	cfg          config.Holder
 	q            pubsub.Queue
+	tr           *translate.Table
+	log          *logrus.Logger
 	shouldNotify bool
 }
 
@@ -28,7 +37,7 @@ func WithTelegramBot(tb bot.TelegramBot) Option {
 
 func WithAppConfig(cfg config.AppConfig) Option {
 	return func(b *Telegram) {
-		b.cfg = cfg
+		b.cfg.Set(cfg)
 	}
 }
 
@@ -38,8 +47,20 @@ func WithQueue(q pubsub.Queue) Option {
 	}
 }
 
+func WithTranslations(tr *translate.Table) Option {
+	return func(b *Telegram) {
+		b.tr = tr
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(b *Telegram) {
+		b.log = log
+	}
+}
+
 func NewTelegram(options ...Option) *Telegram {
-	t := &Telegram{shouldNotify: true}
+	t := &Telegram{log: logging.Discard(), tr: new(translate.Table), shouldNotify: true}
 
 	for _, o := range options {
 		o(t)
@@ -52,9 +73,35 @@ func (t *Telegram) ID() string {
 	return "telegram"
 }
 
+// rewrite is how posts are changed for the broadcast channel with cfg.
+func (t *Telegram) rewrite(cfg config.AppConfig) handlers.Rewrite {
+	return handlers.Rewrite{
+		Template: cfg.Template.Telegram,
+		Rules:    []translate.Rules{cfg.Translate.Telegram, t.tr.Rules(t.ID())},
+		UTM:      cfg.Links.UTM.Telegram,
+	}
+}
+
+// preview is whether the link preview is shown, as the sender chose or as
+// set in LINKS_PREVIEW.
+func preview(cfg config.AppConfig, msg *message.Message) bot.TelegramPreview {
+	if show, err := strconv.ParseBool(msg.Metadata.Get(pubsub.PreviewKey)); err == nil {
+		return bot.TelegramPreview(show)
+	}
+
+	return bot.TelegramPreview(cfg.Links.Preview)
+}
+
 func (t *Telegram) ExecuteHandlers(ctx context.Context) {
 	t.handleText(ctx)
 	t.handlePhoto(ctx)
+	t.handleTweet(ctx)
+}
+
+// Reload swaps the configuration, so posts go to the new broadcast channel
+// rendered with the new template.
+func (t *Telegram) Reload(cfg config.AppConfig) {
+	t.cfg.Set(cfg)
 }
 
 func (t *Telegram) StopNotifications() {
@@ -70,6 +117,7 @@ func (t *Telegram) handleText(ctx context.Context) {
 	go func() {
 		for msg := range messages {
 			if !t.shouldNotify {
+				handlers.Skipped(t.q, t.ID(), msg)
 				msg.Ack()
 
 				continue
@@ -78,16 +126,23 @@ func (t *Telegram) handleText(ctx context.Context) {
 			var m pubsub.TextEvent
 
 			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
-				handlers.SendError(t.q, err)
+				handlers.SendMessageError(t.q, msg, err)
 				msg.Ack()
 
 				continue
 			}
 
-			if err := t.bot.Send(strconv.Itoa(int(t.cfg.BroadcastChannel)), m.Text); err != nil {
-				handlers.SendError(t.q, err)
+			var sent bot.TelegramSent
+
+			cfg := t.cfg.Get()
+
+			text, err := handlers.Render(msg, m.Text, false, t.rewrite(cfg))
+			if err == nil {
+				err = t.bot.Send(msg.Context(), strconv.Itoa(int(cfg.BroadcastChannel)), text, preview(cfg, msg), &sent)
 			}
 
+			handlers.Delivered(t.q, t.log, t.ID(), msg, err, sent.URL)
+
 			msg.Ack()
 		}
 	}()
@@ -102,6 +157,7 @@ func (t *Telegram) handlePhoto(ctx context.Context) {
 	go func() {
 		for msg := range messages {
 			if !t.shouldNotify {
+				handlers.Skipped(t.q, t.ID(), msg)
 				msg.Ack()
 
 				continue
@@ -109,22 +165,87 @@ func (t *Telegram) handlePhoto(ctx context.Context) {
 
 			var m pubsub.PhotoEvent
 			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
-				handlers.SendError(t.q, err)
+				handlers.SendMessageError(t.q, msg, err)
 				msg.Ack()
 
 				continue
 			}
 
-			if err := t.bot.Send(strconv.Itoa(int(t.cfg.BroadcastChannel)), &bot.TelegramPhoto{
-				Caption:  m.Caption,
-				FileID:   m.FileID,
-				FileURL:  m.FileURL,
-				FileSize: m.FileSize,
-			}); err != nil {
-				handlers.SendError(t.q, err)
+			var sent bot.TelegramSent
+
+			cfg := t.cfg.Get()
+
+			caption, err := handlers.Render(msg, m.Caption, true, t.rewrite(cfg))
+			if err == nil {
+				err = t.bot.Send(msg.Context(), strconv.Itoa(int(cfg.BroadcastChannel)), bot.TelegramPhoto{
+					Caption:  caption,
+					FileID:   m.FileID,
+					FileURL:  m.FileURL,
+					FileSize: m.FileSize,
+				}, &sent)
 			}
 
+			handlers.Delivered(t.q, t.log, t.ID(), msg, err, sent.URL)
+
 			msg.Ack()
 		}
 	}()
 }
+
+func (t *Telegram) handleTweet(ctx context.Context) {
+	messages, err := t.q.Subscribe(ctx, pubsub.TweetTopic.String())
+	if err != nil {
+		handlers.SendError(t.q, err)
+	}
+
+	go func() {
+		for msg := range messages {
+			if !t.shouldNotify {
+				handlers.Skipped(t.q, t.ID(), msg)
+				msg.Ack()
+
+				continue
+			}
+
+			var m pubsub.TweetEvent
+			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
+				handlers.SendMessageError(t.q, msg, err)
+				msg.Ack()
+
+				continue
+			}
+
+			err := t.sendTweet(msg.Context(), m)
+			handlers.Delivered(t.q, t.log, t.ID(), msg, err)
+
+			msg.Ack()
+		}
+	}()
+}
+
+func (t *Telegram) sendTweet(ctx context.Context, m pubsub.TweetEvent) error {
+	to := strconv.Itoa(int(t.cfg.Get().BroadcastChannel))
+
+	text := strings.TrimSpace(m.Text + "\n\n" + m.URL)
+
+	if len(m.Photos) == 0 || len([]rune(text)) > captionLength {
+		if err := t.bot.Send(ctx, to, text); err != nil {
+			return err
+		}
+
+		text = ""
+	}
+
+	for i, p := range m.Photos {
+		photo := bot.TelegramPhoto{FileURL: p}
+		if i == 0 {
+			photo.Caption = text
+		}
+
+		if err := t.bot.Send(ctx, to, photo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
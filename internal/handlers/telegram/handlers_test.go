@@ -12,10 +12,14 @@ import (
 	"github.com/javiyt/tweetgram/internal/config"
 	ht "github.com/javiyt/tweetgram/internal/handlers/telegram"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/telegram"
 	mb "github.com/javiyt/tweetgram/mocks/bot"
 	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	mt "github.com/javiyt/tweetgram/mocks/telegram"
+	"github.com/mailru/easyjson"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	tb "gopkg.in/telebot.v3"
 )
 
 type messageNotSendError struct{}
@@ -45,8 +49,8 @@ func TestTelegram_ExecuteHandlers(t *testing.T) {
 	}
 	ctx := context.Background()
 
-	t.Run("it should fail getting channel for text and photo notifications", func(t *testing.T) {
-		th, mockedQueue, _, _, _ := generateHandlerAndMocks(ctx, cfg, false)
+	t.Run("it should fail getting channel for text, photo and tweet notifications", func(t *testing.T) {
+		th, mockedQueue, _, _, _, _ := generateHandlerAndMocks(ctx, cfg, false)
 
 		mockedQueue.On("Subscribe", ctx, pubsub.TextTopic.String()).
 			Once().
@@ -54,9 +58,12 @@ func TestTelegram_ExecuteHandlers(t *testing.T) {
 		mockedQueue.On("Subscribe", ctx, pubsub.PhotoTopic.String()).
 			Once().
 			Return(nil, gettingChannelError{})
+		mockedQueue.On("Subscribe", ctx, pubsub.TweetTopic.String()).
+			Once().
+			Return(nil, gettingChannelError{})
 		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
 			return string(m.Payload) == "{\"error\":\"error getting channel error\"}"
-		})).Times(2).
+		})).Times(3).
 			Return(nil)
 
 		th.ExecuteHandlers(ctx)
@@ -72,11 +79,10 @@ func TestTelegram_ExecuteHandlersText(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("it should fail unmarshaling text event", func(t *testing.T) {
-		th, mockedQueue, _, textChannel, _ := generateHandlerAndMocks(ctx, cfg, true)
+		th, mockedQueue, _, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
 
 		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
-			return string(m.Payload) ==
-				"{\"error\":\"parse error: unterminated string literal near offset 12 of '{\\\"asd\\\":\\\"qwer'\"}"
+			return errorMessage(m) == "parse error: unterminated string literal near offset 12 of '{\"asd\":\"qwer'"
 		})).Once().
 			Return(nil)
 
@@ -88,13 +94,14 @@ func TestTelegram_ExecuteHandlersText(t *testing.T) {
 	})
 
 	t.Run("it should fail sending text message to telegram", func(t *testing.T) {
-		th, mockedQueue, mockedBot, textChannel, _ := generateHandlerAndMocks(ctx, cfg, true)
+		th, mockedQueue, mockedBot, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
 
 		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
-			return string(m.Payload) == "{\"error\":\"couldn't send message to telegram\"}"
+			return errorMessage(m) == "couldn't send message to telegram"
 		})).Once().
 			Return(nil)
-		mockedBot.On("Send", strconv.Itoa(int(cfg.BroadcastChannel)), "failing message").
+		mockedBot.On("Send", mock.Anything, strconv.Itoa(int(cfg.BroadcastChannel)),
+			"failing message", bot.TelegramPreview(false), sentOption).
 			Once().
 			Return(messageNotSendError{})
 
@@ -106,19 +113,139 @@ func TestTelegram_ExecuteHandlersText(t *testing.T) {
 	})
 
 	t.Run("it should send text message to telegram", func(t *testing.T) {
-		th, mockedQueue, mockedBot, textChannel, _ := generateHandlerAndMocks(ctx, cfg, true)
+		th, mockedQueue, mockedBot, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
+
+		mockedBot.On("Send", mock.Anything, strconv.Itoa(int(cfg.BroadcastChannel)),
+			"testing message", bot.TelegramPreview(false), sentOption).
+			Once().
+			Return(nil, nil)
+
+		th.ExecuteHandlers(ctx)
+
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should send text message to channel set on reload", func(t *testing.T) {
+		th, mockedQueue, mockedBot, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
 
-		mockedBot.On("Send", strconv.Itoa(int(cfg.BroadcastChannel)), "testing message").
+		mockedBot.On("Send", mock.Anything, "5678", "testing message", bot.TelegramPreview(false), sentOption).
 			Once().
 			Return(nil, nil)
 
 		th.ExecuteHandlers(ctx)
+		th.Reload(config.AppConfig{BroadcastChannel: 5678})
 
 		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
 
 		mockedQueue.AssertExpectations(t)
 		mockedBot.AssertExpectations(t)
 	})
+
+	t.Run("it should render text message with the template", func(t *testing.T) {
+		cfg := cfg
+		cfg.Template.Telegram = "{{.Text}}\n\nvia @{{.Author}}{{range .Hashtags}} #{{lower .}}{{end}}"
+		th, mockedQueue, mockedBot, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
+
+		msg := message.NewMessage(watermill.NewUUID(), []byte("{\"text\":\"testing #Message\"}"))
+		msg.Metadata.Set(pubsub.AuthorKey, "admin")
+
+		mockedBot.On("Send", mock.Anything, strconv.Itoa(int(cfg.BroadcastChannel)),
+			"testing #Message\n\nvia @admin #message", bot.TelegramPreview(false), sentOption).
+			Once().
+			Return(nil)
+
+		th.ExecuteHandlers(ctx)
+		textChannel <- msg
+
+		require.Eventually(t, func() bool {
+			<-msg.Acked()
+
+			return true
+		}, time.Second, time.Millisecond)
+		mockedQueue.AssertExpectations(t)
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should report channel link to the admin who sent the message", func(t *testing.T) {
+		th, mockedQueue, mockedBot, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
+
+		msg := message.NewMessage(watermill.NewUUID(), []byte("{\"text\":\"testing message\"}"))
+		msg.Metadata.Set(pubsub.SenderIDKey, "5678")
+		msg.Metadata.Set(pubsub.MessageIDKey, "42")
+
+		mockedBot.On("Send", mock.Anything, strconv.Itoa(int(cfg.BroadcastChannel)),
+			"testing message", bot.TelegramPreview(false), sentOption).
+			Once().
+			Run(func(args mock.Arguments) {
+				args.Get(4).(*bot.TelegramSent).URL = "https://t.me/c/1234/1"
+			}).
+			Return(nil)
+		mockedQueue.On("Publish", pubsub.ResultTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return string(m.Payload) == "{\"messageUuid\":\""+msg.UUID+"\",\"handler\":\"telegram\","+
+				"\"urls\":[\"https://t.me/c/1234/1\"]}" &&
+				m.Metadata.Get(pubsub.SenderIDKey) == "5678" &&
+				m.Metadata.Get(pubsub.MessageIDKey) == "42"
+		})).Once().
+			Return(nil)
+
+		th.ExecuteHandlers(ctx)
+		textChannel <- msg
+
+		require.Eventually(t, func() bool {
+			<-msg.Acked()
+
+			return true
+		}, time.Second, time.Millisecond)
+		mockedQueue.AssertExpectations(t)
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should show the link preview and add the UTM parameters as configured", func(t *testing.T) {
+		cfg := cfg
+		cfg.Links.Preview = true
+		cfg.Links.UTM.Telegram = map[string]string{"utm_source": "telegram"}
+		th, mockedQueue, mockedBot, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
+
+		mockedBot.On("Send", mock.Anything, strconv.Itoa(int(cfg.BroadcastChannel)),
+			"read https://example.com/?utm_source=telegram", bot.TelegramPreview(true), sentOption).
+			Once().
+			Return(nil)
+
+		th.ExecuteHandlers(ctx)
+
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"read https://example.com/\"}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should hide the link preview when the sender chose to", func(t *testing.T) {
+		cfg := cfg
+		cfg.Links.Preview = true
+		th, mockedQueue, mockedBot, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
+
+		msg := message.NewMessage(watermill.NewUUID(), []byte("{\"text\":\"read https://example.com/\"}"))
+		msg.Metadata.Set(pubsub.PreviewKey, "false")
+
+		mockedBot.On("Send", mock.Anything, strconv.Itoa(int(cfg.BroadcastChannel)),
+			"read https://example.com/", bot.TelegramPreview(false), sentOption).
+			Once().
+			Return(nil)
+
+		th.ExecuteHandlers(ctx)
+		textChannel <- msg
+
+		require.Eventually(t, func() bool {
+			<-msg.Acked()
+
+			return true
+		}, time.Second, time.Millisecond)
+		mockedQueue.AssertExpectations(t)
+		mockedBot.AssertExpectations(t)
+	})
 }
 
 func TestTelegram_ExecuteHandlersPhoto(t *testing.T) {
@@ -130,11 +257,10 @@ func TestTelegram_ExecuteHandlersPhoto(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("it should fail unmarshaling photo event", func(t *testing.T) {
-		th, mockedQueue, _, _, photoChannel := generateHandlerAndMocks(ctx, cfg, true)
+		th, mockedQueue, _, _, photoChannel, _ := generateHandlerAndMocks(ctx, cfg, true)
 
 		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
-			return string(m.Payload) ==
-				"{\"error\":\"parse error: unterminated string literal near offset 12 of '{\\\"asd\\\":\\\"qwer'\"}"
+			return errorMessage(m) == "parse error: unterminated string literal near offset 12 of '{\"asd\":\"qwer'"
 		})).Once().
 			Return(nil)
 
@@ -146,13 +272,19 @@ func TestTelegram_ExecuteHandlersPhoto(t *testing.T) {
 	})
 
 	t.Run("it should fail sending photo message to telegram", func(t *testing.T) {
-		th, mockedQueue, mockedBot, _, photoChannel := generateHandlerAndMocks(ctx, cfg, true)
+		th, mockedQueue, mockedBot, _, photoChannel, _ := generateHandlerAndMocks(ctx, cfg, true)
 
 		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
-			return string(m.Payload) == "{\"error\":\"couldn't send message to telegram\"}"
+			return errorMessage(m) == "couldn't send message to telegram"
 		})).Once().
 			Return(nil)
-		mockedBot.On("Send", strconv.Itoa(int(cfg.BroadcastChannel)), mock.MatchedBy(matchTelegramPhoto())).
+		mockedBot.On(
+			"Send",
+			mock.Anything,
+			strconv.Itoa(int(cfg.BroadcastChannel)),
+			mock.MatchedBy(matchTelegramPhoto()),
+			sentOption,
+		).
 			Once().Return(messageNotSendError{})
 
 		th.ExecuteHandlers(ctx)
@@ -164,9 +296,15 @@ func TestTelegram_ExecuteHandlersPhoto(t *testing.T) {
 	})
 
 	t.Run("it should send photo message to telegram", func(t *testing.T) {
-		th, mockedQueue, mockedBot, _, photoChannel := generateHandlerAndMocks(ctx, cfg, true)
-
-		mockedBot.On("Send", strconv.Itoa(int(cfg.BroadcastChannel)), mock.MatchedBy(matchTelegramPhoto())).
+		th, mockedQueue, mockedBot, _, photoChannel, _ := generateHandlerAndMocks(ctx, cfg, true)
+
+		mockedBot.On(
+			"Send",
+			mock.Anything,
+			strconv.Itoa(int(cfg.BroadcastChannel)),
+			mock.MatchedBy(matchTelegramPhoto()),
+			sentOption,
+		).
 			Once().Return(nil)
 
 		th.ExecuteHandlers(ctx)
@@ -175,6 +313,99 @@ func TestTelegram_ExecuteHandlersPhoto(t *testing.T) {
 		mockedQueue.AssertExpectations(t)
 		mockedBot.AssertExpectations(t)
 	})
+
+	t.Run("it should send photo message through the telegram client", func(t *testing.T) {
+		mockedQueue := new(mq.Queue)
+		tbBot := new(mt.TbBot)
+		photoChannel := make(chan *message.Message)
+
+		mockedQueue.On("Subscribe", ctx, mock.Anything).
+			Return(func(_ context.Context, topic string) <-chan *message.Message {
+				if topic == pubsub.PhotoTopic.String() {
+					return photoChannel
+				}
+
+				return make(chan *message.Message)
+			}, nil)
+		tbBot.On("Send", tb.ChatID(1234), &tb.Photo{
+			Caption: "testing message",
+			File:    tb.File{FileID: "blablabla", FileURL: "http://photo.url", FileSize: 1234},
+		}).Once().Return(&tb.Message{ID: 1, Chat: &tb.Chat{ID: 1234}}, nil)
+
+		ht.NewTelegram(
+			ht.WithAppConfig(cfg),
+			ht.WithTelegramBot(telegram.NewBot(tbBot)),
+			ht.WithQueue(mockedQueue),
+		).ExecuteHandlers(ctx)
+		sendMessageToChannel(t, photoChannel, eventMsg)
+
+		tbBot.AssertExpectations(t)
+	})
+}
+
+func TestTelegram_ExecuteHandlersTweet(t *testing.T) {
+	cfg := config.AppConfig{
+		BroadcastChannel: 1234,
+	}
+	to := strconv.Itoa(int(cfg.BroadcastChannel))
+	ctx := context.Background()
+
+	t.Run("it should fail unmarshaling tweet event", func(t *testing.T) {
+		th, mockedQueue, _, _, _, tweetChannel := generateHandlerAndMocks(ctx, cfg, true)
+
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return errorMessage(m) == "parse error: unterminated string literal near offset 12 of '{\"asd\":\"qwer'"
+		})).Once().
+			Return(nil)
+
+		th.ExecuteHandlers(ctx)
+
+		sendMessageToChannel(t, tweetChannel, []byte("{\"asd\":\"qwer"))
+
+		mockedQueue.AssertExpectations(t)
+	})
+
+	t.Run("it should fail sending tweet to telegram", func(t *testing.T) {
+		th, mockedQueue, mockedBot, _, _, tweetChannel := generateHandlerAndMocks(ctx, cfg, true)
+
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return errorMessage(m) == "couldn't send message to telegram"
+		})).Once().
+			Return(nil)
+		mockedBot.On("Send", mock.Anything, to, "testing tweet\n\nhttps://twitter.com/tweetgram/status/1").
+			Once().Return(messageNotSendError{})
+
+		th.ExecuteHandlers(ctx)
+
+		sendMessageToChannel(
+			t,
+			tweetChannel,
+			[]byte("{\"id\":1,\"text\":\"testing tweet\",\"url\":\"https://twitter.com/tweetgram/status/1\"}"),
+		)
+
+		mockedQueue.AssertExpectations(t)
+		mockedBot.AssertExpectations(t)
+	})
+
+	t.Run("it should send tweet photos to telegram with caption on first one", func(t *testing.T) {
+		th, mockedQueue, mockedBot, _, _, tweetChannel := generateHandlerAndMocks(ctx, cfg, true)
+
+		mockedBot.On("Send", mock.Anything, to, bot.TelegramPhoto{
+			Caption: "testing tweet\n\nhttps://twitter.com/tweetgram/status/1",
+			FileURL: "https://pbs.twimg.com/media/first.jpg",
+		}).Once().Return(nil)
+		mockedBot.On("Send", mock.Anything, to, bot.TelegramPhoto{FileURL: "https://pbs.twimg.com/media/second.jpg"}).
+			Once().Return(nil)
+
+		th.ExecuteHandlers(ctx)
+
+		sendMessageToChannel(t, tweetChannel, []byte("{\"id\":1,\"text\":\"testing tweet\","+
+			"\"url\":\"https://twitter.com/tweetgram/status/1\",\"photos\":[\"https://pbs.twimg.com/media/first.jpg\","+
+			"\"https://pbs.twimg.com/media/second.jpg\"]}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedBot.AssertExpectations(t)
+	})
 }
 
 func TestTelegram_ExecuteHandlersNotificationsDisabled(t *testing.T) {
@@ -184,7 +415,7 @@ func TestTelegram_ExecuteHandlersNotificationsDisabled(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("it should not send text message to telegram when notification disabled", func(t *testing.T) {
-		th, mockedQueue, mockedBot, textChannel, _ := generateHandlerAndMocks(ctx, cfg, true)
+		th, mockedQueue, mockedBot, textChannel, _, _ := generateHandlerAndMocks(ctx, cfg, true)
 
 		th.StopNotifications()
 		th.ExecuteHandlers(ctx)
@@ -193,14 +424,14 @@ func TestTelegram_ExecuteHandlersNotificationsDisabled(t *testing.T) {
 
 		mockedQueue.AssertExpectations(t)
 		mockedBot.Test(t)
-		mockedBot.AssertNotCalled(t, "Send", strconv.Itoa(int(cfg.BroadcastChannel)), "testing message")
+		mockedBot.AssertNotCalled(t, "Send", mock.Anything, strconv.Itoa(int(cfg.BroadcastChannel)), "testing message")
 	})
 
 	t.Run("it should not send photo message to telegram when notification disabled", func(t *testing.T) {
 		eventMsg := []byte("{\"caption\":\"testing message\",\"fileId\":\"blablabla\",\"fileUrl\":\"http://photo.url\"," +
 			"\"fileSize\":1234}")
 
-		th, mockedQueue, mockedBot, _, photoChannel := generateHandlerAndMocks(ctx, cfg, true)
+		th, mockedQueue, mockedBot, _, photoChannel, _ := generateHandlerAndMocks(ctx, cfg, true)
 
 		th.StopNotifications()
 		th.ExecuteHandlers(ctx)
@@ -208,15 +439,30 @@ func TestTelegram_ExecuteHandlersNotificationsDisabled(t *testing.T) {
 
 		mockedQueue.AssertExpectations(t)
 		mockedBot.Test(t)
-		mockedBot.AssertNotCalled(t, "Send", strconv.Itoa(int(cfg.BroadcastChannel)), mock.MatchedBy(matchTelegramPhoto()))
+		mockedBot.AssertNotCalled(
+			t,
+			"Send",
+			mock.Anything,
+			strconv.Itoa(int(cfg.BroadcastChannel)),
+			mock.MatchedBy(matchTelegramPhoto()),
+		)
 	})
 }
 
+var sentOption = mock.AnythingOfType("*bot.TelegramSent")
+
 func generateHandlerAndMocks(
 	ctx context.Context,
 	cfg config.AppConfig,
 	returnChannels bool,
-) (*ht.Telegram, *mq.Queue, *mb.TelegramBot, chan *message.Message, chan *message.Message) {
+) (
+	*ht.Telegram,
+	*mq.Queue,
+	*mb.TelegramBot,
+	chan *message.Message,
+	chan *message.Message,
+	chan *message.Message,
+) {
 	mockedBot := new(mb.TelegramBot)
 	mockedQueue := new(mq.Queue)
 
@@ -224,6 +470,7 @@ func generateHandlerAndMocks(
 
 	textChannel := make(chan *message.Message)
 	photoChannel := make(chan *message.Message)
+	tweetChannel := make(chan *message.Message)
 
 	if returnChannels {
 		mockedQueue.On("Subscribe", ctx, pubsub.TextTopic.String()).
@@ -236,9 +483,14 @@ func generateHandlerAndMocks(
 			Return(func(context.Context, string) <-chan *message.Message {
 				return photoChannel
 			}, nil)
+		mockedQueue.On("Subscribe", ctx, pubsub.TweetTopic.String()).
+			Once().
+			Return(func(context.Context, string) <-chan *message.Message {
+				return tweetChannel
+			}, nil)
 	}
 
-	return th, mockedQueue, mockedBot, textChannel, photoChannel
+	return th, mockedQueue, mockedBot, textChannel, photoChannel, tweetChannel
 }
 
 func sendMessageToChannel(t *testing.T, channel chan *message.Message, eventMsg []byte) {
@@ -254,12 +506,8 @@ func sendMessageToChannel(t *testing.T, channel chan *message.Message, eventMsg
 
 func matchTelegramPhoto() func(m interface{}) bool {
 	return func(m interface{}) bool {
-		var (
-			p  *bot.TelegramPhoto
-			ok bool
-		)
-
-		if p, ok = m.(*bot.TelegramPhoto); !ok {
+		p, ok := m.(bot.TelegramPhoto)
+		if !ok {
 			return false
 		}
 
@@ -269,3 +517,10 @@ func matchTelegramPhoto() func(m interface{}) bool {
 			p.FileSize == 1234
 	}
 }
+
+func errorMessage(m *message.Message) string {
+	var e pubsub.ErrorEvent
+	_ = easyjson.Unmarshal(m.Payload, &e)
+
+	return e.Err
+}
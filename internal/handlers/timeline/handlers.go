@@ -0,0 +1,189 @@
+package handlerstimeline
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/tracing"
+	"github.com/javiyt/tweetgram/internal/twitter"
+	"github.com/mailru/easyjson"
+	"github.com/sirupsen/logrus"
+)
+
+const lastIDKey = "timeline/last_id"
+
+type TimelineClient interface {
+	UserTimeline(ctx context.Context, sinceID int64) ([]twitter.Tweet, error)
+}
+
+type PublishedChecker interface {
+	IsPublished(id int64) bool
+}
+
+type Timeline struct {
+	cfg          config.AppConfig
+	q            pubsub.Queue
+	s            storage.Store
+	tc           TimelineClient
+	pc           PublishedChecker
+	mu           sync.Mutex
+	log          *logrus.Logger
+	shouldNotify bool
+}
+
+type Option func(t *Timeline)
+
+func WithAppConfig(cfg config.AppConfig) Option {
+	return func(t *Timeline) {
+		t.cfg = cfg
+	}
+}
+
+func WithQueue(q pubsub.Queue) Option {
+	return func(t *Timeline) {
+		t.q = q
+	}
+}
+
+func WithStore(s storage.Store) Option {
+	return func(t *Timeline) {
+		t.s = s
+	}
+}
+
+func WithTimelineClient(tc TimelineClient) Option {
+	return func(t *Timeline) {
+		t.tc = tc
+	}
+}
+
+func WithPublishedChecker(pc PublishedChecker) Option {
+	return func(t *Timeline) {
+		t.pc = pc
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(t *Timeline) {
+		t.log = log
+	}
+}
+
+func NewTimeline(options ...Option) *Timeline {
+	t := &Timeline{log: logging.Discard(), shouldNotify: true}
+
+	for _, o := range options {
+		o(t)
+	}
+
+	return t
+}
+
+func (t *Timeline) ID() string {
+	return "timeline"
+}
+
+func (t *Timeline) ExecuteHandlers(ctx context.Context) {
+	if t.cfg.TimelinePollInterval > 0 {
+		go t.schedulePoll(ctx)
+	}
+}
+
+func (t *Timeline) StopNotifications() {
+	t.shouldNotify = false
+}
+
+// Poll fetches the tweets published since the last poll and sends them to the
+// tweet topic. The first poll only records the newest tweet, so the account
+// history isn't imported. The last ID is saved after every tweet, so a poll
+// failing halfway doesn't publish the earlier ones again.
+func (t *Timeline) Poll(ctx context.Context) (err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ctx, span := tracing.Tracer().Start(logging.WithCorrelationID(ctx, watermill.NewUUID()), "poll timeline")
+	defer func() { tracing.End(span, err) }()
+
+	var lastID int64
+	if err := t.s.Load(lastIDKey, &lastID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	tweets, err := t.tc.UserTimeline(ctx, lastID)
+	if err != nil {
+		return err
+	}
+
+	if len(tweets) == 0 {
+		return nil
+	}
+
+	sort.Slice(tweets, func(i, j int) bool { return tweets[i].ID < tweets[j].ID })
+
+	if lastID == 0 {
+		return t.s.Save(lastIDKey, tweets[len(tweets)-1].ID)
+	}
+
+	for _, tw := range tweets {
+		if t.pc == nil || !t.pc.IsPublished(tw.ID) {
+			if err := t.publish(ctx, tw); err != nil {
+				return err
+			}
+
+			t.log.WithContext(ctx).WithField("tweet_id", tw.ID).Info("tweet imported from timeline")
+		}
+
+		if err := t.s.Save(lastIDKey, tw.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Timeline) publish(ctx context.Context, tw twitter.Tweet) error {
+	payload, err := easyjson.Marshal(pubsub.TweetEvent{
+		ID:     tw.ID,
+		Text:   tw.Text,
+		URL:    tw.URL,
+		Photos: tw.Photos,
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.SetContext(ctx)
+
+	return t.q.Publish(pubsub.TweetTopic.String(), msg)
+}
+
+func (t *Timeline) schedulePoll(ctx context.Context) {
+	ticker := time.NewTicker(t.cfg.TimelinePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !t.shouldNotify {
+				continue
+			}
+
+			if err := t.Poll(ctx); err != nil {
+				handlers.SendError(t.q, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package handlerstimeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	ht "github.com/javiyt/tweetgram/internal/handlers/timeline"
+	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/twitter"
+	mt "github.com/javiyt/tweetgram/mocks/handlers/timeline"
+	mq "github.com/javiyt/tweetgram/mocks/pubsub"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type timelineError struct{}
+
+func (m timelineError) Error() string {
+	return "error getting timeline"
+}
+
+func TestTimeline_ID(t *testing.T) {
+	require.Equal(t, "timeline", ht.NewTimeline().ID())
+}
+
+func TestTimeline_Poll(t *testing.T) {
+	t.Run("it should fail when timeline can't be fetched", func(t *testing.T) {
+		th, mockedQueue, mockedClient, _ := generateHandlerAndMocks(t, config.AppConfig{})
+
+		mockedClient.On("UserTimeline", mock.Anything, int64(0)).Once().Return(nil, timelineError{})
+
+		require.EqualError(t, th.Poll(context.Background()), "error getting timeline")
+		mockedQueue.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+
+	t.Run("it should only record newest tweet on first poll", func(t *testing.T) {
+		th, mockedQueue, mockedClient, _ := generateHandlerAndMocks(t, config.AppConfig{})
+
+		mockedClient.On("UserTimeline", mock.Anything, int64(0)).Once().Return([]twitter.Tweet{{ID: 12}, {ID: 10}}, nil)
+		mockedClient.On("UserTimeline", mock.Anything, int64(12)).Once().Return(nil, nil)
+
+		require.NoError(t, th.Poll(context.Background()))
+		require.NoError(t, th.Poll(context.Background()))
+		mockedQueue.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+
+	t.Run("it should publish new tweets not published by tweetgram in order", func(t *testing.T) {
+		th, mockedQueue, mockedClient, mockedChecker := generateHandlerAndMocks(t, config.AppConfig{})
+
+		mockedClient.On("UserTimeline", mock.Anything, int64(0)).Once().Return([]twitter.Tweet{{ID: 10}}, nil)
+		mockedClient.On("UserTimeline", mock.Anything, int64(10)).Once().Return([]twitter.Tweet{
+			{ID: 13, Text: "third", URL: "https://twitter.com/tweetgram/status/13"},
+			{ID: 12, Text: "published"},
+			{ID: 11, Text: "first", Photos: []string{"https://pbs.twimg.com/media/photo.jpg"}},
+		}, nil)
+		mockedClient.On("UserTimeline", mock.Anything, int64(13)).Once().Return(nil, nil)
+		mockedChecker.On("IsPublished", int64(11)).Once().Return(false)
+		mockedChecker.On("IsPublished", int64(12)).Once().Return(true)
+		mockedChecker.On("IsPublished", int64(13)).Once().Return(false)
+
+		var published []string
+		mockedQueue.On("Publish", pubsub.TweetTopic.String(), mock.Anything).
+			Times(2).
+			Run(func(args mock.Arguments) {
+				published = append(published, string(args.Get(1).(*message.Message).Payload))
+			}).
+			Return(nil)
+
+		require.NoError(t, th.Poll(context.Background()))
+		require.NoError(t, th.Poll(context.Background()))
+		require.NoError(t, th.Poll(context.Background()))
+		require.Equal(t, []string{
+			"{\"id\":11,\"text\":\"first\",\"url\":\"\",\"photos\":[\"https://pbs.twimg.com/media/photo.jpg\"]}",
+			"{\"id\":13,\"text\":\"third\",\"url\":\"https://twitter.com/tweetgram/status/13\",\"photos\":null}",
+		}, published)
+		mockedQueue.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+		mockedChecker.AssertExpectations(t)
+	})
+}
+
+func TestTimeline_PollFailure(t *testing.T) {
+	t.Run("it should not publish again tweets published before a failure", func(t *testing.T) {
+		th, mockedQueue, mockedClient, mockedChecker := generateHandlerAndMocks(t, config.AppConfig{})
+
+		mockedClient.On("UserTimeline", mock.Anything, int64(0)).Once().Return([]twitter.Tweet{{ID: 10}}, nil)
+		mockedClient.On("UserTimeline", mock.Anything, int64(10)).Once().
+			Return([]twitter.Tweet{{ID: 11, Text: "first"}, {ID: 12, Text: "second"}}, nil)
+		mockedClient.On("UserTimeline", mock.Anything, int64(11)).Once().
+			Return([]twitter.Tweet{{ID: 12, Text: "second"}}, nil)
+		mockedChecker.On("IsPublished", mock.Anything).Return(false)
+		mockedQueue.On("Publish", pubsub.TweetTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return string(m.Payload) == "{\"id\":11,\"text\":\"first\",\"url\":\"\",\"photos\":null}"
+		})).Once().Return(nil)
+		mockedQueue.On("Publish", pubsub.TweetTopic.String(), mock.Anything).Once().Return(timelineError{})
+		mockedQueue.On("Publish", pubsub.TweetTopic.String(), mock.Anything).Once().Return(nil)
+
+		require.NoError(t, th.Poll(context.Background()))
+		require.EqualError(t, th.Poll(context.Background()), "error getting timeline")
+		require.NoError(t, th.Poll(context.Background()))
+		mockedQueue.AssertExpectations(t)
+		mockedClient.AssertExpectations(t)
+	})
+}
+
+func TestTimeline_ExecuteHandlers(t *testing.T) {
+	t.Run("it should send error when scheduled poll fails", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		th, mockedQueue, mockedClient, _ := generateHandlerAndMocks(
+			t,
+			config.AppConfig{TimelinePollInterval: time.Millisecond},
+		)
+
+		called := make(chan struct{}, 1)
+
+		mockedClient.On("UserTimeline", mock.Anything, int64(0)).Return(nil, timelineError{})
+		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			return string(m.Payload) == "{\"error\":\"error getting timeline\"}"
+		})).Run(func(mock.Arguments) {
+			select {
+			case called <- struct{}{}:
+			default:
+			}
+		}).Return(nil)
+
+		th.ExecuteHandlers(ctx)
+
+		select {
+		case <-called:
+		case <-time.After(time.Second):
+			require.Fail(t, "timeline wasn't polled")
+		}
+	})
+
+	t.Run("it should not poll when notifications disabled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		th, mockedQueue, mockedClient, _ := generateHandlerAndMocks(
+			t,
+			config.AppConfig{TimelinePollInterval: time.Millisecond},
+		)
+
+		th.StopNotifications()
+		th.ExecuteHandlers(ctx)
+
+		time.Sleep(10 * time.Millisecond)
+
+		mockedQueue.AssertExpectations(t)
+		mockedClient.AssertNotCalled(t, "UserTimeline", mock.Anything, mock.Anything)
+	})
+}
+
+func generateHandlerAndMocks(
+	t *testing.T,
+	cfg config.AppConfig,
+) (*ht.Timeline, *mq.Queue, *mt.TimelineClient, *mt.PublishedChecker) {
+	mockedQueue := new(mq.Queue)
+	mockedClient := new(mt.TimelineClient)
+	mockedChecker := new(mt.PublishedChecker)
+
+	th := ht.NewTimeline(
+		ht.WithAppConfig(cfg),
+		ht.WithQueue(mockedQueue),
+		ht.WithStore(storage.NewFileStore(t.TempDir())),
+		ht.WithTimelineClient(mockedClient),
+		ht.WithPublishedChecker(mockedChecker),
+	)
+
+	return th, mockedQueue, mockedClient, mockedChecker
+}
@@ -2,16 +2,28 @@ package handlerstwitter
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
 	"github.com/javiyt/tweetgram/internal/handlers"
+	"github.com/javiyt/tweetgram/internal/logging"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/translate"
 	"github.com/mailru/easyjson"
+	"github.com/sirupsen/logrus"
 )
 
+const statusURL = "https://twitter.com/i/web/status/%d"
+
 type Twitter struct {
 	tc           bot.TwitterClient
+	cfg          config.Holder
 	q            pubsub.Queue
+	tr           *translate.Table
+	p            *Published
+	log          *logrus.Logger
 	shouldNotify bool
 }
 
@@ -23,14 +35,46 @@ func WithTwitterClient(tc bot.TwitterClient) Option {
 	}
 }
 
+func WithAppConfig(cfg config.AppConfig) Option {
+	return func(t *Twitter) {
+		t.cfg.Set(cfg)
+	}
+}
+
 func WithQueue(q pubsub.Queue) Option {
 	return func(t *Twitter) {
 		t.q = q
 	}
 }
 
+func WithTranslations(tr *translate.Table) Option {
+	return func(t *Twitter) {
+		t.tr = tr
+	}
+}
+
+// WithStore keeps the published tweets in s, use WithPublished instead when
+// they are recorded elsewhere too.
+func WithStore(s storage.Store) Option {
+	return func(t *Twitter) {
+		t.p = NewPublished(s)
+	}
+}
+
+func WithPublished(p *Published) Option {
+	return func(t *Twitter) {
+		t.p = p
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(b *Twitter) {
+		b.log = log
+	}
+}
+
 func NewTwitter(options ...Option) *Twitter {
-	t := &Twitter{shouldNotify: true}
+	t := &Twitter{log: logging.Discard(), tr: new(translate.Table), shouldNotify: true}
 
 	for _, o := range options {
 		o(t)
@@ -43,15 +87,35 @@ func (t *Twitter) ID() string {
 	return "twitter"
 }
 
+// rewrite is how posts are changed into tweets with cfg.
+func (t *Twitter) rewrite(cfg config.AppConfig) handlers.Rewrite {
+	return handlers.Rewrite{
+		Template: cfg.Template.Twitter,
+		Rules:    []translate.Rules{cfg.Translate.Twitter, t.tr.Rules(t.ID())},
+		UTM:      cfg.Links.UTM.Twitter,
+	}
+}
+
 func (t *Twitter) ExecuteHandlers(ctx context.Context) {
 	t.handleText(ctx)
 	t.handlePhoto(ctx)
 }
 
+// Reload swaps the configuration, so tweets are rendered with the new template.
+func (t *Twitter) Reload(cfg config.AppConfig) {
+	t.cfg.Set(cfg)
+}
+
 func (t *Twitter) StopNotifications() {
 	t.shouldNotify = false
 }
 
+// IsPublished reports whether the tweet was published by tweetgram, so the
+// timeline importer doesn't mirror tweetgram's own posts back to Telegram.
+func (t *Twitter) IsPublished(id int64) bool {
+	return t.p.IsPublished(id)
+}
+
 func (t *Twitter) handleText(ctx context.Context) {
 	messages, err := t.q.Subscribe(ctx, pubsub.TextTopic.String())
 	if err != nil {
@@ -61,6 +125,7 @@ func (t *Twitter) handleText(ctx context.Context) {
 	go func() {
 		for msg := range messages {
 			if !t.shouldNotify {
+				handlers.Skipped(t.q, t.ID(), msg)
 				msg.Ack()
 
 				continue
@@ -68,14 +133,25 @@ func (t *Twitter) handleText(ctx context.Context) {
 
 			var m pubsub.TextEvent
 			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
-				handlers.SendError(t.q, err)
+				handlers.SendMessageError(t.q, msg, err)
 				msg.Ack()
 
 				continue
 			}
 
-			if err := t.tc.SendUpdate(m.Text); err != nil {
-				handlers.SendError(t.q, err)
+			var ids []int64
+
+			cfg := t.cfg.Get()
+
+			text, err := handlers.Render(msg, m.Text, false, t.rewrite(cfg))
+			if err == nil {
+				ids, err = t.tc.SendUpdate(msg.Context(), text)
+			}
+
+			handlers.Delivered(t.q, t.log, t.ID(), msg, err, tweetURLs(ids)...)
+
+			if err := t.p.Record(ids); err != nil {
+				handlers.SendMessageError(t.q, msg, err)
 			}
 
 			msg.Ack()
@@ -92,6 +168,7 @@ func (t *Twitter) handlePhoto(ctx context.Context) {
 	go func() {
 		for msg := range messages {
 			if !t.shouldNotify {
+				handlers.Skipped(t.q, t.ID(), msg)
 				msg.Ack()
 
 				continue
@@ -99,17 +176,37 @@ func (t *Twitter) handlePhoto(ctx context.Context) {
 
 			var m pubsub.PhotoEvent
 			if err := easyjson.Unmarshal(msg.Payload, &m); err != nil {
-				handlers.SendError(t.q, err)
+				handlers.SendMessageError(t.q, msg, err)
 				msg.Ack()
 
 				continue
 			}
 
-			if err := t.tc.SendUpdateWithPhoto(m.Caption, m.FileContent); err != nil {
-				handlers.SendError(t.q, err)
+			var ids []int64
+
+			cfg := t.cfg.Get()
+
+			caption, err := handlers.Render(msg, m.Caption, true, t.rewrite(cfg))
+			if err == nil {
+				ids, err = t.tc.SendUpdateWithPhoto(msg.Context(), caption, m.FileContent)
+			}
+
+			handlers.Delivered(t.q, t.log, t.ID(), msg, err, tweetURLs(ids)...)
+
+			if err := t.p.Record(ids); err != nil {
+				handlers.SendMessageError(t.q, msg, err)
 			}
 
 			msg.Ack()
 		}
 	}()
 }
+
+func tweetURLs(ids []int64) []string {
+	urls := make([]string, 0, len(ids))
+	for _, id := range ids {
+		urls = append(urls, fmt.Sprintf(statusURL, id))
+	}
+
+	return urls
+}
@@ -7,8 +7,11 @@ import (
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
 	ht "github.com/javiyt/tweetgram/internal/handlers/twitter"
 	"github.com/javiyt/tweetgram/internal/pubsub"
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/translate"
 	mb "github.com/javiyt/tweetgram/mocks/bot"
 	mq "github.com/javiyt/tweetgram/mocks/pubsub"
 	"github.com/mailru/easyjson"
@@ -67,8 +70,7 @@ func TestTwitter_ExecuteHandlersText(t *testing.T) {
 		th, mockedQueue, _, textChannel, _ := getTwitterHandlerAndMocks(ctx, true)
 
 		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
-			return string(m.Payload) ==
-				"{\"error\":\"parse error: unterminated string literal near offset 12 of '{\\\"asd\\\":\\\"qwer'\"}"
+			return errorMessage(m) == "parse error: unterminated string literal near offset 12 of '{\"asd\":\"qwer'"
 		})).Once().
 			Return(nil)
 
@@ -86,13 +88,13 @@ func TestTwitter_ExecuteHandlersText(t *testing.T) {
 			"Publish",
 			pubsub.ErrorTopic.String(),
 			mock.MatchedBy(func(m *message.Message) bool {
-				return string(m.Payload) == "{\"error\":\"couldn't send message to twitter\"}"
+				return errorMessage(m) == "couldn't send message to twitter"
 			}),
 		).Once().
 			Return(nil)
-		mockedTwitter.On("SendUpdate", "testing message").
+		mockedTwitter.On("SendUpdate", mock.Anything, "testing message").
 			Once().
-			Return(messageNotSendError{})
+			Return(nil, messageNotSendError{})
 
 		th.ExecuteHandlers(ctx)
 
@@ -105,7 +107,7 @@ func TestTwitter_ExecuteHandlersText(t *testing.T) {
 	t.Run("it should send text message to twitter", func(t *testing.T) {
 		th, mockedQueue, mockedTwitter, textChannel, _ := getTwitterHandlerAndMocks(ctx, true)
 
-		mockedTwitter.On("SendUpdate", "testing message").Once().Return(nil)
+		mockedTwitter.On("SendUpdate", mock.Anything, "testing message").Once().Return([]int64{1234}, nil)
 
 		th.ExecuteHandlers(ctx)
 
@@ -114,6 +116,117 @@ func TestTwitter_ExecuteHandlersText(t *testing.T) {
 		mockedQueue.AssertExpectations(t)
 		mockedTwitter.AssertExpectations(t)
 	})
+
+	t.Run("it should report thread links to the admin who sent the message", func(t *testing.T) {
+		th, mockedQueue, mockedTwitter, textChannel, _ := getTwitterHandlerAndMocks(ctx, true)
+
+		msg := message.NewMessage(watermill.NewUUID(), []byte("{\"text\":\"testing message\"}"))
+		msg.Metadata.Set(pubsub.SenderIDKey, "5678")
+
+		mockedTwitter.On("SendUpdate", mock.Anything, "testing message").Once().Return([]int64{1234, 1235}, nil)
+		mockedQueue.On("Publish", pubsub.ResultTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
+			var r pubsub.ResultEvent
+			_ = easyjson.Unmarshal(m.Payload, &r)
+
+			return r.MessageUUID == msg.UUID && r.Handler == "twitter" && len(r.URLs) == 2 &&
+				r.URLs[0] == "https://twitter.com/i/web/status/1234" &&
+				r.URLs[1] == "https://twitter.com/i/web/status/1235"
+		})).Once().
+			Return(nil)
+
+		th.ExecuteHandlers(ctx)
+		textChannel <- msg
+
+		require.Eventually(t, func() bool {
+			<-msg.Acked()
+
+			return true
+		}, time.Second, time.Millisecond)
+		mockedQueue.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+	})
+
+	t.Run("it should render text message with the template", func(t *testing.T) {
+		var cfg config.AppConfig
+		cfg.Template.Twitter = "{{.Text}} {{join .Hashtags \",\" | upper}}"
+
+		th, mockedQueue, mockedTwitter, textChannel, _ := getTwitterHandlerAndMocks(ctx, true, ht.WithAppConfig(cfg))
+
+		mockedTwitter.On("SendUpdate", mock.Anything, "testing #go #bots GO,BOTS").Once().Return([]int64{1234}, nil)
+
+		th.ExecuteHandlers(ctx)
+
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing #go #bots\"}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+	})
+
+	t.Run("it should rewrite mentions and hashtags", func(t *testing.T) {
+		var cfg config.AppConfig
+		cfg.Translate.Twitter = map[string]string{"@acme_tg": "@AcmeCorp", "#tg": "#tw"}
+		cfg.Template.Twitter = "{{.Text}} {{join .Hashtags \",\"}}"
+
+		table := translate.NewTable(storage.NewFileStore(t.TempDir()))
+		require.NoError(t, table.Add("twitter", "#tg", "#twitter"))
+
+		th, mockedQueue, mockedTwitter, textChannel, _ := getTwitterHandlerAndMocks(
+			ctx,
+			true,
+			ht.WithAppConfig(cfg),
+			ht.WithTranslations(table),
+		)
+
+		mockedTwitter.On("SendUpdate", mock.Anything, "News from @AcmeCorp #twitter twitter").
+			Once().
+			Return([]int64{1234}, nil)
+
+		th.ExecuteHandlers(ctx)
+
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"News from @ACME_tg #tg\"}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+	})
+
+	t.Run("it should render text message with the template set on reload", func(t *testing.T) {
+		th, mockedQueue, mockedTwitter, textChannel, _ := getTwitterHandlerAndMocks(ctx, true)
+
+		var cfg config.AppConfig
+		cfg.Template.Twitter = "{{if .Author}}@{{.Author}}: {{end}}{{.Text}}"
+
+		mockedTwitter.On("SendUpdate", mock.Anything, "testing message").Once().Return([]int64{1234}, nil)
+
+		th.ExecuteHandlers(ctx)
+		th.Reload(cfg)
+
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
+
+		mockedQueue.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+	})
+
+	t.Run("it should remember published tweets", func(t *testing.T) {
+		th, mockedQueue, mockedTwitter, textChannel, _ := getTwitterHandlerAndMocks(
+			ctx,
+			true,
+			ht.WithStore(storage.NewFileStore(t.TempDir())),
+		)
+
+		mockedTwitter.On("SendUpdate", mock.Anything, "testing message").Once().Return([]int64{1234, 1235}, nil)
+
+		th.ExecuteHandlers(ctx)
+
+		require.False(t, th.IsPublished(1234))
+
+		sendMessageToChannel(t, textChannel, []byte("{\"text\":\"testing message\"}"))
+
+		require.True(t, th.IsPublished(1234))
+		require.True(t, th.IsPublished(1235))
+		require.False(t, th.IsPublished(1236))
+		mockedQueue.AssertExpectations(t)
+		mockedTwitter.AssertExpectations(t)
+	})
 }
 
 func TestTwitter_ExecuteHandlersPhoto(t *testing.T) {
@@ -132,8 +245,7 @@ func TestTwitter_ExecuteHandlersPhoto(t *testing.T) {
 		th, mockedQueue, _, _, photoChannel := getTwitterHandlerAndMocks(context.Background(), true)
 
 		mockedQueue.On("Publish", pubsub.ErrorTopic.String(), mock.MatchedBy(func(m *message.Message) bool {
-			return string(m.Payload) ==
-				"{\"error\":\"parse error: unterminated string literal near offset 12 of '{\\\"asd\\\":\\\"qwer'\"}"
+			return errorMessage(m) == "parse error: unterminated string literal near offset 12 of '{\"asd\":\"qwer'"
 		})).Once().Return(nil)
 
 		th.ExecuteHandlers(context.Background())
@@ -150,11 +262,11 @@ func TestTwitter_ExecuteHandlersPhoto(t *testing.T) {
 			"Publish",
 			pubsub.ErrorTopic.String(),
 			mock.MatchedBy(func(m *message.Message) bool {
-				return string(m.Payload) == "{\"error\":\"couldn't send message to twitter\"}"
+				return errorMessage(m) == "couldn't send message to twitter"
 			}),
 		).Once().Return(nil)
-		mockedTwitter.On("SendUpdateWithPhoto", "testing caption", photoContent).
-			Once().Return(messageNotSendError{})
+		mockedTwitter.On("SendUpdateWithPhoto", mock.Anything, "testing caption", photoContent).
+			Once().Return(nil, messageNotSendError{})
 
 		th.ExecuteHandlers(context.Background())
 
@@ -167,8 +279,8 @@ func TestTwitter_ExecuteHandlersPhoto(t *testing.T) {
 	t.Run("it should send photo to twitter", func(t *testing.T) {
 		th, mockedQueue, mockedTwitter, _, photoChannel := getTwitterHandlerAndMocks(context.Background(), true)
 
-		mockedTwitter.On("SendUpdateWithPhoto", "testing caption", photoContent).
-			Once().Return(nil)
+		mockedTwitter.On("SendUpdateWithPhoto", mock.Anything, "testing caption", photoContent).
+			Once().Return([]int64{1234}, nil)
 
 		th.ExecuteHandlers(context.Background())
 
@@ -192,7 +304,7 @@ func TestTwitter_ExecuteHandlersNotificationsDisabled(t *testing.T) {
 
 		mockedQueue.AssertExpectations(t)
 		mockedTwitter.Test(t)
-		mockedTwitter.AssertNotCalled(t, "SendUpdate", "testing message")
+		mockedTwitter.AssertNotCalled(t, "SendUpdate", mock.Anything, "testing message")
 	})
 
 	t.Run("it should send photo to twitter when notification disabled", func(t *testing.T) {
@@ -216,11 +328,11 @@ func TestTwitter_ExecuteHandlersNotificationsDisabled(t *testing.T) {
 
 		mockedQueue.AssertExpectations(t)
 		mockedTwitter.Test(t)
-		mockedTwitter.AssertNotCalled(t, "SendUpdateWithPhoto", "testing caption", photoContent)
+		mockedTwitter.AssertNotCalled(t, "SendUpdateWithPhoto", mock.Anything, "testing caption", photoContent)
 	})
 }
 
-func getTwitterHandlerAndMocks(ctx context.Context, returnChannels bool) (
+func getTwitterHandlerAndMocks(ctx context.Context, returnChannels bool, options ...ht.Option) (
 	*ht.Twitter,
 	*mq.Queue,
 	*mb.TwitterClient,
@@ -230,7 +342,10 @@ func getTwitterHandlerAndMocks(ctx context.Context, returnChannels bool) (
 	mockedTwitter := new(mb.TwitterClient)
 	mockedQueue := new(mq.Queue)
 
-	th := ht.NewTwitter(ht.WithTwitterClient(mockedTwitter), ht.WithQueue(mockedQueue))
+	th := ht.NewTwitter(append(
+		[]ht.Option{ht.WithTwitterClient(mockedTwitter), ht.WithQueue(mockedQueue)},
+		options...,
+	)...)
 
 	textChannel := make(chan *message.Message)
 	photoChannel := make(chan *message.Message)
@@ -261,3 +376,10 @@ func sendMessageToChannel(t *testing.T, channel chan *message.Message, eventMsg
 		return true
 	}, time.Second, time.Millisecond)
 }
+
+func errorMessage(m *message.Message) string {
+	var e pubsub.ErrorEvent
+	_ = easyjson.Unmarshal(m.Payload, &e)
+
+	return e.Err
+}
@@ -0,0 +1,72 @@
+package handlerstwitter
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/javiyt/tweetgram/internal/storage"
+)
+
+const (
+	publishedKey      = "twitter/published"
+	maxPublishedSaved = 1000
+)
+
+// Published keeps the IDs of the tweets sent by tweetgram, the posts of this
+// handler and the replies sent from the bot, so the timeline importer doesn't
+// mirror them back to Telegram. A single one has to be shared by everything
+// recording tweets, as the IDs are kept under the same key.
+type Published struct {
+	s  storage.Store
+	mu sync.Mutex
+}
+
+func NewPublished(s storage.Store) *Published {
+	return &Published{s: s}
+}
+
+// Record adds the IDs, dropping the oldest ones once there are more than
+// maxPublishedSaved.
+func (p *Published) Record(ids []int64) error {
+	if p == nil || p.s == nil || len(ids) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var published []int64
+	if err := p.s.Load(publishedKey, &published); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	published = append(published, ids...)
+	if len(published) > maxPublishedSaved {
+		published = published[len(published)-maxPublishedSaved:]
+	}
+
+	return p.s.Save(publishedKey, published)
+}
+
+// IsPublished reports whether the tweet was sent by tweetgram.
+func (p *Published) IsPublished(id int64) bool {
+	if p == nil || p.s == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ids []int64
+	if err := p.s.Load(publishedKey, &ids); err != nil {
+		return false
+	}
+
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package i18n
+
+var english = Catalog{
+	"start": "Thanks for using the bot! You can type /help command to know what can I do",
+
+	"command.addadmin":    "Add an admin by user ID or @username",
+	"command.admins":      "List the admins",
+	"command.audit":       "Show the last entries of the audit log or export it as csv or json",
+	"command.cancel":      "Cancel the pending reply to a tweet or draft edit",
+	"command.help":        "Show help",
+	"command.post":        "Publish the text following the command, also in the staff group",
+	"command.removeadmin": "Remove an admin added with /addadmin",
+	"command.start":       "Start a conversation with the bot",
+	"command.stop":        "Stop notifications for all handlers or specific handler",
+	"command.translate":   "List the mentions and hashtags rewritten for every destination or add one",
+	"command.untranslate": "Stop rewriting a mention or hashtag added with /translate",
+
+	"admins.configured":       "(configured)",
+	"admins.unknown":          "I don't know %s, only staff members who talked to the bot can be added by username",
+	"admins.add_usage":        "Usage: /addadmin <id|@username>",
+	"admins.already":          "%s is already an admin",
+	"admins.added":            "%s is now an admin",
+	"admins.remove_usage":     "Usage: /removeadmin <id>",
+	"admins.not_removable":    "%s is configured as admin and can only be removed from the configuration",
+	"admins.not_admin":        "%s is not an admin",
+	"admins.removed":          "%s is no longer an admin",
+	"audit.disabled":          "The audit log is disabled",
+	"audit.usage":             "Usage: /audit [number|csv|json]",
+	"audit.empty":             "The audit log is empty",
+	"translate.usage":         "Usage: /translate [<destination> <@mention|#hashtag> <text>]",
+	"translate.remove_usage":  "Usage: /untranslate <destination> <@mention|#hashtag>",
+	"translate.destination":   "%s is not a destination, use one of %s",
+	"translate.invalid":       "%s is not a mention or hashtag",
+	"translate.added":         "%s is now written %s on %s",
+	"translate.removed":       "%s is no longer rewritten on %s",
+	"translate.not_removable": "%s is configured for %s and can only be removed from the configuration",
+	"translate.not_found":     "%s is not rewritten on %s",
+	"translate.configured":    "(configured)",
+	"translate.empty":         "No mention or hashtag is rewritten",
+	"reply.not_allowed":       "You are not allowed to publish on Twitter",
+	"reply.prompt":            "Send me the reply to @%s or type /cancel to discard it",
+	"reply.published":         "Reply published",
+	"cancel.nothing":          "There is nothing to cancel",
+	"cancel.reply":            "Reply discarded",
+	"cancel.edit":             "Draft edit discarded",
+	"drafts.button.approve":   "Approve",
+	"drafts.button.edit":      "Edit",
+	"drafts.button.reject":    "Reject",
+	"drafts.from":             "Draft from %s:\n\n%s",
+	"drafts.user":             "user %s",
+	"drafts.submitted":        "Your post has been sent for review",
+	"drafts.approved":         "Your post has been approved",
+	"drafts.rejected":         "Your post has been rejected",
+	"drafts.published":        "Draft approved and published",
+	"drafts.discarded":        "Draft rejected",
+	"drafts.edit_prompt":      "Send me the new text of the draft or type /cancel to keep it",
+	"drafts.reviewed":         "This draft has already been reviewed",
+	"unauthorized.no_role":    "Sorry, you are not allowed to use this bot",
+	"unauthorized.denied":     "Sorry, you are not allowed to do that",
+	"access.button":           "Request access",
+	"access.user":             "User %s",
+	"access.requested":        "%s asks for access to the bot",
+	"access.sent":             "Your request has been sent to the owners",
+	"mentions.text":           "@%s mentioned you:\n\n%s\n\n%s",
+	"mentions.button.reply":   "Reply",
+	"errors.failed":           "Publishing to %s failed: %s",
+	"errors.suppressed":       "%d more failures were not notified",
+	"receipts.published":      "%s: published",
+	"receipts.failed":         "%s: failed, %s",
+	"receipts.skipped":        "%s: skipped",
+	"receipts.skipped_reason": "%s: skipped, %s",
+	"receipts.no_response":    "%s: no response",
+	"reload.rejected":         "Configuration reload rejected, %s",
+	"reload.unchanged":        "Configuration reloaded, nothing changed",
+	"reload.done":             "Configuration reloaded",
+	"reload.applied":          "Applied: %s",
+	"reload.restart":          "Needs a restart: %s",
+}
@@ -0,0 +1,78 @@
+package i18n
+
+var spanish = Catalog{
+	"start": "¡Gracias por usar el bot! Escribe /help para saber qué puedo hacer",
+
+	"command.addadmin":    "Añadir un administrador por ID de usuario o @usuario",
+	"command.admins":      "Listar los administradores",
+	"command.audit":       "Mostrar las últimas entradas del registro de auditoría o exportarlo en csv o json",
+	"command.cancel":      "Cancelar la respuesta pendiente a un tweet o la edición de un borrador",
+	"command.help":        "Mostrar la ayuda",
+	"command.post":        "Publicar el texto que sigue al comando, también en el grupo del equipo",
+	"command.removeadmin": "Quitar un administrador añadido con /addadmin",
+	"command.start":       "Empezar una conversación con el bot",
+	"command.stop":        "Detener las notificaciones de todos los handlers o de uno en concreto",
+	"command.translate":   "Listar las menciones y hashtags que se reescriben en cada destino o añadir uno",
+	"command.untranslate": "Dejar de reescribir una mención o hashtag añadido con /translate",
+
+	"admins.configured":       "(configurado)",
+	"admins.unknown":          "No conozco a %s, solo se añade por nombre a quien es del equipo y ha hablado con el bot",
+	"admins.add_usage":        "Uso: /addadmin <id|@usuario>",
+	"admins.already":          "%s ya es administrador",
+	"admins.added":            "%s ahora es administrador",
+	"admins.remove_usage":     "Uso: /removeadmin <id>",
+	"admins.not_removable":    "%s está configurado como administrador y solo se puede quitar desde la configuración",
+	"admins.not_admin":        "%s no es administrador",
+	"admins.removed":          "%s ya no es administrador",
+	"audit.disabled":          "El registro de auditoría está desactivado",
+	"audit.usage":             "Uso: /audit [número|csv|json]",
+	"audit.empty":             "El registro de auditoría está vacío",
+	"translate.usage":         "Uso: /translate [<destino> <@mención|#hashtag> <texto>]",
+	"translate.remove_usage":  "Uso: /untranslate <destino> <@mención|#hashtag>",
+	"translate.destination":   "%s no es un destino, usa uno de %s",
+	"translate.invalid":       "%s no es una mención ni un hashtag",
+	"translate.added":         "%s ahora se escribe %s en %s",
+	"translate.removed":       "%s ya no se reescribe en %s",
+	"translate.not_removable": "%s está configurado para %s y solo se puede quitar desde la configuración",
+	"translate.not_found":     "%s no se reescribe en %s",
+	"translate.configured":    "(configurado)",
+	"translate.empty":         "No se reescribe ninguna mención ni hashtag",
+	"reply.not_allowed":       "No tienes permiso para publicar en Twitter",
+	"reply.prompt":            "Envíame la respuesta a @%s o escribe /cancel para descartarla",
+	"reply.published":         "Respuesta publicada",
+	"cancel.nothing":          "No hay nada que cancelar",
+	"cancel.reply":            "Respuesta descartada",
+	"cancel.edit":             "Edición del borrador descartada",
+	"drafts.button.approve":   "Aprobar",
+	"drafts.button.edit":      "Editar",
+	"drafts.button.reject":    "Rechazar",
+	"drafts.from":             "Borrador de %s:\n\n%s",
+	"drafts.user":             "usuario %s",
+	"drafts.submitted":        "Tu publicación se ha enviado a revisión",
+	"drafts.approved":         "Tu publicación ha sido aprobada",
+	"drafts.rejected":         "Tu publicación ha sido rechazada",
+	"drafts.published":        "Borrador aprobado y publicado",
+	"drafts.discarded":        "Borrador rechazado",
+	"drafts.edit_prompt":      "Envíame el nuevo texto del borrador o escribe /cancel para mantenerlo",
+	"drafts.reviewed":         "Este borrador ya ha sido revisado",
+	"unauthorized.no_role":    "Lo siento, no tienes permiso para usar este bot",
+	"unauthorized.denied":     "Lo siento, no tienes permiso para hacer eso",
+	"access.button":           "Solicitar acceso",
+	"access.user":             "Usuario %s",
+	"access.requested":        "%s pide acceso al bot",
+	"access.sent":             "Tu solicitud se ha enviado a los propietarios",
+	"mentions.text":           "@%s te ha mencionado:\n\n%s\n\n%s",
+	"mentions.button.reply":   "Responder",
+	"errors.failed":           "Error al publicar en %s: %s",
+	"errors.suppressed":       "%d errores más no se han notificado",
+	"receipts.published":      "%s: publicado",
+	"receipts.failed":         "%s: error, %s",
+	"receipts.skipped":        "%s: omitido",
+	"receipts.skipped_reason": "%s: omitido, %s",
+	"receipts.no_response":    "%s: sin respuesta",
+	"reload.rejected":         "Recarga de la configuración rechazada, %s",
+	"reload.unchanged":        "Configuración recargada, no ha cambiado nada",
+	"reload.done":             "Configuración recargada",
+	"reload.applied":          "Aplicado: %s",
+	"reload.restart":          "Necesita reiniciar: %s",
+}
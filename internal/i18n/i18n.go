@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fallback is the language used when neither the user's nor the configured
+// one has a catalog.
+const Fallback = "en"
+
+// Catalog maps message keys to their text, which may hold fmt verbs.
+type Catalog map[string]string
+
+var catalogs = map[string]Catalog{
+	"en": english,
+	"es": spanish,
+}
+
+// Languages returns the languages there is a catalog for, sorted.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for l := range catalogs {
+		langs = append(langs, l)
+	}
+
+	sort.Strings(langs)
+
+	return langs
+}
+
+// Keys returns the keys in the catalog of the language, sorted.
+func Keys(lang string) []string {
+	keys := make([]string, 0, len(catalogs[lang]))
+	for k := range catalogs[lang] {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Lookup returns the text of the key in the catalog of the language.
+func Lookup(lang string, key string) (string, bool) {
+	text, ok := catalogs[lang][key]
+
+	return text, ok
+}
+
+// Language picks the catalog for a Telegram language code, which may carry a
+// region like es-ES, falling back to def and then to English.
+func Language(code string, def string) string {
+	for _, l := range []string{code, def} {
+		l = strings.ToLower(strings.SplitN(l, "-", 2)[0])
+		if _, ok := catalogs[l]; ok {
+			return l
+		}
+	}
+
+	return Fallback
+}
+
+// T formats the text of the key in the language, taking it from the English
+// catalog when missing there and using the key itself as last resort.
+func T(lang string, key string, args ...interface{}) string {
+	text, ok := Lookup(lang, key)
+	if !ok {
+		if text, ok = Lookup(Fallback, key); !ok {
+			text = key
+		}
+	}
+
+	if len(args) == 0 {
+		return text
+	}
+
+	return fmt.Sprintf(text, args...)
+}
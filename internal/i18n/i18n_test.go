@@ -0,0 +1,68 @@
+package i18n_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/javiyt/tweetgram/internal/i18n"
+	"github.com/stretchr/testify/require"
+)
+
+var verbs = regexp.MustCompile(`%[a-z]`)
+
+func TestCatalogs(t *testing.T) {
+	langs := i18n.Languages()
+
+	require.Equal(t, []string{"en", "es"}, langs)
+
+	for _, lang := range langs {
+		for _, other := range langs {
+			for _, key := range i18n.Keys(other) {
+				t.Run("it should have "+key+" in "+lang, func(t *testing.T) {
+					text, ok := i18n.Lookup(lang, key)
+					require.True(t, ok)
+					require.NotEmpty(t, text)
+
+					want, _ := i18n.Lookup(other, key)
+					require.Equal(t, verbs.FindAllString(want, -1), verbs.FindAllString(text, -1),
+						"format verbs differ from the %s catalog", other)
+				})
+			}
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		def      string
+		expected string
+	}{
+		{name: "it should use the language of the user", code: "es", def: "en", expected: "es"},
+		{name: "it should ignore the region", code: "es-MX", def: "en", expected: "es"},
+		{name: "it should use the default when there is no catalog", code: "fr", def: "es", expected: "es"},
+		{name: "it should use the default when the user has no language", code: "", def: "es", expected: "es"},
+		{name: "it should fall back to English", code: "fr", def: "", expected: "en"},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			require.Equal(t, testCases[i].expected, i18n.Language(testCases[i].code, testCases[i].def))
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	t.Run("it should format the text", func(t *testing.T) {
+		require.Equal(t, "@user ya es administrador", i18n.T("es", "admins.already", "@user"))
+	})
+
+	t.Run("it should use the English text when missing", func(t *testing.T) {
+		require.Equal(t, "Show help", i18n.T("fr", "command.help"))
+	})
+
+	t.Run("it should use the key when unknown", func(t *testing.T) {
+		require.Equal(t, "unknown", i18n.T("es", "unknown"))
+	})
+}
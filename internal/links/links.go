@@ -0,0 +1,217 @@
+// Package links cleans up the links in posts: shortened links are expanded,
+// tracking parameters removed and, for every destination, UTM parameters
+// added.
+package links
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/javiyt/tweetgram/internal/twittertext"
+)
+
+// Processor expands and cleans the links in a text before it's published.
+type Processor struct {
+	client     *http.Client
+	shorteners map[string]bool
+	strip      bool
+}
+
+type Option func(p *Processor)
+
+// WithHTTPClient sets the client following the redirects of shortened links.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Processor) {
+		p.client = c
+	}
+}
+
+// WithShorteners sets the hosts whose links are expanded, others are left as
+// they are so posts don't wait for every link to be fetched.
+func WithShorteners(hosts ...string) Option {
+	return func(p *Processor) {
+		for _, h := range hosts {
+			p.shorteners[normalizeHost(h)] = true
+		}
+	}
+}
+
+// WithTrackersStripped removes the utm_* and fbclid parameters from links.
+func WithTrackersStripped(strip bool) Option {
+	return func(p *Processor) {
+		p.strip = strip
+	}
+}
+
+func NewProcessor(options ...Option) *Processor {
+	p := &Processor{client: http.DefaultClient, shorteners: make(map[string]bool)}
+
+	for _, o := range options {
+		o(p)
+	}
+
+	return p
+}
+
+// Process expands the shortened links in text and strips their trackers.
+// Links that can't be expanded, because the shortener is down or ctx is done,
+// are only stripped.
+func (p *Processor) Process(ctx context.Context, text string) string {
+	return rewrite(text, func(link string) string {
+		if u, err := url.Parse(withScheme(link)); err == nil && p.shorteners[normalizeHost(u.Hostname())] {
+			if expanded, err := p.expand(ctx, u.String()); err == nil {
+				link = expanded
+			}
+		}
+
+		if p.strip {
+			link = Strip(link)
+		}
+
+		return link
+	})
+}
+
+// expand follows the redirects of link, asking only for the headers unless
+// the shortener doesn't support it.
+func (p *Processor) expand(ctx context.Context, link string) (string, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		var req *http.Request
+
+		req, err = http.NewRequestWithContext(ctx, method, link, http.NoBody)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err = p.client.Do(req)
+		if err != nil {
+			continue
+		}
+
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			break
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Request.URL.String(), nil
+}
+
+// Strip removes the utm_* and fbclid parameters from link, keeping the rest
+// in the order they were.
+func Strip(link string) string {
+	return editQuery(link, func(params []string) []string {
+		kept := params[:0]
+
+		for _, p := range params {
+			name := strings.ToLower(strings.SplitN(p, "=", 2)[0])
+			if !strings.HasPrefix(name, "utm_") && name != "fbclid" {
+				kept = append(kept, p)
+			}
+		}
+
+		return kept
+	})
+}
+
+// Tag adds the parameters to every link in text, replacing the ones with the
+// same name the links already had.
+func Tag(text string, params map[string]string) string {
+	if len(params) == 0 {
+		return text
+	}
+
+	names := make([]string, 0, len(params))
+	for n := range params {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	return rewrite(text, func(link string) string {
+		return editQuery(link, func(query []string) []string {
+			tagged := query[:0]
+
+			for _, q := range query {
+				if _, ok := params[strings.SplitN(q, "=", 2)[0]]; !ok {
+					tagged = append(tagged, q)
+				}
+			}
+
+			for _, n := range names {
+				tagged = append(tagged, url.QueryEscape(n)+"="+url.QueryEscape(params[n]))
+			}
+
+			return tagged
+		})
+	})
+}
+
+// rewrite replaces every link in text with what edit returns for it.
+func rewrite(text string, edit func(string) string) string {
+	var (
+		sb   strings.Builder
+		last int
+	)
+
+	for _, e := range twittertext.ExtractURLs(text) {
+		sb.WriteString(text[last:e.Start])
+		sb.WriteString(edit(e.Value))
+		last = e.End
+	}
+
+	sb.WriteString(text[last:])
+
+	return sb.String()
+}
+
+// editQuery hands the parameters of the link, as written, to edit and puts
+// back the ones it returns. The rest of the link is left untouched, as
+// parsing and printing it again would escape what the author didn't.
+func editQuery(link string, edit func([]string) []string) string {
+	rest, fragment, hasFragment := strings.Cut(link, "#")
+	base, query, _ := strings.Cut(rest, "?")
+
+	var params []string
+	if query != "" {
+		params = strings.Split(query, "&")
+	}
+
+	link = base
+	if params = edit(params); len(params) > 0 {
+		link += "?" + strings.Join(params, "&")
+	}
+
+	if hasFragment {
+		link += "#" + fragment
+	}
+
+	return link
+}
+
+// withScheme adds http to links written without scheme, like bit.ly/x, so
+// their host can be parsed and fetched.
+func withScheme(link string) string {
+	if strings.Contains(link, "://") {
+		return link
+	}
+
+	return "http://" + link
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
@@ -0,0 +1,193 @@
+package links_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/links"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessor_Process(t *testing.T) {
+	ctx := context.Background()
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer site.Close()
+
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+
+	requested := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		m := methods
+		methods = nil
+
+		return m
+	}
+
+	shortener := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/slow" {
+			mu.Lock()
+			methods = append(methods, r.Method)
+			mu.Unlock()
+		}
+
+		switch r.URL.Path {
+		case "/abc":
+			http.Redirect(w, r, "/hop", http.StatusMovedPermanently)
+		case "/hop":
+			http.Redirect(w, r, "http://example.org/article?id=1&utm_source=newsletter&fbclid=xyz", http.StatusFound)
+		case "/get":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+
+				return
+			}
+
+			http.Redirect(w, r, "http://example.org/other", http.StatusFound)
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+			http.Redirect(w, r, "http://example.org/late", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer shortener.Close()
+
+	client := &http.Client{Transport: rerouted{"bit.ly": shortener.URL, "example.org": site.URL}}
+
+	p := links.NewProcessor(
+		links.WithHTTPClient(client),
+		links.WithShorteners("bit.ly"),
+		links.WithTrackersStripped(true),
+	)
+
+	t.Run("it should expand shortened links and strip their trackers", func(t *testing.T) {
+		require.Equal(t, "Read http://example.org/article?id=1 now.", p.Process(ctx, "Read https://bit.ly/abc now."))
+		require.Equal(t, []string{http.MethodHead, http.MethodHead}, requested())
+	})
+
+	t.Run("it should expand shortened links written without scheme", func(t *testing.T) {
+		require.Equal(t, "Read http://example.org/article?id=1 now.", p.Process(ctx, "Read bit.ly/abc now."))
+		require.Equal(t, []string{http.MethodHead, http.MethodHead}, requested())
+	})
+
+	t.Run("it should fall back to GET when the shortener doesn't support HEAD", func(t *testing.T) {
+		require.Equal(t, "http://example.org/other", p.Process(ctx, "https://bit.ly/get"))
+		require.Equal(t, []string{http.MethodHead, http.MethodGet}, requested())
+	})
+
+	t.Run("it should keep links it can't expand", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		require.Equal(t, "https://bit.ly/slow", p.Process(ctx, "https://bit.ly/slow?utm_medium=social"))
+	})
+
+	t.Run("it should not fetch other links", func(t *testing.T) {
+		require.Equal(t, "https://example.com/a?b=1#top", p.Process(ctx, "https://example.com/a?b=1&fbclid=x#top"))
+		require.Empty(t, requested())
+	})
+
+	t.Run("it should keep trackers when not stripping them", func(t *testing.T) {
+		p := links.NewProcessor(links.WithHTTPClient(client), links.WithShorteners("bit.ly"))
+
+		require.Equal(t,
+			"http://example.org/article?id=1&utm_source=newsletter&fbclid=xyz",
+			p.Process(ctx, "https://bit.ly/abc"),
+		)
+	})
+}
+
+// rerouted sends the requests for the hosts in it to the test servers, as
+// links to them are the only ones found in a text.
+type rerouted map[string]string
+
+func (r rerouted) RoundTrip(req *http.Request) (*http.Response, error) {
+	server, ok := r[req.URL.Hostname()]
+	if !ok {
+		return nil, http.ErrNotSupported
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Scheme = u.Scheme
+	out.URL.Host = u.Host
+
+	resp, err := http.DefaultTransport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Request = req
+
+	return resp, nil
+}
+
+func TestStrip(t *testing.T) {
+	testCases := []struct {
+		name     string
+		link     string
+		expected string
+	}{
+		{
+			name:     "it should remove tracking parameters keeping the order of the rest",
+			link:     "https://example.com/a?z=1&UTM_Source=x&utm_campaign=y&a=2&fbclid=abc#top",
+			expected: "https://example.com/a?z=1&a=2#top",
+		},
+		{
+			name:     "it should remove the query when only trackers were there",
+			link:     "https://example.com/?utm_source=x",
+			expected: "https://example.com/",
+		},
+		{
+			name:     "it should leave the rest of the link as written",
+			link:     "https://es.wikipedia.org/wiki/Año?x=a%20b",
+			expected: "https://es.wikipedia.org/wiki/Año?x=a%20b",
+		},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			require.Equal(t, testCases[i].expected, links.Strip(testCases[i].link))
+		})
+	}
+}
+
+func TestTag(t *testing.T) {
+	utm := map[string]string{"utm_source": "twitter", "utm_medium": "social media"}
+
+	t.Run("it should add the parameters to every link", func(t *testing.T) {
+		require.Equal(t,
+			"see https://example.com/a?id=1&utm_medium=social+media&utm_source=twitter and "+
+				"http://example.org/?utm_medium=social+media&utm_source=twitter#top!",
+			links.Tag("see https://example.com/a?id=1&utm_source=telegram and http://example.org/#top!", utm),
+		)
+	})
+
+	t.Run("it should add the parameters to links written without scheme", func(t *testing.T) {
+		require.Equal(t,
+			"more at bit.ly/x?utm_medium=social+media&utm_source=twitter.",
+			links.Tag("more at bit.ly/x.", utm),
+		)
+	})
+
+	t.Run("it should leave the text as it is without parameters", func(t *testing.T) {
+		require.Equal(t, "https://example.com/a?utm_source=x", links.Tag("https://example.com/a?utm_source=x", nil))
+	})
+}
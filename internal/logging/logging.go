@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"context"
+	"io"
+
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const CorrelationIDField = "correlation_id"
+
+type fieldsKey struct{}
+
+// NewLogger returns a logger writing to out in the format and level selected
+// in the configuration, adding to every entry the fields found in its context.
+func NewLogger(cfg config.AppConfig, out io.Writer) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(out)
+	logger.SetReportCaller(true)
+	logger.AddHook(ContextHook{})
+
+	if cfg.LogFormat == "json" {
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		logger.SetFormatter(&logrus.TextFormatter{
+			DisableColors: true,
+			FullTimestamp: true,
+		})
+	}
+
+	logger.SetLevel(logrus.DebugLevel)
+	if cfg.IsProd() {
+		logger.SetLevel(logrus.ErrorLevel)
+	}
+
+	return logger
+}
+
+// Discard returns a logger dropping every entry, used by components created
+// without a logger.
+func Discard() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return logger
+}
+
+// WithFields returns a copy of ctx carrying the given fields along with the
+// ones already stored in it.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	merged := make(logrus.Fields, len(fields))
+	for k, v := range Fields(ctx) {
+		merged[k] = v
+	}
+
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+func Fields(ctx context.Context) logrus.Fields {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+
+	return fields
+}
+
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return WithFields(ctx, logrus.Fields{CorrelationIDField: id})
+}
+
+// CorrelationID returns the ID shared by everything done on behalf of the
+// same Telegram update, empty when ctx doesn't carry one.
+func CorrelationID(ctx context.Context) string {
+	id, _ := Fields(ctx)[CorrelationIDField].(string)
+
+	return id
+}
+
+// ContextHook adds to log entries the fields and trace ID stored in the
+// context passed with Logger.WithContext.
+type ContextHook struct{}
+
+func (ContextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (ContextHook) Fire(e *logrus.Entry) error {
+	if e.Context == nil {
+		return nil
+	}
+
+	for k, v := range Fields(e.Context) {
+		if _, ok := e.Data[k]; !ok {
+			e.Data[k] = v
+		}
+	}
+
+	if sc := trace.SpanContextFromContext(e.Context); sc.IsValid() {
+		e.Data["trace_id"] = sc.TraceID().String()
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestNewLogger(t *testing.T) {
+	ctx := logging.WithCorrelationID(context.Background(), "update-1234")
+
+	t.Run("it should write json entries with context fields", func(t *testing.T) {
+		var out bytes.Buffer
+
+		log := logging.NewLogger(config.AppConfig{LogFormat: "json"}, &out)
+		log.WithContext(ctx).WithField("handler", "twitter").Info("message delivered")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+		require.Equal(t, "message delivered", entry["msg"])
+		require.Equal(t, "update-1234", entry["correlation_id"])
+		require.Equal(t, "twitter", entry["handler"])
+	})
+
+	t.Run("it should write text entries by default", func(t *testing.T) {
+		var out bytes.Buffer
+
+		log := logging.NewLogger(config.AppConfig{}, &out)
+		log.WithContext(ctx).Info("message delivered")
+
+		require.Contains(t, out.String(), "msg=\"message delivered\"")
+		require.Contains(t, out.String(), "correlation_id=update-1234")
+	})
+
+	t.Run("it should only log errors in production", func(t *testing.T) {
+		log := logging.NewLogger(config.AppConfig{Environment: "PROD"}, &bytes.Buffer{})
+
+		require.Equal(t, logrus.ErrorLevel, log.GetLevel())
+	})
+
+	t.Run("it should add trace id to entries", func(t *testing.T) {
+		var out bytes.Buffer
+
+		traced, span := sdktrace.NewTracerProvider().Tracer("testing").Start(ctx, "testing span")
+		defer span.End()
+
+		log := logging.NewLogger(config.AppConfig{LogFormat: "json"}, &out)
+		log.WithContext(traced).Info("message delivered")
+
+		require.Contains(t, out.String(), "\"trace_id\":\""+span.SpanContext().TraceID().String()+"\"")
+	})
+}
+
+func TestWithFields(t *testing.T) {
+	ctx := logging.WithFields(context.Background(), logrus.Fields{"handler": "twitter", "topic": "text"})
+
+	t.Run("it should merge fields with the ones already in context", func(t *testing.T) {
+		merged := logging.WithFields(ctx, logrus.Fields{"topic": "photo", "message_uuid": "1234"})
+
+		require.Equal(t, logrus.Fields{"handler": "twitter", "topic": "photo", "message_uuid": "1234"},
+			logging.Fields(merged))
+		require.Equal(t, logrus.Fields{"handler": "twitter", "topic": "text"}, logging.Fields(ctx))
+	})
+
+	t.Run("it should return empty correlation id when not set", func(t *testing.T) {
+		require.Empty(t, logging.CorrelationID(ctx))
+		require.Equal(t, "5678", logging.CorrelationID(logging.WithCorrelationID(ctx, "5678")))
+	})
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tweetgram"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	UpdatesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_updates_received_total",
+		Help:      "Telegram updates received per bot endpoint.",
+	}, []string{"endpoint"})
+
+	EventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Events published to the queue per topic.",
+	}, []string{"topic"})
+
+	Deliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deliveries_total",
+		Help:      "Events delivered by every handler, by result.",
+	}, []string{"handler", "result"})
+
+	TwitterRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "twitter_request_duration_seconds",
+		Help:      "Latency of Twitter API requests per operation and HTTP status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "code"})
+
+	TelegramSendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "telegram_send_duration_seconds",
+		Help:      "Latency of messages sent to Telegram per message type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+
+	HandlerPaused = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "handler_paused",
+		Help:      "Whether notifications are stopped for the handler.",
+	}, []string{"handler"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		UpdatesReceived,
+		EventsPublished,
+		Deliveries,
+		TwitterRequestDuration,
+		TelegramSendDuration,
+		HandlerPaused,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+func Delivered(handler string, err error) {
+	result := "succeeded"
+	if err != nil {
+		result = "failed"
+	}
+
+	Deliveries.WithLabelValues(handler, result).Inc()
+}
+
+// ObserveTwitterRequest records the latency of a Twitter API request started
+// at the given time, resp can be nil when the request didn't get a response.
+func ObserveTwitterRequest(operation string, start time.Time, resp *http.Response) {
+	code := "error"
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+
+	TwitterRequestDuration.WithLabelValues(operation, code).Observe(time.Since(start).Seconds())
+}
+
+func Paused(handler string, paused bool) {
+	v := 0.0
+	if paused {
+		v = 1
+	}
+
+	HandlerPaused.WithLabelValues(handler).Set(v)
+}
@@ -0,0 +1,110 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type deliveryError struct{}
+
+func (deliveryError) Error() string {
+	return "delivery failed"
+}
+
+func TestDelivered(t *testing.T) {
+	t.Run("it should count succeeded deliveries", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.Deliveries.WithLabelValues("delivered", "succeeded"))
+
+		metrics.Delivered("delivered", nil)
+
+		require.Equal(t, before+1, testutil.ToFloat64(metrics.Deliveries.WithLabelValues("delivered", "succeeded")))
+	})
+
+	t.Run("it should count failed deliveries", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.Deliveries.WithLabelValues("delivered", "failed"))
+
+		metrics.Delivered("delivered", deliveryError{})
+
+		require.Equal(t, before+1, testutil.ToFloat64(metrics.Deliveries.WithLabelValues("delivered", "failed")))
+	})
+}
+
+func TestPaused(t *testing.T) {
+	t.Run("it should mark handler as paused", func(t *testing.T) {
+		metrics.Paused("paused", true)
+
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.HandlerPaused.WithLabelValues("paused")))
+	})
+
+	t.Run("it should mark handler as running", func(t *testing.T) {
+		metrics.Paused("paused", false)
+
+		require.Equal(t, 0.0, testutil.ToFloat64(metrics.HandlerPaused.WithLabelValues("paused")))
+	})
+}
+
+func TestObserveTwitterRequest(t *testing.T) {
+	t.Run("it should label request with response status code", func(t *testing.T) {
+		series := "tweetgram_twitter_request_duration_seconds_count{code=\"403\",operation=\"forbidden\"}"
+		before := value(t, series)
+
+		metrics.ObserveTwitterRequest("forbidden", time.Now(), &http.Response{StatusCode: http.StatusForbidden})
+
+		require.Equal(t, before+1, value(t, series))
+	})
+
+	t.Run("it should label request without response as error", func(t *testing.T) {
+		series := "tweetgram_twitter_request_duration_seconds_count{code=\"error\",operation=\"unanswered\"}"
+		before := value(t, series)
+
+		metrics.ObserveTwitterRequest("unanswered", time.Now(), nil)
+
+		require.Equal(t, before+1, value(t, series))
+	})
+}
+
+func TestHandler(t *testing.T) {
+	series := "tweetgram_events_published_total{topic=\"exposed\"}"
+	before := value(t, series)
+
+	metrics.EventsPublished.WithLabelValues("exposed").Inc()
+
+	require.Equal(t, before+1, value(t, series))
+	require.Contains(t, scrape(t), "go_goroutines")
+}
+
+func scrape(t *testing.T) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	return rec.Body.String()
+}
+
+// value returns the value of the series in the exposed metrics, zero while it
+// hasn't been recorded yet.
+func value(t *testing.T, series string) float64 {
+	t.Helper()
+
+	for _, line := range strings.Split(scrape(t), "\n") {
+		if v, ok := strings.CutPrefix(line, series+" "); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			require.NoError(t, err)
+
+			return f
+		}
+	}
+
+	return 0
+}
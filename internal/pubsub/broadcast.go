@@ -2,6 +2,7 @@ package pubsub
 
 import (
 	"context"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill/message"
 )
@@ -16,12 +17,30 @@ const (
 	PhotoTopic
 	TextTopic
 	CommandTopic
+	TweetTopic
+	ResultTopic
 )
 
 const (
 	StopCommand CommandName = iota
 )
 
+// Metadata keys set on every message published to the queue.
+const (
+	CorrelationIDKey = "correlation_id"
+	PublishedAtKey   = "published_at"
+	SenderIDKey      = "sender_id"
+	MessageIDKey     = "message_id"
+	DestinationsKey  = "destinations"
+	AuthorKey        = "author"
+	// LanguageKey is the language code of the sender's Telegram app, so what
+	// is sent back to them about the message is in their language.
+	LanguageKey = "language"
+	// PreviewKey is only set when the sender chose whether to show the link
+	// preview.
+	PreviewKey = "preview"
+)
+
 type Queue interface {
 	Publish(topic string, messages ...*message.Message) error
 	Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error)
@@ -30,7 +49,14 @@ type Queue interface {
 
 //easyjson:json
 type ErrorEvent struct {
-	Err string `json:"error"`
+	Err           string     `json:"error"`
+	Handler       string     `json:"handler,omitempty"`
+	Topic         string     `json:"topic,omitempty"`
+	MessageUUID   string     `json:"messageUuid,omitempty"`
+	CorrelationID string     `json:"correlationId,omitempty"`
+	SenderID      string     `json:"senderId,omitempty"`
+	Language      string     `json:"language,omitempty"`
+	PublishedAt   *time.Time `json:"publishedAt,omitempty"`
 }
 
 //easyjson:json
@@ -52,3 +78,21 @@ type CommandEvent struct {
 	Command CommandName `json:"command"`
 	Handler string      `json:"handler"`
 }
+
+//easyjson:json
+type TweetEvent struct {
+	ID     int64    `json:"id"`
+	Text   string   `json:"text"`
+	URL    string   `json:"url"`
+	Photos []string `json:"photos"`
+}
+
+//easyjson:json
+type ResultEvent struct {
+	MessageUUID string   `json:"messageUuid"`
+	Handler     string   `json:"handler"`
+	URLs        []string `json:"urls,omitempty"`
+	Err         string   `json:"error,omitempty"`
+	Skipped     bool     `json:"skipped,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+}
@@ -0,0 +1,74 @@
+package render
+
+import (
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/twittertext"
+)
+
+var funcs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// Post is what destination templates are executed with: the text or photo
+// caption, the username of the admin who sent it, when it was sent and the
+// hashtags in the text, without the # sign.
+type Post struct {
+	Text     string
+	Author   string
+	Date     time.Time
+	Hashtags []string
+	Photo    bool
+}
+
+// NewPost builds the post for the text, extracting its hashtags.
+func NewPost(text string, author string, date time.Time, photo bool) Post {
+	return Post{
+		Text:     text,
+		Author:   author,
+		Date:     date,
+		Hashtags: twittertext.ExtractHashtags(text),
+		Photo:    photo,
+	}
+}
+
+// Parse parses the template and executes it with an empty post, so templates
+// using fields that don't exist are caught when the configuration is loaded.
+func Parse(text string) (*template.Template, error) {
+	t, err := template.New("post").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Execute(io.Discard, Post{}); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Text renders the post with the template, an empty template leaves the text
+// as it is.
+func Text(text string, p Post) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return p.Text, nil
+	}
+
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, p); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
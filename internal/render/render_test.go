@@ -0,0 +1,105 @@
+package render_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/render"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPost(t *testing.T) {
+	date := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("it should extract the hashtags", func(t *testing.T) {
+		p := render.NewPost("#Go is fun, #año_nuevo! not a#tag nor # alone", "admin", date, false)
+
+		require.Equal(t, []string{"Go", "año_nuevo"}, p.Hashtags)
+		require.Equal(t, "admin", p.Author)
+		require.Equal(t, date, p.Date)
+	})
+
+	t.Run("it should have no hashtags", func(t *testing.T) {
+		require.Empty(t, render.NewPost("testing", "", date, true).Hashtags)
+	})
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "it should parse the template", text: "{{.Text}}"},
+		{
+			name:     "it should fail with a malformed template",
+			text:     "{{.Text",
+			expected: "template: post:1: unclosed action",
+		},
+		{
+			name:     "it should fail with an unknown field",
+			text:     "{{.Handle}}",
+			expected: "template: post:1:2: executing \"post\" at <.Handle>: can't evaluate field Handle in type render.Post",
+		},
+		{
+			name:     "it should fail with an unknown function",
+			text:     "{{title .Text}}",
+			expected: "template: post:1: function \"title\" not defined",
+		},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			_, err := render.Parse(testCases[i].text)
+			if testCases[i].expected == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.EqualError(t, err, testCases[i].expected)
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	p := render.NewPost("Release day #Go #tweetgram", "admin", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), true)
+
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "it should leave the text as it is with an empty template", text: " ", expected: p.Text},
+		{
+			name:     "it should render the fields",
+			text:     "{{.Text}}\n\n{{if .Photo}}📷 {{end}}@{{.Author}} {{.Date.Format \"02/01/2006\"}}",
+			expected: "Release day #Go #tweetgram\n\n📷 @admin 01/03/2024",
+		},
+		{
+			name:     "it should use the functions",
+			text:     "{{upper .Author}} {{join .Hashtags \", \" | lower}}",
+			expected: "ADMIN go, tweetgram",
+		},
+		{
+			name:     "it should trim the output",
+			text:     "\n{{.Author}}\n\n",
+			expected: "admin",
+		},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			text, err := render.Text(testCases[i].text, p)
+
+			require.NoError(t, err)
+			require.Equal(t, testCases[i].expected, text)
+		})
+	}
+
+	t.Run("it should fail with a malformed template", func(t *testing.T) {
+		_, err := render.Text("{{.Text", p)
+
+		require.Error(t, err)
+	})
+}
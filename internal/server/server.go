@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const readHeaderTimeout = 5 * time.Second
+
+type Router interface {
+	Routes() map[string]http.Handler
+}
+
+type Server struct {
+	mux *http.ServeMux
+	srv *http.Server
+}
+
+func NewServer(addr string, routers ...Router) *Server {
+	mux := http.NewServeMux()
+
+	s := &Server{
+		mux: mux,
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+	}
+
+	for _, r := range routers {
+		for pattern, h := range r.Routes() {
+			s.Handle(pattern, h)
+		}
+	}
+
+	return s
+}
+
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		_ = s.srv.Serve(l)
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	if err := s.srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
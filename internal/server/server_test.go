@@ -0,0 +1,72 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/javiyt/tweetgram/internal/server"
+	"github.com/stretchr/testify/require"
+)
+
+type router map[string]http.Handler
+
+func (r router) Routes() map[string]http.Handler {
+	return r
+}
+
+func TestServer_Handle(t *testing.T) {
+	s := server.NewServer("", router{
+		"/test": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("router"))
+		}),
+	})
+	s.Handle("/other", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("other"))
+	}))
+
+	for path, expected := range map[string]string{"/test": "router", "/other": "other"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, expected, rec.Body.String())
+	}
+}
+
+func TestServer_StartStop(t *testing.T) {
+	t.Run("it should fail when address is not valid", func(t *testing.T) {
+		require.Error(t, server.NewServer("invalid:address:1").Start())
+	})
+
+	t.Run("it should serve requests until stopped", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		addr := l.Addr().String()
+		_ = l.Close()
+
+		s := server.NewServer(addr, router{
+			"/test": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("running"))
+			}),
+		})
+
+		require.NoError(t, s.Start())
+
+		resp, err := http.Get("http://" + addr + "/test")
+		require.NoError(t, err)
+
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		require.Equal(t, "running", string(body))
+		require.NoError(t, s.Stop(context.Background()))
+
+		_, err = http.Get("http://" + addr + "/test")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("key not found")
+
+type Store interface {
+	Load(key string, v interface{}) error
+	Save(key string, v interface{}) error
+	Delete(key string) error
+}
+
+type FileStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (fs *FileStore) Load(key string, v interface{}) error {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	content, err := os.ReadFile(fs.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
+
+func (fs *FileStore) Save(key string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	p := fs.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, p)
+}
+
+func (fs *FileStore) Delete(key string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	err := os.Remove(fs.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (fs *FileStore) path(key string) string {
+	parts := strings.Split(key, "/")
+	for i := range parts {
+		parts[i] = strings.ReplaceAll(parts[i], "..", "")
+	}
+
+	return filepath.Join(append([]string{fs.dir}, parts...)...) + ".json"
+}
@@ -0,0 +1,78 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type storedValue struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestFileStore_Load(t *testing.T) {
+	dir := t.TempDir()
+	fs := storage.NewFileStore(dir)
+
+	t.Run("it should fail when key not found", func(t *testing.T) {
+		var v storedValue
+
+		require.ErrorIs(t, fs.Load("missing", &v), storage.ErrNotFound)
+	})
+
+	t.Run("it should fail when stored content is not valid", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{\"name\""), 0o600))
+
+		var v storedValue
+
+		require.EqualError(t, fs.Load("invalid", &v), "unexpected end of JSON input")
+	})
+
+	t.Run("it should load a saved value", func(t *testing.T) {
+		require.NoError(t, fs.Save("nested/value", storedValue{Name: "test", Count: 2}))
+
+		var v storedValue
+
+		require.NoError(t, fs.Load("nested/value", &v))
+		require.Equal(t, storedValue{Name: "test", Count: 2}, v)
+	})
+}
+
+func TestFileStore_Save(t *testing.T) {
+	dir := t.TempDir()
+	fs := storage.NewFileStore(dir)
+
+	t.Run("it should fail when value can't be marshaled", func(t *testing.T) {
+		require.Error(t, fs.Save("channel", make(chan int)))
+	})
+
+	t.Run("it should write value inside store directory", func(t *testing.T) {
+		require.NoError(t, fs.Save("../outside/value", storedValue{Name: "test"}))
+
+		content, err := os.ReadFile(filepath.Join(dir, "outside", "value.json"))
+
+		require.NoError(t, err)
+		require.JSONEq(t, "{\"name\":\"test\",\"count\":0}", string(content))
+	})
+}
+
+func TestFileStore_Delete(t *testing.T) {
+	fs := storage.NewFileStore(t.TempDir())
+
+	t.Run("it should not fail when key not found", func(t *testing.T) {
+		require.NoError(t, fs.Delete("missing"))
+	})
+
+	t.Run("it should delete a saved value", func(t *testing.T) {
+		require.NoError(t, fs.Save("value", storedValue{Name: "test"}))
+		require.NoError(t, fs.Delete("value"))
+
+		var v storedValue
+
+		require.ErrorIs(t, fs.Load("value", &v), storage.ErrNotFound)
+	})
+}
@@ -1,17 +1,31 @@
 package telegram
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ThreeDotsLabs/watermill"
 	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/logging"
+	"github.com/javiyt/tweetgram/internal/metrics"
+	"github.com/javiyt/tweetgram/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	tb "gopkg.in/telebot.v3"
 )
 
-const telegramMessageLength = 4096
+const (
+	telegramMessageLength = 4096
+	pollingTimeout        = 10 * time.Second
+)
 
 type TbBot interface {
 	Start()
@@ -21,14 +35,62 @@ type TbBot interface {
 	Send(to tb.Recipient, what interface{}, opts ...interface{}) (*tb.Message, error)
 	File(file *tb.File) (io.ReadCloser, error)
 	FileByID(fileID string) (tb.File, error)
+	RemoveWebhook(dropPending ...bool) error
 }
 
 type Bot struct {
-	b TbBot
+	b       TbBot
+	webhook bool
+}
+
+type Option func(b *Bot)
+
+// WithWebhook makes the bot remove the registered webhook when stopped, the
+// Webhook poller registers it on start.
+func WithWebhook() Option {
+	return func(b *Bot) {
+		b.webhook = true
+	}
+}
+
+func NewBot(b TbBot, options ...Option) bot.TelegramBot {
+	bt := &Bot{b: b}
+
+	for _, o := range options {
+		o(bt)
+	}
+
+	return bt
 }
 
-func NewBot(b TbBot) bot.TelegramBot {
-	return &Bot{b: b}
+// NewPoller returns the poller receiving Telegram updates for the configured
+// update mode. Webhooks report to h whether they are listening, long polling
+// is tracked by the Health transport.
+func NewPoller(cfg config.AppConfig, h *Health) tb.Poller {
+	if !cfg.IsWebhook() {
+		return &tb.LongPoller{Timeout: pollingTimeout}
+	}
+
+	w := &Webhook{
+		Webhook: tb.Webhook{
+			Listen:      cfg.WebhookListen,
+			SecretToken: cfg.WebhookSecretToken,
+		},
+		Health: h,
+	}
+
+	if cfg.WebhookTLSCert != "" && cfg.WebhookTLSKey != "" {
+		w.TLS = &tb.WebhookTLS{Key: cfg.WebhookTLSKey, Cert: cfg.WebhookTLSCert}
+	}
+
+	if cfg.WebhookURL != "" {
+		w.Endpoint = &tb.WebhookEndpoint{PublicURL: cfg.WebhookURL}
+		if w.TLS != nil {
+			w.Endpoint.Cert = w.TLS.Cert
+		}
+	}
+
+	return w
 }
 
 func (b *Bot) Start() {
@@ -37,9 +99,13 @@ func (b *Bot) Start() {
 
 func (b *Bot) Stop() {
 	b.b.Stop()
+
+	if b.webhook {
+		_ = b.b.RemoveWebhook()
+	}
 }
 
-func (b *Bot) SetCommands(commands []bot.TelegramBotCommand) error {
+func (b *Bot) SetCommands(commands []bot.TelegramBotCommand, options ...interface{}) error {
 	cmd := make([]tb.Command, 0, len(commands))
 
 	for i := range commands {
@@ -49,7 +115,18 @@ func (b *Bot) SetCommands(commands []bot.TelegramBotCommand) error {
 		})
 	}
 
-	return b.b.SetCommands(cmd)
+	opts := []interface{}{cmd}
+
+	for _, o := range options {
+		switch v := o.(type) {
+		case bot.TelegramCommandScope:
+			opts = append(opts, tb.CommandScope{Type: tb.CommandScopeChat, ChatID: int64(v)})
+		case bot.TelegramLanguage:
+			opts = append(opts, string(v))
+		}
+	}
+
+	return b.b.SetCommands(opts...)
 }
 
 func (b *Bot) Handle(endpoint string, handler bot.TelegramHandler) {
@@ -64,35 +141,157 @@ func (b *Bot) Handle(endpoint string, handler bot.TelegramHandler) {
 			}
 		}
 
-		return handler(bot.TelegramMessage{
-			SenderID:  fmt.Sprintf("%v", m.Sender().ID),
-			Text:      m.Text(),
-			Payload:   m.Message().Payload,
-			Photo:     p,
-			IsPrivate: m.Chat().Private,
+		text := m.Text()
+
+		mention, mentioned := mentionsBot(m)
+		if mentioned {
+			text = strings.TrimSpace(strings.Replace(text, mention, "", 1))
+			p.Caption = strings.TrimSpace(strings.Replace(p.Caption, mention, "", 1))
+		}
+
+		var data string
+		if c := m.Callback(); c != nil {
+			data = c.Data
+			_ = m.Respond()
+		}
+
+		ctx := logging.WithFields(context.Background(), logrus.Fields{
+			logging.CorrelationIDField: watermill.NewUUID(),
+			"endpoint":                 strings.TrimLeft(endpoint, "\a\f"),
+			"sender_id":                m.Sender().ID,
 		})
+
+		ctx, span := tracing.Tracer().Start(
+			ctx,
+			"telegram update "+strings.TrimLeft(endpoint, "\a\f"),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.Int64("telegram.sender_id", m.Sender().ID)),
+		)
+
+		err := handler(ctx, bot.TelegramMessage{
+			SenderID:       fmt.Sprintf("%v", m.Sender().ID),
+			SenderUsername: m.Sender().Username,
+			LanguageCode:   m.Sender().LanguageCode,
+			ChatID:         m.Chat().ID,
+			MessageID:      m.Message().ID,
+			Text:           text,
+			Payload:        m.Message().Payload,
+			CallbackData:   data,
+			Photo:          p,
+			Forward:        forwardedFrom(m.Message()),
+			IsPrivate:      m.Chat().Private,
+			Mentioned:      mentioned,
+			Preview:        preview(m.Message()),
+		})
+		tracing.End(span, err)
+
+		return err
 	})
 }
 
-func (b *Bot) Send(to string, what interface{}, options ...interface{}) error {
+// mentionsBot returns the mention of the bot in the text or caption of the
+// message, if any.
+func mentionsBot(c tb.Context) (string, bool) {
+	msg := c.Message()
+
+	for _, e := range append(msg.Entities, msg.CaptionEntities...) {
+		if e.Type != tb.EntityMention {
+			continue
+		}
+
+		if mention := msg.EntityText(e); strings.EqualFold(mention, "@"+c.Bot().Me.Username) {
+			return mention, true
+		}
+	}
+
+	return "", false
+}
+
+// preview returns whether the sender chose to show the link preview, nil when
+// they didn't change it.
+func preview(msg *tb.Message) *bool {
+	if msg.PreviewOptions == nil {
+		return nil
+	}
+
+	show := !msg.PreviewOptions.Disabled
+
+	return &show
+}
+
+// forwardedFrom returns the channel the message was forwarded from, with a link
+// to the original post when the channel is public.
+func forwardedFrom(msg *tb.Message) bot.TelegramForward {
+	if msg.OriginalChat == nil || msg.OriginalChat.Type != tb.ChatChannel {
+		return bot.TelegramForward{}
+	}
+
+	f := bot.TelegramForward{Channel: msg.OriginalChat.Title}
+	if msg.OriginalChat.Username != "" {
+		f.URL = fmt.Sprintf("https://t.me/%s/%d", msg.OriginalChat.Username, msg.OriginalMessageID)
+	}
+
+	return f
+}
+
+func (b *Bot) Send(ctx context.Context, to string, what interface{}, options ...interface{}) (err error) {
 	toInt, err := strconv.ParseFloat(to, 0)
 	if err != nil {
 		return err
 	}
 
+	var (
+		sent   *bot.TelegramSent
+		markup *tb.ReplyMarkup
+		send   tb.SendOptions
+	)
+
+	opts := make([]interface{}, 0, len(options))
+	for _, o := range options {
+		switch v := o.(type) {
+		case bot.TelegramKeyboard:
+			markup = b.replyMarkup(v)
+		case bot.TelegramReplyTo:
+			send.ReplyTo = &tb.Message{ID: int(v)}
+		case *bot.TelegramSent:
+			sent = v
+		case bot.TelegramPreview:
+			send.DisableWebPagePreview = !bool(v)
+		default:
+			opts = append(opts, o)
+		}
+	}
+
+	options = opts
+
 	var whatTB interface{}
 
+	kind := "photo"
+
 	switch v := what.(type) {
 	case string:
-		var replyTo *tb.Message
+		done := trackSend(ctx, "text", to)
+		defer func() { done(err) }()
+
+		// telebot replaces every option given before a *SendOptions, so the
+		// keyboard goes within it.
+		send.ReplyMarkup = markup
 
-		for _, ts := range b.chunks(v, telegramMessageLength) {
-			options = append(options, &tb.SendOptions{ReplyTo: replyTo})
+		for i, ts := range b.chunks(v, telegramMessageLength) {
+			chunk := send
 
-			replyTo, err = b.b.Send(tb.ChatID(toInt), ts, options...)
+			var m *tb.Message
+
+			m, err = b.b.Send(tb.ChatID(toInt), ts, append(options[:len(options):len(options)], &chunk)...)
 			if err != nil {
 				return err
 			}
+
+			if i == 0 {
+				fillSent(sent, m)
+			}
+
+			send.ReplyTo = m
 		}
 
 		return nil
@@ -105,21 +304,94 @@ func (b *Bot) Send(to string, what interface{}, options ...interface{}) error {
 				FileSize: v.FileSize,
 			},
 		}
+	case bot.TelegramDocument:
+		kind = "document"
+		whatTB = &tb.Document{
+			File:     tb.FromReader(bytes.NewReader(v.Content)),
+			FileName: v.FileName,
+		}
 	default:
 		return errors.New("unsupported type")
 	}
 
-	_, err = b.b.Send(tb.ChatID(toInt), whatTB, options...)
+	done := trackSend(ctx, kind, to)
+	defer func() { done(err) }()
+
+	if send.ReplyTo != nil || markup != nil {
+		options = append(options, &tb.SendOptions{ReplyTo: send.ReplyTo, ReplyMarkup: markup})
+	}
+
+	m, err := b.b.Send(tb.ChatID(toInt), whatTB, options...)
+	if err == nil {
+		fillSent(sent, m)
+	}
 
 	return err
 }
 
-func (b *Bot) GetFile(fileID string) (io.ReadCloser, error) {
+// fillSent sets the ID of the message sent and its public link, only channels
+// and groups have one.
+func fillSent(sent *bot.TelegramSent, m *tb.Message) {
+	if sent == nil || m == nil {
+		return
+	}
+
+	sent.MessageID = m.ID
+
+	if m.Chat == nil {
+		return
+	}
+
+	if m.Chat.Username != "" {
+		sent.URL = fmt.Sprintf("https://t.me/%s/%d", m.Chat.Username, m.ID)
+	} else if id := strconv.FormatInt(m.Chat.ID, 10); strings.HasPrefix(id, "-100") {
+		sent.URL = fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(id, "-100"), m.ID)
+	}
+}
+
+// trackSend measures a message sent to Telegram, tracing it as a child of ctx.
+func trackSend(ctx context.Context, kind string, to string) func(error) {
+	start := time.Now()
+	_, span := tracing.Tracer().Start(
+		ctx,
+		"telegram send "+kind,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("telegram.chat_id", to)),
+	)
+
+	return func(err error) {
+		metrics.TelegramSendDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+		tracing.End(span, err)
+	}
+}
+
+func (b *Bot) replyMarkup(k bot.TelegramKeyboard) *tb.ReplyMarkup {
+	rows := make([][]tb.InlineButton, 0, len(k))
+
+	for _, r := range k {
+		row := make([]tb.InlineButton, 0, len(r))
+		for _, btn := range r {
+			row = append(row, tb.InlineButton{Unique: btn.Unique, Text: btn.Text, Data: btn.Data})
+		}
+
+		rows = append(rows, row)
+	}
+
+	return &tb.ReplyMarkup{InlineKeyboard: rows}
+}
+
+func (b *Bot) GetFile(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	_, span := tracing.Tracer().Start(ctx, "telegram get file", trace.WithSpanKind(trace.SpanKindClient))
+
 	fileByID, err := b.b.FileByID(fileID)
 	if err != nil {
+		tracing.End(span, err)
+
 		return nil, err
 	}
 
+	defer span.End()
+
 	return b.b.File(&fileByID)
 }
 
@@ -2,12 +2,16 @@ package telegram_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -18,7 +22,9 @@ import (
 
 	"github.com/jarcoal/httpmock"
 	"github.com/javiyt/tweetgram/internal/bot"
+	"github.com/javiyt/tweetgram/internal/config"
 	"github.com/javiyt/tweetgram/internal/telegram"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	tb "gopkg.in/telebot.v3"
 )
@@ -27,6 +33,8 @@ const (
 	botToken            = "asdfg:12345"
 	botImageHandleToken = "qwert:98765"
 	botSendToken        = "zxcvb:54321"
+	botWebhookToken     = "poiuy:13579"
+	botKeyboardToken    = "lkjhg:24680"
 )
 
 var (
@@ -109,6 +117,158 @@ func TestBot_Stop(t *testing.T) {
 	tbBot.AssertCalled(t, "Stop")
 }
 
+func TestBot_StopWebhook(t *testing.T) {
+	tbBot := tbBotMock.NewTbBot(t)
+	tbBot.On("Stop").Once()
+	tbBot.On("RemoveWebhook").Once().Return(nil)
+
+	telegram.NewBot(tbBot, telegram.WithWebhook()).Stop()
+}
+
+func TestNewPoller(t *testing.T) {
+	t.Run("it should use long polling by default", func(t *testing.T) {
+		require.Equal(t, &tb.LongPoller{Timeout: 10 * time.Second}, telegram.NewPoller(config.AppConfig{}, nil))
+	})
+
+	t.Run("it should use webhook behind a reverse proxy", func(t *testing.T) {
+		cfg := config.AppConfig{
+			UpdateMode:         "webhook",
+			WebhookListen:      ":8443",
+			WebhookURL:         "https://bot.example.com/hook",
+			WebhookSecretToken: "secret",
+		}
+		h := telegram.NewHealth(cfg)
+
+		require.Equal(t, &telegram.Webhook{
+			Webhook: tb.Webhook{
+				Listen:      ":8443",
+				SecretToken: "secret",
+				Endpoint:    &tb.WebhookEndpoint{PublicURL: "https://bot.example.com/hook"},
+			},
+			Health: h,
+		}, telegram.NewPoller(cfg, h))
+	})
+
+	t.Run("it should use webhook with TLS certificate", func(t *testing.T) {
+		require.Equal(t, &telegram.Webhook{Webhook: tb.Webhook{
+			Listen:   ":8443",
+			TLS:      &tb.WebhookTLS{Key: "bot.key", Cert: "bot.pem"},
+			Endpoint: &tb.WebhookEndpoint{PublicURL: "https://bot.example.com:8443", Cert: "bot.pem"},
+		}}, telegram.NewPoller(config.AppConfig{
+			UpdateMode:     "webhook",
+			WebhookListen:  ":8443",
+			WebhookURL:     "https://bot.example.com:8443",
+			WebhookTLSCert: "bot.pem",
+			WebhookTLSKey:  "bot.key",
+		}, nil))
+	})
+}
+
+func TestBot_Webhook(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	listen := l.Addr().String()
+	_ = l.Close()
+
+	var registered, removed atomic.Value
+
+	registered.Store(false)
+	removed.Store(false)
+
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://api.telegram.mock/bot%s/setWebhook", botWebhookToken),
+		func(req *http.Request) (*http.Response, error) {
+			//nolint:tagliatelle
+			var requestBody struct {
+				URL         string `json:"url"`
+				SecretToken string `json:"secret_token"`
+			}
+			_ = json.NewDecoder(req.Body).Decode(&requestBody)
+			registered.Store(requestBody.URL == "https://bot.example.com/hook" && requestBody.SecretToken == "secret")
+
+			return httpmock.NewStringResponse(200, "{\"ok\":true,\"result\":true}"), nil
+		},
+	)
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://api.telegram.mock/bot%s/deleteWebhook", botWebhookToken),
+		func(req *http.Request) (*http.Response, error) {
+			removed.Store(true)
+
+			return httpmock.NewStringResponse(200, "{\"ok\":true,\"result\":true}"), nil
+		},
+	)
+
+	cfg := config.AppConfig{
+		UpdateMode:         "webhook",
+		WebhookListen:      listen,
+		WebhookURL:         "https://bot.example.com/hook",
+		WebhookSecretToken: "secret",
+	}
+	health := telegram.NewHealth(cfg)
+
+	tlgmbot, err := tb.NewBot(tb.Settings{
+		URL:     "https://api.telegram.mock",
+		Token:   botWebhookToken,
+		Poller:  telegram.NewPoller(cfg, health),
+		Offline: true,
+	})
+	require.NoError(t, err)
+
+	bt := telegram.NewBot(tlgmbot, telegram.WithWebhook())
+
+	var handled atomic.Value
+
+	handled.Store("")
+
+	bt.Handle(tb.OnText, func(_ context.Context, m bot.TelegramMessage) error {
+		handled.Store(m.Text)
+
+		return nil
+	})
+
+	go bt.Start()
+
+	require.Eventually(t, checkResponderCalled(&registered), time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return health.Ready() == nil }, time.Second, time.Millisecond)
+
+	update, _ := os.ReadFile("testdata/webhook.json")
+	client := &http.Client{Transport: &http.Transport{}}
+
+	require.Eventually(t, func() bool {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+listen, bytes.NewReader(update))
+		req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "secret")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+
+		_ = resp.Body.Close()
+
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return handled.Load() == "webhook"
+	}, time.Second, time.Millisecond)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+listen, bytes.NewReader(update))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "wrong")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	bt.Stop()
+
+	require.True(t, removed.Load().(bool))
+	require.EqualError(t, health.Ready(), "not receiving updates from Telegram")
+}
+
 func TestBot_SetCommands(t *testing.T) {
 	tlgmbot, err := tb.NewBot(tb.Settings{
 		URL:   "https://api.telegram.mock",
@@ -136,6 +296,44 @@ func TestBot_SetCommands(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestBot_SetCommandsForChat(t *testing.T) {
+	token := "scope:12345"
+	body := make(chan string, 1)
+
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://api.telegram.mock/bot%s/setMyCommands", token),
+		func(req *http.Request) (*http.Response, error) {
+			b, _ := io.ReadAll(req.Body)
+			body <- string(b)
+
+			return httpmock.NewStringResponse(200, "{\"ok\":true,\"result\":true}"), nil
+		},
+	)
+
+	tlgmbot, err := tb.NewBot(tb.Settings{URL: "https://api.telegram.mock", Token: token, Offline: true})
+	require.NoError(t, err)
+
+	err = telegram.NewBot(tlgmbot).SetCommands(
+		[]bot.TelegramBotCommand{{Text: "a", Description: "desc"}},
+		bot.TelegramCommandScope(1234),
+	)
+
+	require.NoError(t, err)
+	require.JSONEq(t, "{\"commands\":[{\"command\":\"a\",\"description\":\"desc\"}],"+
+		"\"scope\":{\"type\":\"chat\",\"chat_id\":1234}}", <-body)
+
+	err = telegram.NewBot(tlgmbot).SetCommands(
+		[]bot.TelegramBotCommand{{Text: "a", Description: "desc"}},
+		bot.TelegramCommandScope(1234),
+		bot.TelegramLanguage("es"),
+	)
+
+	require.NoError(t, err)
+	require.JSONEq(t, "{\"commands\":[{\"command\":\"a\",\"description\":\"desc\"}],"+
+		"\"scope\":{\"type\":\"chat\",\"chat_id\":1234},\"language_code\":\"es\"}", <-body)
+}
+
 func TestBot_Handle(t *testing.T) {
 	tlgmbot, err := tb.NewBot(tb.Settings{
 		URL:   "https://api.telegram.mock",
@@ -153,7 +351,7 @@ func TestBot_Handle(t *testing.T) {
 
 	handled.Store(false)
 
-	bt.Handle(tb.OnPhoto, func(m bot.TelegramMessage) error {
+	bt.Handle(tb.OnPhoto, func(_ context.Context, m bot.TelegramMessage) error {
 		handled.Store(m.Photo.Caption == "image")
 
 		return nil
@@ -168,6 +366,220 @@ func TestBot_Handle(t *testing.T) {
 	}, time.Second, time.Millisecond)
 }
 
+func TestBot_HandleCallback(t *testing.T) {
+	tbBot := tbBotMock.NewTbBot(t)
+	bt := telegram.NewBot(tbBot)
+
+	var handler tb.HandlerFunc
+
+	tbBot.On("Handle", "\freply", mock.Anything).Once().Run(func(args mock.Arguments) {
+		handler, _ = args.Get(1).(tb.HandlerFunc)
+	})
+
+	var received bot.TelegramMessage
+
+	bt.Handle("\freply", func(_ context.Context, m bot.TelegramMessage) error {
+		received = m
+
+		return nil
+	})
+
+	c := new(telebot.Context)
+	c.On("Sender").Return(&tb.User{ID: 1234})
+	c.On("Text").Return("mention")
+	c.On("Message").Return(&tb.Message{ID: 99})
+	c.On("Chat").Return(&tb.Chat{Private: true})
+	c.On("Callback").Return(&tb.Callback{Data: "5678"})
+	c.On("Respond").Once().Return(nil)
+
+	require.NoError(t, handler(c))
+	require.Equal(t, bot.TelegramMessage{
+		SenderID:     "1234",
+		MessageID:    99,
+		Text:         "mention",
+		CallbackData: "5678",
+		IsPrivate:    true,
+	}, received)
+	c.AssertExpectations(t)
+}
+
+func TestBot_HandleGroupMessage(t *testing.T) {
+	tbBot := tbBotMock.NewTbBot(t)
+	bt := telegram.NewBot(tbBot)
+
+	var handler tb.HandlerFunc
+
+	tbBot.On("Handle", tb.OnText, mock.Anything).Once().Run(func(args mock.Arguments) {
+		handler, _ = args.Get(1).(tb.HandlerFunc)
+	})
+
+	var received bot.TelegramMessage
+
+	bt.Handle(tb.OnText, func(_ context.Context, m bot.TelegramMessage) error {
+		received = m
+
+		return nil
+	})
+
+	c := new(telebot.Context)
+	c.On("Sender").Return(&tb.User{ID: 1234, Username: "editor"})
+	c.On("Text").Return("@TweetgramBot publish this")
+	c.On("Message").Return(&tb.Message{
+		ID:                99,
+		Text:              "@TweetgramBot publish this",
+		Entities:          tb.Entities{{Type: tb.EntityMention, Offset: 0, Length: 13}},
+		OriginalChat:      &tb.Chat{Type: tb.ChatChannel, Title: "News", Username: "news"},
+		OriginalMessageID: 42,
+		PreviewOptions:    &tb.PreviewOptions{Disabled: true},
+	})
+	c.On("Chat").Return(&tb.Chat{ID: -5678})
+	c.On("Callback").Return(nil)
+	c.On("Bot").Return(&tb.Bot{Me: &tb.User{Username: "tweetgrambot"}})
+
+	preview := false
+
+	require.NoError(t, handler(c))
+	require.Equal(t, bot.TelegramMessage{
+		SenderID:       "1234",
+		SenderUsername: "editor",
+		ChatID:         -5678,
+		MessageID:      99,
+		Text:           "publish this",
+		Forward:        bot.TelegramForward{Channel: "News", URL: "https://t.me/news/42"},
+		Mentioned:      true,
+		Preview:        &preview,
+	}, received)
+	c.AssertExpectations(t)
+}
+
+func TestBot_SendWithKeyboard(t *testing.T) {
+	tbBot := tbBotMock.NewTbBot(t)
+
+	tbBot.On(
+		"Send",
+		tb.ChatID(1234567890),
+		"test message",
+		&tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{InlineKeyboard: [][]tb.InlineButton{{{Unique: "reply", Text: "Reply", Data: "5678"}}}},
+		},
+	).Once().Return(&tb.Message{}, nil)
+
+	require.NoError(t, telegram.NewBot(tbBot).Send(
+		context.Background(),
+		"1234567890",
+		"test message",
+		bot.TelegramKeyboard{{{Unique: "reply", Text: "Reply", Data: "5678"}}},
+	))
+}
+
+func TestBot_SendKeyboardToTelegram(t *testing.T) {
+	var markups sync.Map
+
+	for _, method := range []string{"sendMessage", "sendPhoto"} {
+		httpmock.RegisterResponder(
+			"POST",
+			fmt.Sprintf("https://api.telegram.mock/bot%s/%s", botKeyboardToken, method),
+			func(req *http.Request) (*http.Response, error) {
+				//nolint:tagliatelle
+				var requestBody struct {
+					ReplyMarkup string `json:"reply_markup"`
+				}
+				_ = json.NewDecoder(req.Body).Decode(&requestBody)
+				markups.Store(method, requestBody.ReplyMarkup)
+
+				return httpmock.NewStringResponse(200, `{"ok":true,"result":{"message_id":1,"photo":[{"file_id":"1"}]}}`), nil
+			},
+		)
+	}
+
+	tlgmbot, err := tb.NewBot(tb.Settings{URL: "https://api.telegram.mock", Token: botKeyboardToken, Offline: true})
+	require.NoError(t, err)
+
+	bt := telegram.NewBot(tlgmbot)
+	keyboard := bot.TelegramKeyboard{{{Unique: "reply", Text: "Reply", Data: "5678"}}}
+
+	t.Run("it should send the keyboard with a text message", func(t *testing.T) {
+		require.NoError(t, bt.Send(context.Background(), "1234", "hello", keyboard, bot.TelegramReplyTo(42)))
+
+		markup, _ := markups.Load("sendMessage")
+		require.Contains(t, markup, `"text":"Reply","callback_data":"\freply|5678"`)
+	})
+
+	t.Run("it should send the keyboard with a photo", func(t *testing.T) {
+		require.NoError(t, bt.Send(context.Background(), "1234", bot.TelegramPhoto{FileID: "123456"}, keyboard))
+
+		markup, _ := markups.Load("sendPhoto")
+		require.Contains(t, markup, `"text":"Reply","callback_data":"\freply|5678"`)
+	})
+}
+
+func TestBot_SendWithoutPreview(t *testing.T) {
+	t.Run("it should hide the link preview", func(t *testing.T) {
+		tbBot := tbBotMock.NewTbBot(t)
+		tbBot.On("Send", tb.ChatID(1234567890), "https://example.com", &tb.SendOptions{DisableWebPagePreview: true}).
+			Once().
+			Return(&tb.Message{}, nil)
+
+		require.NoError(t, telegram.NewBot(tbBot).Send(
+			context.Background(),
+			"1234567890",
+			"https://example.com",
+			bot.TelegramPreview(false),
+		))
+	})
+
+	t.Run("it should leave the link preview", func(t *testing.T) {
+		tbBot := tbBotMock.NewTbBot(t)
+		tbBot.On("Send", tb.ChatID(1234567890), "https://example.com", &tb.SendOptions{}).
+			Once().
+			Return(&tb.Message{}, nil)
+
+		require.NoError(t, telegram.NewBot(tbBot).Send(
+			context.Background(),
+			"1234567890",
+			"https://example.com",
+			bot.TelegramPreview(true),
+		))
+	})
+}
+
+func TestBot_SendReplyingTo(t *testing.T) {
+	t.Run("it should reply to message and return private channel link", func(t *testing.T) {
+		tbBot := tbBotMock.NewTbBot(t)
+		tbBot.On("Send", tb.ChatID(-1001234567890), "test message", &tb.SendOptions{ReplyTo: &tb.Message{ID: 42}}).
+			Once().
+			Return(&tb.Message{ID: 7, Chat: &tb.Chat{ID: -1001234567890}}, nil)
+
+		var sent bot.TelegramSent
+
+		require.NoError(t, telegram.NewBot(tbBot).Send(
+			context.Background(),
+			"-1001234567890",
+			"test message",
+			bot.TelegramReplyTo(42),
+			&sent,
+		))
+		require.Equal(t, bot.TelegramSent{MessageID: 7, URL: "https://t.me/c/1234567890/7"}, sent)
+	})
+
+	t.Run("it should return public channel link of photo", func(t *testing.T) {
+		tbBot := tbBotMock.NewTbBot(t)
+		tbBot.On("Send", tb.ChatID(-1001234567890), mock.AnythingOfType("*telebot.Photo")).
+			Once().
+			Return(&tb.Message{ID: 8, Chat: &tb.Chat{ID: -1001234567890, Username: "tweetgram"}}, nil)
+
+		var sent bot.TelegramSent
+
+		require.NoError(t, telegram.NewBot(tbBot).Send(
+			context.Background(),
+			"-1001234567890",
+			bot.TelegramPhoto{FileURL: "http://image.url"},
+			&sent,
+		))
+		require.Equal(t, bot.TelegramSent{MessageID: 8, URL: "https://t.me/tweetgram/8"}, sent)
+	})
+}
+
 func TestBot_Send(t *testing.T) {
 	tlgmbot, _ := tb.NewBot(tb.Settings{URL: "https://api.telegram.mock", Token: botSendToken, Poller: &tb.LongPoller{
 		Timeout: 10 * time.Second,
@@ -181,33 +593,33 @@ func TestBot_Send(t *testing.T) {
 	firstLongMessage.Store(false)
 
 	t.Run("it should fail when unsupported message sent", func(t *testing.T) {
-		require.EqualError(t, bt.Send("1234567890", tb.File{}), "unsupported type")
+		require.EqualError(t, bt.Send(context.Background(), "1234567890", tb.File{}), "unsupported type")
 	})
 
 	t.Run("it should fail when recipient could not be converted to float", func(t *testing.T) {
 		require.EqualError(
 			t,
-			bt.Send("asdfg", "test message"),
+			bt.Send(context.Background(), "asdfg", "test message"),
 			"strconv.ParseFloat: parsing \"asdfg\": invalid syntax",
 		)
 	})
 
 	t.Run("it sends a text message", func(t *testing.T) {
-		require.NoError(t, bt.Send("1234567890", "test message"))
+		require.NoError(t, bt.Send(context.Background(), "1234567890", "test message"))
 		require.Eventually(t, checkResponderCalled(&testMessageSent), time.Second, time.Millisecond)
 	})
 
 	t.Run("it should fail sending a text message", func(t *testing.T) {
-		require.EqualError(t, bt.Send("1234567890", "fail message"), "telegram:  (0)")
+		require.EqualError(t, bt.Send(context.Background(), "1234567890", "fail message"), "telegram:  (0)")
 	})
 
 	t.Run("it send a text message longer than expected", func(t *testing.T) {
-		require.NoError(t, bt.Send("1234567890", string(generateRandomString())))
+		require.NoError(t, bt.Send(context.Background(), "1234567890", string(generateRandomString())))
 		require.Eventually(t, checkResponderCalled(&testLongMessageSent), time.Second, time.Millisecond)
 	})
 
 	t.Run("it should send a picture", func(t *testing.T) {
-		require.NoError(t, bt.Send("1234567890", bot.TelegramPhoto{
+		require.NoError(t, bt.Send(context.Background(), "1234567890", bot.TelegramPhoto{
 			Caption:  "test",
 			FileID:   "123456",
 			FileURL:  "http://image.url",
@@ -215,6 +627,32 @@ func TestBot_Send(t *testing.T) {
 		}))
 		require.Eventually(t, checkResponderCalled(&photoSent), time.Second, time.Millisecond)
 	})
+
+	t.Run("it should send a document", func(t *testing.T) {
+		var documentSent atomic.Value
+
+		httpmock.RegisterResponder(
+			"POST",
+			fmt.Sprintf("https://api.telegram.mock/bot%s/sendDocument", botSendToken),
+			func(req *http.Request) (*http.Response, error) {
+				file, header, err := req.FormFile("document")
+				if err != nil {
+					return nil, err
+				}
+
+				content, _ := io.ReadAll(file)
+				documentSent.Store(header.Filename == "audit.csv" && string(content) == "time\n")
+
+				return httpmock.NewStringResponse(200, `{"ok":true,"result":{"message_id":1}}`), nil
+			},
+		)
+
+		require.NoError(t, bt.Send(context.Background(), "1234567890", bot.TelegramDocument{
+			FileName: "audit.csv",
+			Content:  []byte("time\n"),
+		}))
+		require.Eventually(t, checkResponderCalled(&documentSent), time.Second, time.Millisecond)
+	})
 }
 
 func TestBot_GetFile(t *testing.T) {
@@ -237,7 +675,7 @@ func TestBot_GetFile(t *testing.T) {
 
 		bt := telegram.NewBot(tlgmbot)
 
-		_, err = bt.GetFile("AZCDxruqG7J3iTM9")
+		_, err = bt.GetFile(context.Background(), "AZCDxruqG7J3iTM9")
 
 		require.EqualError(t, err, "telebot: unexpected end of JSON input")
 	})
@@ -265,7 +703,7 @@ func TestBot_GetFile(t *testing.T) {
 
 		bt := telegram.NewBot(tlgmbot)
 
-		_, err = bt.GetFile("AZCDxruqG7J3iTM9")
+		_, err = bt.GetFile(context.Background(), "AZCDxruqG7J3iTM9")
 
 		require.NoError(t, err)
 	})
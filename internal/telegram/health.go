@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/config"
+)
+
+var errNotReceiving = errors.New("not receiving updates from Telegram")
+
+// Health tells whether updates are being received from Telegram: with long
+// polling, when the last request for updates succeeded recently, and with a
+// webhook, while it's registered and listening.
+type Health struct {
+	maxAge time.Duration
+	now    func() time.Time
+	mu     sync.Mutex
+	up     bool
+	okAt   time.Time
+	err    error
+}
+
+// NewHealth returns the health of the bot receiving updates as configured.
+// Polling is considered stopped when no request for updates succeeded within
+// three polling timeouts, while a listening webhook is always ready, as
+// Telegram only calls it when there are updates.
+func NewHealth(cfg config.AppConfig) *Health {
+	h := &Health{now: time.Now}
+	if !cfg.IsWebhook() {
+		h.maxAge = 3 * pollingTimeout
+	}
+
+	return h
+}
+
+// Ready returns why updates aren't being received, nil when they are.
+func (h *Health) Ready() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case h.up && (h.maxAge == 0 || h.now().Sub(h.okAt) <= h.maxAge):
+		return nil
+	case h.err != nil:
+		return h.err
+	case h.up:
+		return fmt.Errorf("no updates polled from Telegram since %s", h.okAt.Format(time.RFC3339))
+	default:
+		return errNotReceiving
+	}
+}
+
+// Transport records the outcome of the requests for updates sent through
+// base, http.DefaultTransport when nil.
+func (h *Health) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		resp, err := base.RoundTrip(req)
+		if !strings.HasSuffix(req.URL.Path, "/getUpdates") {
+			return resp, err
+		}
+
+		switch {
+		case err != nil:
+			h.failed(fmt.Errorf("error polling Telegram: %w", err))
+		case resp.StatusCode != http.StatusOK:
+			h.failed(fmt.Errorf("error polling Telegram: %s", resp.Status))
+		default:
+			h.succeeded()
+		}
+
+		return resp, err
+	})
+}
+
+func (h *Health) succeeded() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.up, h.okAt, h.err = true, h.now(), nil
+	h.mu.Unlock()
+}
+
+func (h *Health) failed(err error) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.up, h.err = false, err
+	h.mu.Unlock()
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,76 @@
+package telegram_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/telegram"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestHealth(t *testing.T) {
+	answer := func(status int, err error) http.RoundTripper {
+		return roundTripper(func(*http.Request) (*http.Response, error) {
+			if err != nil {
+				return nil, err
+			}
+
+			return &http.Response{
+				StatusCode: status,
+				Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+				Body:       http.NoBody,
+			}, nil
+		})
+	}
+
+	request := func(rt http.RoundTripper, method string) {
+		req, _ := http.NewRequest(http.MethodPost, "https://api.telegram.org/bot1234/"+method, http.NoBody)
+
+		resp, err := rt.RoundTrip(req)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}
+
+	t.Run("it should not be ready before updates are polled", func(t *testing.T) {
+		h := telegram.NewHealth(config.AppConfig{})
+
+		request(h.Transport(answer(http.StatusOK, nil)), "sendMessage")
+
+		require.EqualError(t, h.Ready(), "not receiving updates from Telegram")
+	})
+
+	t.Run("it should be ready once updates are polled", func(t *testing.T) {
+		h := telegram.NewHealth(config.AppConfig{})
+
+		request(h.Transport(answer(http.StatusOK, nil)), "getUpdates")
+
+		require.NoError(t, h.Ready())
+	})
+
+	t.Run("it should report Telegram rejecting the poll", func(t *testing.T) {
+		h := telegram.NewHealth(config.AppConfig{})
+
+		request(h.Transport(answer(http.StatusOK, nil)), "getUpdates")
+		request(h.Transport(answer(http.StatusConflict, nil)), "getUpdates")
+
+		require.EqualError(t, h.Ready(), "error polling Telegram: 409 Conflict")
+	})
+
+	t.Run("it should report polls that couldn't be sent", func(t *testing.T) {
+		h := telegram.NewHealth(config.AppConfig{})
+
+		request(h.Transport(answer(0, errors.New("connection refused"))), "getUpdates")
+
+		require.EqualError(t, h.Ready(), "error polling Telegram: connection refused")
+	})
+}
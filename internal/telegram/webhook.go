@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+const (
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	readHeaderTimeout = 5 * time.Second
+)
+
+// Webhook receives updates through a Telegram webhook. The telebot webhook
+// poller closes the stop channel twice when the bot is stopped, so the
+// listener is run here and telebot is only used to register the webhook.
+// Health, when set, is told whether the webhook is registered and listening.
+type Webhook struct {
+	tb.Webhook
+	Health *Health
+}
+
+func (w *Webhook) Poll(b *tb.Bot, dest chan tb.Update, stop chan struct{}) {
+	if err := b.SetWebhook(&w.Webhook); err != nil {
+		w.Health.failed(fmt.Errorf("error registering webhook: %w", err))
+		b.OnError(err, nil)
+		<-stop
+
+		return
+	}
+
+	ln, err := net.Listen("tcp", w.Listen)
+	if err != nil {
+		w.Health.failed(fmt.Errorf("error listening for webhook updates: %w", err))
+		b.OnError(err, nil)
+		<-stop
+
+		return
+	}
+
+	s := &http.Server{
+		Handler:           w.handler(dest),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errs := make(chan error, 1)
+
+	go func() {
+		if w.TLS != nil {
+			errs <- s.ServeTLS(ln, w.TLS.Cert, w.TLS.Key)
+		} else {
+			errs <- s.Serve(ln)
+		}
+	}()
+
+	w.Health.succeeded()
+
+	select {
+	case <-stop:
+		_ = s.Shutdown(context.Background())
+		w.Health.failed(nil)
+	case err := <-errs:
+		w.Health.failed(fmt.Errorf("error listening for webhook updates: %w", err))
+		if !errors.Is(err, http.ErrServerClosed) {
+			b.OnError(err, nil)
+		}
+		<-stop
+	}
+}
+
+func (w *Webhook) handler(dest chan<- tb.Update) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if w.SecretToken != "" && r.Header.Get(secretTokenHeader) != w.SecretToken {
+			rw.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		var update tb.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		select {
+		case dest <- update:
+		case <-r.Context().Done():
+		}
+	})
+}
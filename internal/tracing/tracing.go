@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/javiyt/tweetgram"
+	serviceName         = "tweetgram"
+)
+
+// Setup installs the global tracer provider configured by TracingExporter,
+// the returned function flushes pending spans and releases the exporter.
+func Setup(ctx context.Context, cfg config.AppConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("deployment.environment", cfg.Environment),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+
+		if closer != nil {
+			_ = closer.Close()
+		}
+
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, cfg config.AppConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.TracingExporter {
+	case "", "none":
+		return nil, nil, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+
+		return exporter, nil, err
+	case "file":
+		file, err := os.OpenFile(cfg.TracingFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error opening traces file: %w", err)
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+
+		return exporter, file, err
+	case "otlp":
+		var options []otlptracehttp.Option
+		if cfg.TracingEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(cfg.TracingEndpoint))
+		}
+
+		exporter, err := otlptracehttp.New(ctx, options...)
+
+		return exporter, nil, err
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing exporter %q", cfg.TracingExporter)
+	}
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Inject stores the trace context of ctx in the message metadata, so it
+// survives being published to the queue.
+func Inject(ctx context.Context, msg *message.Message) {
+	if msg.Metadata == nil {
+		msg.Metadata = make(message.Metadata)
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(msg.Metadata))
+}
+
+// Extract returns the message context carrying the trace context stored in
+// its metadata by Inject.
+func Extract(msg *message.Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(msg.Context(), propagation.MapCarrier(msg.Metadata))
+}
+
+// End records err, when not nil, as the outcome of the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
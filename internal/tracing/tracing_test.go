@@ -0,0 +1,108 @@
+package tracing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/javiyt/tweetgram/internal/config"
+	"github.com/javiyt/tweetgram/internal/tracing"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type spanError struct{}
+
+func (spanError) Error() string {
+	return "span failed"
+}
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it should not export traces when disabled", func(t *testing.T) {
+		shutdown, err := tracing.Setup(ctx, config.AppConfig{TracingExporter: "none"})
+
+		require.NoError(t, err)
+		require.NoError(t, shutdown(ctx))
+	})
+
+	t.Run("it should fail with unknown exporter", func(t *testing.T) {
+		_, err := tracing.Setup(ctx, config.AppConfig{TracingExporter: "zipkin"})
+
+		require.EqualError(t, err, "unknown tracing exporter \"zipkin\"")
+	})
+
+	t.Run("it should fail when traces file can't be opened", func(t *testing.T) {
+		_, err := tracing.Setup(ctx, config.AppConfig{
+			TracingExporter: "file",
+			TracingFile:     filepath.Join(t.TempDir(), "missing", "traces.json"),
+		})
+
+		require.ErrorContains(t, err, "error opening traces file:")
+	})
+
+	t.Run("it should write spans to file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "traces.json")
+
+		shutdown, err := tracing.Setup(ctx, config.AppConfig{TracingExporter: "file", TracingFile: file})
+		require.NoError(t, err)
+
+		_, span := tracing.Tracer().Start(ctx, "testing span")
+		span.End()
+
+		require.NoError(t, shutdown(ctx))
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "\"Name\":\"testing span\"")
+		require.Contains(t, string(content), "\"Value\":\"tweetgram\"")
+	})
+}
+
+func TestPropagation(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+
+	_, err := tracing.Setup(ctx, config.AppConfig{})
+	require.NoError(t, err)
+	otel.SetTracerProvider(tp)
+
+	defer otel.SetTracerProvider(previous)
+
+	t.Run("it should carry trace context in message metadata", func(t *testing.T) {
+		parent, span := tp.Tracer("testing").Start(ctx, "parent")
+		defer span.End()
+
+		published := message.NewMessage(watermill.NewUUID(), nil)
+		tracing.Inject(parent, published)
+
+		received := message.NewMessage(published.UUID, nil)
+		received.Metadata = published.Metadata
+
+		_, child := tracing.Tracer().Start(tracing.Extract(received), "child")
+		child.End()
+
+		require.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID())
+	})
+
+	t.Run("it should record error when ending span", func(t *testing.T) {
+		_, span := tracing.Tracer().Start(ctx, "failing")
+		tracing.End(span, spanError{})
+
+		ended := recorder.Ended()
+		last := ended[len(ended)-1]
+
+		require.Equal(t, "failing", last.Name())
+		require.Equal(t, codes.Error, last.Status().Code)
+		require.Equal(t, "span failed", last.Status().Description)
+	})
+}
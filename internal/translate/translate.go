@@ -0,0 +1,176 @@
+// Package translate rewrites mentions and hashtags for every destination, as
+// the same people and topics go by different names on Telegram and Twitter.
+package translate
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/twittertext"
+)
+
+const tableKey = "translate/rules"
+
+// Rules maps mentions and hashtags, sign included, to the text they are
+// replaced with.
+type Rules map[string]string
+
+// Key returns how from is matched: lower case and with the ASCII sign. ok is
+// false when from isn't a single mention or hashtag.
+func Key(from string) (key string, ok bool) {
+	e := twittertext.Extract(from)
+	if len(e) != 1 || e[0].Start != 0 || e[0].End != len(from) {
+		return "", false
+	}
+
+	return strings.ToLower(e[0].Sign()), true
+}
+
+// Apply replaces the mentions and hashtags in text found in the rules, no
+// matter the case they are written in. Later rules take precedence.
+func Apply(text string, rules ...Rules) string {
+	merged := make(Rules)
+
+	for _, r := range rules {
+		for from, to := range r {
+			if key, ok := Key(from); ok {
+				merged[key] = to
+			}
+		}
+	}
+
+	if len(merged) == 0 {
+		return text
+	}
+
+	var (
+		sb   strings.Builder
+		last int
+	)
+
+	for _, e := range twittertext.Extract(text) {
+		to, ok := merged[strings.ToLower(e.Sign())]
+		if !ok {
+			continue
+		}
+
+		sb.WriteString(text[last:e.Start])
+		sb.WriteString(to)
+		last = e.End
+	}
+
+	sb.WriteString(text[last:])
+
+	return sb.String()
+}
+
+// Table keeps the rules added by owners while running for every destination,
+// which apply on top of the configured ones. The zero value is an empty table.
+type Table struct {
+	s     storage.Store
+	mu    sync.RWMutex
+	rules map[string]Rules
+}
+
+func NewTable(s storage.Store) *Table {
+	return &Table{s: s}
+}
+
+// Load reads the rules kept in the store.
+func (t *Table) Load() error {
+	rules := make(map[string]Rules)
+	if err := t.s.Load(tableKey, &rules); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	t.mu.Lock()
+	t.rules = rules
+	t.mu.Unlock()
+
+	return nil
+}
+
+// Rules returns the rules of the destination.
+func (t *Table) Rules(destination string) Rules {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	rules := make(Rules, len(t.rules[destination]))
+	for from, to := range t.rules[destination] {
+		rules[from] = to
+	}
+
+	return rules
+}
+
+// Add sets the rule for the mention or hashtag in the destination, replacing
+// the one there was.
+func (t *Table) Add(destination string, from string, to string) error {
+	key, ok := Key(from)
+	if !ok {
+		return errors.New(from + " is not a mention or hashtag")
+	}
+
+	return t.update(func(rules map[string]Rules) bool {
+		if rules[destination] == nil {
+			rules[destination] = make(Rules)
+		}
+
+		rules[destination][key] = to
+
+		return true
+	})
+}
+
+// Remove deletes the rule for the mention or hashtag in the destination,
+// reporting whether there was one.
+func (t *Table) Remove(destination string, from string) (bool, error) {
+	key, _ := Key(from)
+
+	var found bool
+
+	err := t.update(func(rules map[string]Rules) bool {
+		if _, found = rules[destination][key]; !found {
+			return false
+		}
+
+		delete(rules[destination], key)
+
+		if len(rules[destination]) == 0 {
+			delete(rules, destination)
+		}
+
+		return true
+	})
+
+	return found, err
+}
+
+// update applies the change to a copy of the rules, which is saved and then
+// replaces the current ones.
+func (t *Table) update(change func(map[string]Rules) bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rules := make(map[string]Rules, len(t.rules))
+	for d, r := range t.rules {
+		rules[d] = make(Rules, len(r))
+		for from, to := range r {
+			rules[d][from] = to
+		}
+	}
+
+	if !change(rules) {
+		return nil
+	}
+
+	if err := t.s.Save(tableKey, rules); err != nil {
+		return err
+	}
+
+	t.rules = rules
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package translate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javiyt/tweetgram/internal/storage"
+	"github.com/javiyt/tweetgram/internal/translate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from     string
+		expected string
+	}{
+		{name: "it should lower case mentions", from: "@Acme_TG", expected: "@acme_tg"},
+		{name: "it should use the ASCII sign", from: "＃Launch", expected: "#launch"},
+		{name: "it should reject plain words", from: "acme"},
+		{name: "it should reject several entities", from: "@acme #launch"},
+		{name: "it should reject text around the entity", from: "@acme!"},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			key, ok := translate.Key(testCases[i].from)
+
+			require.Equal(t, testCases[i].expected != "", ok)
+			require.Equal(t, testCases[i].expected, key)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	configured := translate.Rules{"@acme_tg": "@AcmeCorp", "#tg": "#tw"}
+
+	testCases := []struct {
+		name     string
+		text     string
+		rules    []translate.Rules
+		expected string
+	}{
+		{
+			name:     "it should rewrite mentions and hashtags",
+			text:     "News from @ACME_tg! #TG #other",
+			rules:    []translate.Rules{configured},
+			expected: "News from @AcmeCorp! #tw #other",
+		},
+		{
+			name:     "it should give precedence to later rules",
+			text:     "@acme_tg",
+			rules:    []translate.Rules{configured, {"@Acme_TG": "ACME Corp"}},
+			expected: "ACME Corp",
+		},
+		{
+			name:     "it should leave out what isn't an entity",
+			text:     "mail info@acme_tg or see https://example.com/#tg",
+			rules:    []translate.Rules{configured},
+			expected: "mail info@acme_tg or see https://example.com/#tg",
+		},
+		{
+			name:     "it should leave the text as it is without rules",
+			text:     "@acme_tg #tg",
+			expected: "@acme_tg #tg",
+		},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			require.Equal(t, testCases[i].expected, translate.Apply(testCases[i].text, testCases[i].rules...))
+		})
+	}
+}
+
+func TestTable(t *testing.T) {
+	t.Run("it should keep rules in the store", func(t *testing.T) {
+		s := storage.NewFileStore(t.TempDir())
+		table := translate.NewTable(s)
+
+		require.NoError(t, table.Load())
+		require.NoError(t, table.Add("twitter", "@Acme_TG", "@AcmeCorp"))
+		require.NoError(t, table.Add("twitter", "#tg", "#tw"))
+		require.NoError(t, table.Add("feed", "@acme_tg", "ACME"))
+		require.EqualError(t, table.Add("twitter", "acme", "ACME"), "acme is not a mention or hashtag")
+
+		reloaded := translate.NewTable(s)
+		require.NoError(t, reloaded.Load())
+		require.Equal(t, translate.Rules{"@acme_tg": "@AcmeCorp", "#tg": "#tw"}, reloaded.Rules("twitter"))
+		require.Equal(t, translate.Rules{"@acme_tg": "ACME"}, reloaded.Rules("feed"))
+		require.Empty(t, reloaded.Rules("email"))
+	})
+
+	t.Run("it should remove rules", func(t *testing.T) {
+		table := translate.NewTable(storage.NewFileStore(t.TempDir()))
+
+		require.NoError(t, table.Add("twitter", "@acme_tg", "@AcmeCorp"))
+
+		found, err := table.Remove("twitter", "@ACME_TG")
+		require.NoError(t, err)
+		require.True(t, found)
+
+		found, err = table.Remove("twitter", "@acme_tg")
+		require.NoError(t, err)
+		require.False(t, found)
+		require.Empty(t, table.Rules("twitter"))
+	})
+
+	t.Run("it should fail loading invalid rules", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "translate"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "translate", "rules.json"), []byte("{"), 0o600))
+
+		require.Error(t, translate.NewTable(storage.NewFileStore(dir)).Load())
+	})
+
+	t.Run("it should be empty when not loaded", func(t *testing.T) {
+		var table translate.Table
+
+		require.Empty(t, table.Rules("twitter"))
+	})
+}
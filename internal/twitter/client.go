@@ -1,10 +1,17 @@
 package twitter
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/javiyt/tweetgram/internal/metrics"
+	"github.com/javiyt/tweetgram/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	gt "github.com/javiyt/go-twitter/twitter"
 	"github.com/javiyt/twitter-text-go/validate"
@@ -23,40 +30,69 @@ func NewTwitterClient(tc *gt.Client) *Client {
 	return &Client{tc: tc}
 }
 
-func (c *Client) SendUpdate(s string) error {
-	return c.publishTweet(s, &gt.StatusUpdateParams{})
+func (c *Client) SendUpdate(ctx context.Context, s string) ([]int64, error) {
+	return c.publishTweet(ctx, s, &gt.StatusUpdateParams{})
 }
 
-func (c *Client) SendUpdateWithPhoto(s string, pic []byte) error {
+func (c *Client) SendUpdateWithPhoto(ctx context.Context, s string, pic []byte) ([]int64, error) {
+	done := track(ctx, "media_upload")
 	uploadResult, resp, err := c.tc.Media.Upload(pic, http.DetectContentType(pic))
+	done(resp, err)
 
-	defer func() { _ = resp.Body.Close() }()
+	if err != nil {
+		return nil, requestError("error sending status update", err, resp)
+	}
+
+	return c.publishTweet(ctx, s, &gt.StatusUpdateParams{MediaIds: []int64{uploadResult.MediaID}})
+}
+
+func (c *Client) SendReply(ctx context.Context, inReplyTo int64, s string) ([]int64, error) {
+	return c.publishTweet(ctx, s, &gt.StatusUpdateParams{InReplyToStatusID: inReplyTo})
+}
+
+func (c *Client) UserTimeline(ctx context.Context, sinceID int64) ([]Tweet, error) {
+	done := track(ctx, "user_timeline")
+	tweets, resp, err := c.tc.Timelines.UserTimeline(&gt.UserTimelineParams{
+		SinceID:   sinceID,
+		TweetMode: "extended",
+	})
+	done(resp, err)
+
+	if err != nil {
+		return nil, requestError("error getting user timeline", err, resp)
+	}
+
+	return toTweets(tweets), nil
+}
+
+func (c *Client) Mentions(ctx context.Context, sinceID int64) ([]Tweet, error) {
+	done := track(ctx, "mentions_timeline")
+	tweets, resp, err := c.tc.Timelines.MentionTimeline(&gt.MentionTimelineParams{
+		SinceID:   sinceID,
+		TweetMode: "extended",
+	})
+	done(resp, err)
 
 	if err != nil {
-		buf := new(strings.Builder)
-		_, _ = io.Copy(buf, resp.Body)
-
-		return fmt.Errorf(
-			"error sending status update: %w. Response status code: %v and body: %s",
-			err,
-			resp.StatusCode,
-			buf.String(),
-		)
+		return nil, requestError("error getting mentions", err, resp)
 	}
 
-	return c.publishTweet(s, &gt.StatusUpdateParams{MediaIds: []int64{uploadResult.MediaID}})
+	return toTweets(tweets), nil
 }
 
-func (c *Client) publishTweet(s string, params *gt.StatusUpdateParams) error {
+func (c *Client) publishTweet(ctx context.Context, s string, params *gt.StatusUpdateParams) ([]int64, error) {
 	err := validate.ValidateTweet(s)
 	switch err.(type) {
 	case validate.EmptyError:
-		return nil
+		return nil, nil
 	case validate.InvalidCharacterError:
-		return fmt.Errorf("error sending status update: %w", err)
+		return nil, fmt.Errorf("error sending status update: %w", err)
 	}
 
-	var replyToID int64
+	var (
+		replyToID int64
+		ids       []int64
+	)
 	for _, ts := range c.chunks(s, tweetMaxLength-len(joinString)) {
 		if replyToID > 0 {
 			params.InReplyToStatusID = replyToID
@@ -66,24 +102,51 @@ func (c *Client) publishTweet(s string, params *gt.StatusUpdateParams) error {
 			ts += joinString
 		}
 
+		done := track(ctx, "statuses_update")
 		tweet, resp, err := c.tc.Statuses.Update(ts, params)
+		done(resp, err)
+
 		if err != nil {
-			buf := new(strings.Builder)
-			_, _ = io.Copy(buf, resp.Body)
-			_ = resp.Body.Close()
-
-			return fmt.Errorf(
-				"error sending status update: %w. Response status code: %v and body: %s",
-				err,
-				resp.StatusCode,
-				buf.String(),
-			)
+			return ids, requestError("error sending status update", err, resp)
 		}
 
 		replyToID = tweet.ID
+		ids = append(ids, tweet.ID)
+	}
+
+	return ids, nil
+}
+
+// requestError wraps the error of a request to the Twitter API with the status
+// and body of the response, when the request failed before getting one there
+// is only the error.
+func requestError(msg string, err error, resp *http.Response) error {
+	if resp == nil {
+		return fmt.Errorf("%s: %w", msg, err)
 	}
 
-	return nil
+	defer func() { _ = resp.Body.Close() }()
+
+	buf := new(strings.Builder)
+	_, _ = io.Copy(buf, resp.Body)
+
+	return fmt.Errorf("%s: %w. Response status code: %v and body: %s", msg, err, resp.StatusCode, buf.String())
+}
+
+// track measures a Twitter API request, tracing it as a child of ctx.
+func track(ctx context.Context, operation string) func(*http.Response, error) {
+	start := time.Now()
+	_, span := tracing.Tracer().Start(ctx, "twitter "+operation, trace.WithSpanKind(trace.SpanKindClient))
+
+	return func(resp *http.Response, err error) {
+		metrics.ObserveTwitterRequest(operation, start, resp)
+
+		if resp != nil {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		}
+
+		tracing.End(span, err)
+	}
 }
 
 func (c *Client) chunks(s string, chunkSize int) []string {
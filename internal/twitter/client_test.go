@@ -2,6 +2,8 @@ package twitter_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"math/rand"
 	"net/http"
 	"os"
@@ -27,42 +29,40 @@ func TestClient_SendUpdate(t *testing.T) {
 	client := twitter.NewTwitterClient(gt.NewClient(httpClient))
 
 	t.Run("it should fail when error happens on Twitter API", func(t *testing.T) {
-		require.EqualError(
-			t,
-			client.SendUpdate("it should fail"),
-			"error sending status update: EOF. Response status code: 403 and body: ",
-		)
+		ids, err := client.SendUpdate(context.Background(), "it should fail")
+		require.EqualError(t, err, "error sending status update: EOF. Response status code: 403 and body: ")
+		require.Empty(t, ids)
 		require.Equal(t, 1, httpmock.GetTotalCallCount())
 		httpmock.ZeroCallCounters()
 	})
 
 	t.Run("it should not send status update when status is empty", func(t *testing.T) {
-		require.NoError(
-			t,
-			client.SendUpdate(""),
-		)
+		ids, err := client.SendUpdate(context.Background(), "")
+		require.NoError(t, err)
+		require.Empty(t, ids)
 		require.Zero(t, httpmock.GetTotalCallCount())
 		httpmock.ZeroCallCounters()
 	})
 
 	t.Run("it should fail when invalid character in status update", func(t *testing.T) {
-		require.EqualError(
-			t,
-			client.SendUpdate("test \uFFFE"),
-			"error sending status update: Invalid chararcter [\uFFFE] found at byte offset 5",
-		)
+		_, err := client.SendUpdate(context.Background(), "test \uFFFE")
+		require.EqualError(t, err, "error sending status update: Invalid chararcter [\uFFFE] found at byte offset 5")
 		require.Zero(t, httpmock.GetTotalCallCount())
 		httpmock.ZeroCallCounters()
 	})
 
 	t.Run("it should send status update to Twitter API", func(t *testing.T) {
-		require.NoError(t, client.SendUpdate("testing"))
+		ids, err := client.SendUpdate(context.Background(), "testing")
+		require.NoError(t, err)
+		require.Equal(t, []int64{1050118621198921700}, ids)
 		require.Equal(t, 1, httpmock.GetTotalCallCount())
 		httpmock.ZeroCallCounters()
 	})
 
 	t.Run("it should send long status update to Twitter API", func(t *testing.T) {
-		require.NoError(t, client.SendUpdate(longTweet))
+		ids, err := client.SendUpdate(context.Background(), longTweet)
+		require.NoError(t, err)
+		require.Equal(t, []int64{1445823463904798049, 1445823463904798051}, ids)
 		require.Equal(t, 2, httpmock.GetTotalCallCount())
 		httpmock.ZeroCallCounters()
 	})
@@ -85,11 +85,8 @@ func TestClient_SendUpdateWithPhoto(t *testing.T) {
 		buf := new(bytes.Buffer)
 		_, _ = buf.ReadFrom(file)
 
-		require.EqualError(
-			t,
-			client.SendUpdateWithPhoto("testing", buf.Bytes()),
-			"error sending status update: EOF. Response status code: 403 and body: ",
-		)
+		_, err := client.SendUpdateWithPhoto(context.Background(), "testing", buf.Bytes())
+		require.EqualError(t, err, "error sending status update: EOF. Response status code: 403 and body: ")
 	})
 
 	t.Run("it should fail sending status update with photo to Twitter API", func(t *testing.T) {
@@ -98,11 +95,8 @@ func TestClient_SendUpdateWithPhoto(t *testing.T) {
 		buf := new(bytes.Buffer)
 		_, _ = buf.ReadFrom(file)
 
-		require.EqualError(
-			t,
-			client.SendUpdateWithPhoto("it should fail", buf.Bytes()),
-			"error sending status update: EOF. Response status code: 403 and body: ",
-		)
+		_, err := client.SendUpdateWithPhoto(context.Background(), "it should fail", buf.Bytes())
+		require.EqualError(t, err, "error sending status update: EOF. Response status code: 403 and body: ")
 	})
 
 	t.Run("it should send status update with photo to Twitter API", func(t *testing.T) {
@@ -111,10 +105,176 @@ func TestClient_SendUpdateWithPhoto(t *testing.T) {
 		buf := new(bytes.Buffer)
 		_, _ = buf.ReadFrom(file)
 
-		require.NoError(
-			t,
-			client.SendUpdateWithPhoto("testing", buf.Bytes()),
+		ids, err := client.SendUpdateWithPhoto(context.Background(), "testing", buf.Bytes())
+		require.NoError(t, err)
+		require.Equal(t, []int64{1050118621198921700}, ids)
+	})
+}
+
+func TestClient_UserTimeline(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpClient := oauth1.NewConfig("consumerKey", "consumerSecret").
+		Client(oauth1.NoContext, oauth1.NewToken("accessToken", "accessSecret"))
+
+	client := twitter.NewTwitterClient(gt.NewClient(httpClient))
+
+	t.Run("it should fail when error happens on Twitter API", func(t *testing.T) {
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.twitter.com/1.1/statuses/user_timeline.json",
+			httpmock.NewStringResponder(http.StatusForbidden, ""),
+		)
+
+		_, err := client.UserTimeline(context.Background(), 0)
+		require.EqualError(t, err, "error getting user timeline: EOF. Response status code: 403 and body: ")
+	})
+
+	t.Run("it should fail when the request could not be sent", func(t *testing.T) {
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.twitter.com/1.1/statuses/user_timeline.json",
+			httpmock.NewErrorResponder(errors.New("connection reset by peer")),
+		)
+
+		_, err := client.UserTimeline(context.Background(), 0)
+		require.ErrorContains(t, err, "error getting user timeline: ")
+		require.ErrorContains(t, err, "connection reset by peer")
+	})
+
+	t.Run("it should return tweets with expanded links and photos", func(t *testing.T) {
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.twitter.com/1.1/statuses/user_timeline.json",
+			func(req *http.Request) (*http.Response, error) {
+				require.Equal(t, "1234", req.URL.Query().Get("since_id"))
+				require.Equal(t, "extended", req.URL.Query().Get("tweet_mode"))
+
+				return httpmock.NewJsonResponse(http.StatusOK, []gt.Tweet{
+					{
+						ID:       1235,
+						FullText: "look at https://t.co/link https://t.co/media",
+						User:     &gt.User{ScreenName: "tweetgram"},
+						Entities: &gt.Entities{Urls: []gt.URLEntity{
+							{URL: "https://t.co/link", ExpandedURL: "https://example.com"},
+						}},
+						ExtendedEntities: &gt.ExtendedEntity{Media: []gt.MediaEntity{
+							{
+								URLEntity:     gt.URLEntity{URL: "https://t.co/media"},
+								Type:          "photo",
+								MediaURLHttps: "https://pbs.twimg.com/media/photo.jpg",
+							},
+						}},
+					},
+				})
+			},
+		)
+
+		tweets, err := client.UserTimeline(context.Background(), 1234)
+		require.NoError(t, err)
+		require.Equal(t, []twitter.Tweet{
+			{
+				ID:     1235,
+				Text:   "look at https://example.com",
+				URL:    "https://twitter.com/tweetgram/status/1235",
+				Author: "tweetgram",
+				Photos: []string{"https://pbs.twimg.com/media/photo.jpg"},
+			},
+		}, tweets)
+	})
+}
+
+func TestClient_Mentions(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpClient := oauth1.NewConfig("consumerKey", "consumerSecret").
+		Client(oauth1.NoContext, oauth1.NewToken("accessToken", "accessSecret"))
+
+	client := twitter.NewTwitterClient(gt.NewClient(httpClient))
+
+	t.Run("it should fail when error happens on Twitter API", func(t *testing.T) {
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.twitter.com/1.1/statuses/mentions_timeline.json",
+			httpmock.NewStringResponder(http.StatusForbidden, ""),
+		)
+
+		_, err := client.Mentions(context.Background(), 0)
+		require.EqualError(t, err, "error getting mentions: EOF. Response status code: 403 and body: ")
+	})
+
+	t.Run("it should fail when the request could not be sent", func(t *testing.T) {
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.twitter.com/1.1/statuses/mentions_timeline.json",
+			httpmock.NewErrorResponder(errors.New("connection reset by peer")),
+		)
+
+		_, err := client.Mentions(context.Background(), 0)
+		require.ErrorContains(t, err, "error getting mentions: ")
+		require.ErrorContains(t, err, "connection reset by peer")
+	})
+
+	t.Run("it should return mentions", func(t *testing.T) {
+		httpmock.RegisterResponder(
+			"GET",
+			"https://api.twitter.com/1.1/statuses/mentions_timeline.json",
+			func(req *http.Request) (*http.Response, error) {
+				require.Equal(t, "1234", req.URL.Query().Get("since_id"))
+
+				return httpmock.NewJsonResponse(http.StatusOK, []gt.Tweet{
+					{ID: 1235, FullText: "@tweetgram hello", User: &gt.User{ScreenName: "someone"}},
+				})
+			},
 		)
+
+		tweets, err := client.Mentions(context.Background(), 1234)
+		require.NoError(t, err)
+		require.Equal(t, []twitter.Tweet{
+			{
+				ID:     1235,
+				Text:   "@tweetgram hello",
+				URL:    "https://twitter.com/someone/status/1235",
+				Author: "someone",
+			},
+		}, tweets)
+	})
+}
+
+func TestClient_SendReply(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpClient := oauth1.NewConfig("consumerKey", "consumerSecret").
+		Client(oauth1.NoContext, oauth1.NewToken("accessToken", "accessSecret"))
+
+	client := twitter.NewTwitterClient(gt.NewClient(httpClient))
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.twitter.com/1.1/statuses/update.json",
+		func(req *http.Request) (*http.Response, error) {
+			_ = req.ParseForm()
+
+			if req.Form.Get("in_reply_to_status_id") != "1235" {
+				return httpmock.NewStringResponse(http.StatusForbidden, ""), nil
+			}
+
+			return httpmock.NewJsonResponse(http.StatusOK, gt.Tweet{ID: 1236})
+		},
+	)
+
+	t.Run("it should fail when error happens on Twitter API", func(t *testing.T) {
+		_, err := client.SendReply(context.Background(), 1, "@someone thanks")
+		require.EqualError(t, err, "error sending status update: EOF. Response status code: 403 and body: ")
+	})
+
+	t.Run("it should send reply in thread", func(t *testing.T) {
+		ids, err := client.SendReply(context.Background(), 1235, "@someone thanks")
+		require.NoError(t, err)
+		require.Equal(t, []int64{1236}, ids)
 	})
 }
 
@@ -0,0 +1,60 @@
+package twitter
+
+import (
+	"fmt"
+	"strings"
+
+	gt "github.com/javiyt/go-twitter/twitter"
+)
+
+type Tweet struct {
+	ID     int64
+	Text   string
+	URL    string
+	Author string
+	Photos []string
+}
+
+func toTweets(tweets []gt.Tweet) []Tweet {
+	ts := make([]Tweet, 0, len(tweets))
+
+	for i := range tweets {
+		ts = append(ts, toTweet(&tweets[i]))
+	}
+
+	return ts
+}
+
+func toTweet(t *gt.Tweet) Tweet {
+	text := t.FullText
+	if text == "" {
+		text = t.Text
+	}
+
+	tweet := Tweet{ID: t.ID}
+
+	if t.Entities != nil {
+		for _, u := range t.Entities.Urls {
+			text = strings.ReplaceAll(text, u.URL, u.ExpandedURL)
+		}
+	}
+
+	if t.ExtendedEntities != nil {
+		for _, m := range t.ExtendedEntities.Media {
+			text = strings.ReplaceAll(text, m.URL, "")
+
+			if m.Type == "photo" {
+				tweet.Photos = append(tweet.Photos, m.MediaURLHttps)
+			}
+		}
+	}
+
+	tweet.Text = strings.TrimSpace(text)
+
+	if t.User != nil {
+		tweet.Author = t.User.ScreenName
+		tweet.URL = fmt.Sprintf("https://twitter.com/%s/status/%d", t.User.ScreenName, t.ID)
+	}
+
+	return tweet
+}
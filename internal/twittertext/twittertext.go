@@ -0,0 +1,119 @@
+// Package twittertext finds mentions, hashtags and links in a text with
+// Twitter's twitter-text rules, so what is rewritten is what Twitter would
+// link.
+package twittertext
+
+import (
+	"unicode/utf8"
+
+	"github.com/javiyt/twitter-text-go/extract"
+)
+
+type Type string
+
+const (
+	Mention Type = "mention"
+	Hashtag Type = "hashtag"
+	URL     Type = "url"
+)
+
+// Entity is a mention, hashtag or link in a text, Value is its name without
+// the sign or the whole link, and Start and End the byte offsets of the whole
+// entity, sign included.
+type Entity struct {
+	Type  Type
+	Value string
+	Start int
+	End   int
+}
+
+// Sign returns the entity as written in the text with the ASCII sign, @ for
+// mentions and # for hashtags, whatever the full width one used. Links have
+// no sign.
+func (e Entity) Sign() string {
+	switch e.Type {
+	case Mention:
+		return "@" + e.Value
+	case Hashtag:
+		return "#" + e.Value
+	default:
+		return e.Value
+	}
+}
+
+// Extract returns the mentions and hashtags in the text, in the order they
+// appear. Hashtags within links, like anchors, and keycap emojis are left out,
+// and mentions of lists only cover the username.
+func Extract(text string) []Entity {
+	var entities []Entity
+
+	for _, e := range extract.ExtractEntities(text) {
+		switch e.Type {
+		case extract.MENTION:
+			name, _ := e.ScreenName()
+			_, size := utf8.DecodeRuneInString(e.Text)
+			entities = append(entities, Entity{
+				Type:  Mention,
+				Value: name,
+				Start: e.ByteRange.Start,
+				End:   e.ByteRange.Start + size + len(name),
+			})
+		case extract.HASH_TAG:
+			tag, _ := e.Hashtag()
+			if startsEmoji(tag) {
+				continue
+			}
+
+			entities = append(entities, Entity{
+				Type:  Hashtag,
+				Value: tag,
+				Start: e.ByteRange.Start,
+				End:   e.ByteRange.Stop,
+			})
+		}
+	}
+
+	return entities
+}
+
+// ExtractURLs returns the links in the text, with or without scheme, like
+// bit.ly/x, leaving out the punctuation following them.
+func ExtractURLs(text string) []Entity {
+	var entities []Entity
+
+	for _, e := range extract.ExtractUrls(text) {
+		entities = append(entities, Entity{Type: URL, Value: e.Text, Start: e.ByteRange.Start, End: e.ByteRange.Stop})
+	}
+
+	return entities
+}
+
+// ExtractMentions returns the usernames mentioned in the text, without @.
+func ExtractMentions(text string) []string {
+	return values(Extract(text), Mention)
+}
+
+// ExtractHashtags returns the hashtags in the text, without #.
+func ExtractHashtags(text string) []string {
+	return values(Extract(text), Hashtag)
+}
+
+func values(entities []Entity, t Type) []string {
+	var v []string
+
+	for _, e := range entities {
+		if e.Type == t {
+			v = append(v, e.Value)
+		}
+	}
+
+	return v
+}
+
+// startsEmoji reports keycap emojis like #️⃣, whose sign is followed by a
+// variation selector or the combining keycap.
+func startsEmoji(tag string) bool {
+	r, _ := utf8.DecodeRuneInString(tag)
+
+	return r == '\uFE0F' || r == '\u20E3'
+}
@@ -0,0 +1,118 @@
+package twittertext_test
+
+import (
+	"testing"
+
+	"github.com/javiyt/tweetgram/internal/twittertext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractMentions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{name: "it should extract a mention at the start", text: "@acme_tg says hi", expected: []string{"acme_tg"}},
+		{name: "it should extract several mentions", text: "cc @alice, @bob.", expected: []string{"alice", "bob"}},
+		{name: "it should extract full width mentions", text: "hola ＠acme", expected: []string{"acme"}},
+		{name: "it should extract mentions after RT", text: "RT@acme: news", expected: []string{"acme"}},
+		{name: "it should ignore email addresses", text: "write to info@acme.com", expected: nil},
+		{name: "it should ignore names running into accents", text: "@acmé", expected: nil},
+		{name: "it should ignore names followed by @", text: "@acme@example", expected: nil},
+		{name: "it should ignore a lone sign", text: "meet @ 5", expected: nil},
+		{
+			name:     "it should stop at 20 characters",
+			text:     "@abcdefghijklmnopqrst",
+			expected: []string{"abcdefghijklmnopqrst"},
+		},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			require.Equal(t, testCases[i].expected, twittertext.ExtractMentions(testCases[i].text))
+		})
+	}
+}
+
+func TestExtractHashtags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{name: "it should extract hashtags", text: "#Go is fun, #año_nuevo!", expected: []string{"Go", "año_nuevo"}},
+		{name: "it should extract full width hashtags", text: "＃東京 travel", expected: []string{"東京"}},
+		{name: "it should extract hashtags with digits", text: "#web3 #2024", expected: []string{"web3"}},
+		{name: "it should ignore hashtags within words", text: "a#tag &#39;", expected: nil},
+		{name: "it should ignore hashtags followed by #", text: "#one#two", expected: nil},
+		{name: "it should ignore keycap emojis", text: "#️⃣ key", expected: nil},
+		{name: "it should ignore anchors in links", text: "see https://example.com/#intro #docs", expected: []string{"docs"}},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			require.Equal(t, testCases[i].expected, twittertext.ExtractHashtags(testCases[i].text))
+		})
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{
+			name:     "it should extract links",
+			text:     "read https://example.com/a?b=c and HTTP://bit.ly/x",
+			expected: []string{"https://example.com/a?b=c", "HTTP://bit.ly/x"},
+		},
+		{
+			name:     "it should leave out trailing punctuation",
+			text:     "see https://example.com/a. Or (https://example.com/b)!",
+			expected: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name:     "it should keep balanced parentheses",
+			text:     "https://en.wikipedia.org/wiki/Go_(language)",
+			expected: []string{"https://en.wikipedia.org/wiki/Go_(language)"},
+		},
+		{
+			name:     "it should extract links without scheme",
+			text:     "more at bit.ly/x, or example.com.",
+			expected: []string{"bit.ly/x", "example.com"},
+		},
+		{name: "it should ignore other schemes", text: "ftp://example.com", expected: nil},
+	}
+
+	for i := range testCases {
+		t.Run(testCases[i].name, func(t *testing.T) {
+			var links []string
+			for _, e := range twittertext.ExtractURLs(testCases[i].text) {
+				require.Equal(t, twittertext.URL, e.Type)
+				require.Equal(t, e.Value, testCases[i].text[e.Start:e.End])
+
+				links = append(links, e.Value)
+			}
+
+			require.Equal(t, testCases[i].expected, links)
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	t.Run("it should return entities in order with their offsets", func(t *testing.T) {
+		text := "#launch by ＠acme_tg"
+
+		entities := twittertext.Extract(text)
+
+		require.Equal(t, []twittertext.Entity{
+			{Type: twittertext.Hashtag, Value: "launch", Start: 0, End: 7},
+			{Type: twittertext.Mention, Value: "acme_tg", Start: 11, End: 21},
+		}, entities)
+		require.Equal(t, "＠acme_tg", text[entities[1].Start:entities[1].End])
+		require.Equal(t, "@acme_tg", entities[1].Sign())
+		require.Equal(t, "#launch", entities[0].Sign())
+	})
+}